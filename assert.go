@@ -0,0 +1,50 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Assert logs an error with RootLogger when cond is false. See Logger.Assert
+func Assert(cond bool, format string, v ...interface{}) {
+	if !cond {
+		RootLogger.assertionFailed(fmt.Sprintf(format, v...))
+	}
+}
+
+// AssertNoErr logs an error with RootLogger when err is not nil. See Logger.AssertNoErr
+func AssertNoErr(err error) {
+	if err != nil {
+		RootLogger.assertionFailed(err.Error())
+	}
+}
+
+// Assert checks an invariant. When cond is false, the message format and v is logged as an
+// error with a stack trace of the caller. format and v are only formatted when cond is false.
+//
+//   logger.Assert(len(queue) <= maxQueue, "queue overflow: %d", len(queue))
+//
+// In programs built with the "debug" build tag (go build -tags debug), a failed assertion
+// also panics after the message has been written.
+func (l *Logger) Assert(cond bool, format string, v ...interface{}) {
+	if !cond {
+		l.assertionFailed(fmt.Sprintf(format, v...))
+	}
+}
+
+// AssertNoErr is like Assert with the condition err == nil and the message of err
+func (l *Logger) AssertNoErr(err error) {
+	if err != nil {
+		l.assertionFailed(err.Error())
+	}
+}
+
+func (l *Logger) assertionFailed(msg string) {
+	if l.GetLevel() <= LevelError {
+		l.log(LevelError, "assertion failed: %s\n%s", msg, strings.TrimSuffix(callerStack(), "\n"))
+	}
+	if assertPanics {
+		l.Sync()
+		panic("assertion failed: " + msg)
+	}
+}
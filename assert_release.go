@@ -0,0 +1,6 @@
+// +build !debug
+
+package log
+
+// assertPanics is true when built with the "debug" tag; see Logger.Assert
+const assertPanics = false
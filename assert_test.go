@@ -0,0 +1,43 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestAssert(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixError)
+	defer l.Close()
+
+	l.Assert(true, "not logged %d", 1)
+	l.AssertNoErr(nil)
+	l.Sync()
+	assert.Eq("no output", w.String(), "")
+
+	func() {
+		defer func() {
+			assert.Eq("panics in debug builds", recover() != nil, assertPanics)
+		}()
+		l.Assert(1 > 2, "math is broken: %d", 3)
+	}()
+	l.Sync()
+	lines := strings.Split(w.String(), "\n")
+	assert.Eq("message", lines[0], "[error] assertion failed: math is broken: 3")
+	assert.Ok("stack starts at caller %q", strings.HasSuffix(lines[1], ".TestAssert.func1"),
+		lines[1])
+	assert.Ok("file", strings.Contains(lines[2], "assert_test.go:"))
+
+	w.Reset()
+	func() {
+		defer func() { recover() }()
+		l.AssertNoErr(errors.New("oops"))
+	}()
+	l.Sync()
+	assert.Ok("AssertNoErr", strings.HasPrefix(w.String(), "[error] assertion failed: oops\n"))
+}
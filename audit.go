@@ -0,0 +1,248 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+)
+
+// AuditLogger is a Logger for append-only, tamper-evident logs. Each line ends with a hash of
+// the line chained with the hash of the previous line, making it possible to detect records
+// which were modified, removed or reordered after being written. See VerifyAuditLog.
+//
+// Messages are always written synchronously and, if the writer has a Sync method (like
+// *os.File), flushed to stable storage before the logging call returns. Lines of multi-line
+// messages, except for the last one, end with a backslash.
+//
+// When key is non-empty, the hash is an HMAC-SHA256 with key, which prevents anyone without
+// the key from rewriting the log and recomputing the hash chain. Without a key, SHA-256 is used.
+type AuditLogger struct {
+	*Logger
+	w *auditWriter
+}
+
+// auditFeatures is the fixed feature set of audit loggers: full UTC timestamps, level prefixes
+// and synchronous writes.
+const auditFeatures = FDate | FTime | FMicroseconds | FUTC |
+	FPrefixDebug | FPrefixInfo | FPrefixWarn | FPrefixError | FSync
+
+// NewAuditLogger creates an audit logger which starts a new hash chain on w.
+// Use OpenAuditLog to continue an existing log file.
+func NewAuditLogger(w io.Writer, prefix string, key []byte) *AuditLogger {
+	return newAuditLogger(newAuditWriter(w, key, nil), prefix)
+}
+
+// OpenAuditLog opens or creates the audit log file filename for appending.
+// An existing file is verified (see VerifyAuditLog) and its hash chain is continued.
+func OpenAuditLog(filename, prefix string, key []byte) (*AuditLogger, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_, last, err := verifyAuditLog(f, key)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return newAuditLogger(newAuditWriter(f, key, last), prefix), nil
+}
+
+func newAuditLogger(w *auditWriter, prefix string) *AuditLogger {
+	var feats Features = auditFeatures
+	l := NewLogger(w, prefix, LevelInfo, feats)
+	return &AuditLogger{Logger: l, w: w}
+}
+
+// Err returns the first error encountered while writing or syncing the log
+func (a *AuditLogger) Err() error {
+	a.w.mu.Lock()
+	defer a.w.mu.Unlock()
+	return a.w.err
+}
+
+// Close closes the logger and its writer. Returns the first write error, if any.
+func (a *AuditLogger) Close() error {
+	err := a.Logger.Close()
+	if e := a.Err(); e != nil {
+		err = e
+	}
+	return err
+}
+
+// VerifyAuditLog reads an audit log from r and verifies its hash chain using key, which must be
+// the same key the log was written with. Returns the number of records verified and, if the
+// log has been tampered with, an error describing the first invalid line.
+func VerifyAuditLog(r io.Reader, key []byte) (int, error) {
+	n, _, err := verifyAuditLog(r, key)
+	return n, err
+}
+
+const (
+	auditHashSep = " #"            // separates a record from its hash
+	auditHashLen = sha256.Size * 2 // hex-encoded
+)
+
+// auditWriter writes records terminated by a chained hash
+type auditWriter struct {
+	w    io.Writer
+	mu   sync.Mutex
+	h    hash.Hash
+	prev []byte // hash of previous record
+	buf  []byte
+	err  error // first write error
+}
+
+func newAuditWriter(w io.Writer, key []byte, prev []byte) *auditWriter {
+	if prev == nil {
+		prev = make([]byte, sha256.Size)
+	}
+	return &auditWriter{w: w, h: newAuditHash(key), prev: prev}
+}
+
+func newAuditHash(key []byte) hash.Hash {
+	if len(key) > 0 {
+		return hmac.New(sha256.New, key)
+	}
+	return sha256.New()
+}
+
+// chainHash returns the hash of record chained with prev
+func chainHash(h hash.Hash, prev, record []byte) []byte {
+	h.Reset()
+	h.Write(prev)
+	h.Write(record)
+	return h.Sum(nil)
+}
+
+func (w *auditWriter) writeRecord(m *logRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = w.buf[:0]
+	m.format(&w.buf)
+	return w.writeLocked(w.buf[:len(w.buf)-1]) // without trailing newline
+}
+
+// Write writes p, less any trailing newline, as one record
+func (w *auditWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf[:0], bytes.TrimSuffix(p, []byte{'\n'})...)
+	if err := w.writeLocked(w.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *auditWriter) writeLocked(record []byte) error {
+	sum := chainHash(w.h, w.prev, record)
+	line := make([]byte, 0, len(record)+len(auditHashSep)+auditHashLen+1)
+	line = appendAuditRecord(line, record)
+	line = append(line, auditHashSep...)
+	line = append(line, hex.EncodeToString(sum)...)
+	line = append(line, '\n')
+	if _, err := w.w.Write(line); err != nil {
+		return w.fail(err)
+	}
+	// the record is in the log, so the next one chains to it even if syncing fails
+	w.prev = sum
+	if err := syncWriter(w.w); err != nil {
+		return w.fail(err)
+	}
+	return nil
+}
+
+// fail records err as the first error, if there's none yet, and returns err
+func (w *auditWriter) fail(err error) error {
+	if w.err == nil {
+		w.err = err
+	}
+	return err
+}
+
+// appendAuditRecord appends record to buf with each line but the last terminated by a
+// backslash, so that lines of multi-line records can't be mistaken for the end of a record.
+// A line ending with a backslash thus gets a second one.
+func appendAuditRecord(buf, record []byte) []byte {
+	for {
+		i := bytes.IndexByte(record, '\n')
+		if i == -1 {
+			return append(buf, record...)
+		}
+		buf = append(buf, record[:i]...)
+		buf = append(buf, "\\\n"...)
+		record = record[i+1:]
+	}
+}
+
+func (w *auditWriter) Close() error {
+	if c, ok := w.w.(io.Closer); ok && w.w != os.Stdout && w.w != os.Stderr {
+		return c.Close()
+	}
+	return nil
+}
+
+// verifyAuditLog verifies the audit log read from r and returns the number of records and
+// the hash of the last record (nil if there are no records.)
+// Records spanning multiple lines (multi-line messages) end with the line carrying the hash;
+// their other lines end with a backslash (see appendAuditRecord).
+func verifyAuditLog(r io.Reader, key []byte) (int, []byte, error) {
+	h := newAuditHash(key)
+	prev := make([]byte, sha256.Size)
+	br := bufio.NewReader(r)
+	var record []byte
+	n, lineno, startLine := 0, 0, 1
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			lineno++
+			if line[len(line)-1] != '\n' {
+				return n, nil, fmt.Errorf("line %d: truncated record", lineno)
+			}
+			if bytes.HasSuffix(line, []byte("\\\n")) {
+				record = append(record, line[:len(line)-2]...)
+				record = append(record, '\n')
+			} else if sum, ok := auditLineHash(line); ok {
+				record = append(record, line[:len(line)-len(auditHashSep)-auditHashLen-1]...)
+				if !hmac.Equal(sum, chainHash(h, prev, record)) {
+					return n, nil, fmt.Errorf("line %d: hash mismatch", startLine)
+				}
+				prev = sum
+				record = record[:0]
+				n++
+				startLine = lineno + 1
+			} else {
+				return n, nil, fmt.Errorf("line %d: record without hash", startLine)
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, nil, err
+		}
+	}
+	if len(record) > 0 {
+		return n, nil, fmt.Errorf("line %d: record without hash", startLine)
+	}
+	if n == 0 {
+		return 0, nil, nil
+	}
+	return n, prev, nil
+}
+
+// auditLineHash returns the hash at the end of a newline-terminated line, if any
+func auditLineHash(line []byte) ([]byte, bool) {
+	end := len(line) - 1 // excluding '\n'
+	start := end - auditHashLen
+	if start < len(auditHashSep) || string(line[start-len(auditHashSep):start]) != auditHashSep {
+		return nil, false
+	}
+	sum, err := hex.DecodeString(string(line[start:end]))
+	return sum, err == nil
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestAuditLogger(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	key := []byte("secret")
+	w := &bytes.Buffer{}
+	l := NewAuditLogger(w, "[audit]", key)
+	l.Info("user %q logged in", "bob")
+	l.Warn("multi\nline")
+	l.Error("denied")
+	// no Sync needed; audit loggers write synchronously
+	out := w.String()
+	assert.NoErr("close", l.Close())
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	assert.Eq("lines", len(lines), 4)
+	assert.Ok("header and message; got %q", strings.Contains(lines[0], "[audit] user \"bob\" logged in #"), lines[0])
+
+	n, err := VerifyAuditLog(strings.NewReader(out), key)
+	assert.NoErr("verify", err)
+	assert.Eq("records", n, 3)
+
+	_, err = VerifyAuditLog(strings.NewReader(out), []byte("wrong"))
+	assert.Err("wrong key", "line 1: hash mismatch", err)
+
+	tampered := strings.Replace(out, "bob", "eve", 1)
+	_, err = VerifyAuditLog(strings.NewReader(tampered), key)
+	assert.Err("modified", "line 1: hash mismatch", err)
+
+	removed := strings.Join(append(lines[:1:1], lines[3]), "\n") + "\n"
+	_, err = VerifyAuditLog(strings.NewReader(removed), key)
+	assert.Err("removed", "line 2: hash mismatch", err)
+
+	_, err = VerifyAuditLog(strings.NewReader(out[:len(out)-1]), key)
+	assert.Err("truncated", "line 4: truncated record", err)
+}
+
+func TestOpenAuditLog(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoErr("TempDir", err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "audit.log")
+
+	for i := 0; i < 2; i++ {
+		l, err := OpenAuditLog(filename, "", nil)
+		if !assert.NoErr("open", err) {
+			return
+		}
+		l.Info("session %d", i)
+		assert.NoErr("close", l.Close())
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	assert.NoErr("ReadFile", err)
+	n, err := VerifyAuditLog(bytes.NewReader(data), nil)
+	assert.NoErr("verify", err)
+	assert.Eq("records", n, 2)
+
+	// a tampered log is not continued
+	data[len(data)-2] ^= 1
+	assert.NoErr("WriteFile", ioutil.WriteFile(filename, data, 0600))
+	_, err = OpenAuditLog(filename, "", nil)
+	assert.Ok("tampered log rejected", err != nil)
+}
+
+// syncFailWriter is a bytes.Buffer whose Sync method fails with err
+type syncFailWriter struct {
+	bytes.Buffer
+	err error
+}
+
+func (w *syncFailWriter) Sync() error { return w.err }
+
+func TestAuditLoggerSyncError(t *testing.T) {
+	assert := testutil.NewAssert(t)
+
+	// EINVAL, like when syncing a pipe or terminal, is not an error
+	w := &syncFailWriter{err: &os.PathError{Op: "sync", Path: "/dev/stdout", Err: syscall.EINVAL}}
+	l := NewAuditLogger(w, "", nil)
+	l.Info("a")
+	l.Info("b")
+	assert.NoErr("close", l.Close())
+	n, err := VerifyAuditLog(bytes.NewReader(w.Bytes()), nil)
+	assert.NoErr("verify", err)
+	assert.Eq("records", n, 2)
+
+	// records written before a failed sync are still chained to
+	w = &syncFailWriter{err: errors.New("disk on fire")}
+	l = NewAuditLogger(w, "", nil)
+	l.Info("a")
+	l.Info("b")
+	assert.Err("close", "disk on fire", l.Close())
+	n, err = VerifyAuditLog(bytes.NewReader(w.Bytes()), nil)
+	assert.NoErr("verify", err)
+	assert.Eq("records", n, 2)
+}
+
+func TestAuditLoggerMultiLine(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewAuditLogger(w, "", nil)
+	fake := " #" + strings.Repeat("ab", 32)
+	l.Info("line 1" + fake + "\nline 2\\\nline 3" + fake)
+	l.Info("next")
+	assert.NoErr("close", l.Close())
+	out := w.String()
+	assert.Eq("lines", strings.Count(out, "\n"), 4)
+	assert.Ok("escaped", strings.Contains(out, "line 1"+fake+"\\\nline 2\\\\\n"), out)
+	n, err := VerifyAuditLog(strings.NewReader(out), nil)
+	assert.NoErr("verify", err)
+	assert.Eq("records", n, 2)
+}
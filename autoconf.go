@@ -0,0 +1,118 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Environment is a kind of environment which a program runs in; see DetectEnvironment
+type Environment int
+
+const (
+	EnvOther     Environment = iota // stdout is a file or pipe
+	EnvTerminal                     // stdout is a terminal
+	EnvSystemd                      // stdout is connected to the systemd journal
+	EnvContainer                    // running in a container, e.g. with Docker or Kubernetes
+	EnvCI                           // running in a CI system, e.g. GitHub Actions
+)
+
+var environmentNames = []string{"other", "terminal", "systemd", "container", "ci"}
+
+func (e Environment) String() string {
+	if e >= 0 && int(e) < len(environmentNames) {
+		return environmentNames[e]
+	}
+	return fmt.Sprintf("Environment(%d)", int(e))
+}
+
+// DetectEnvironment returns the kind of environment the program runs in, judging by stdout and
+// environment variables, in this order:
+//
+//   - EnvTerminal if stdout is a terminal
+//   - EnvSystemd if $JOURNAL_STREAM names stdout
+//   - EnvCI if $CI is set, or a variable specific to a CI system like $GITHUB_ACTIONS
+//   - EnvContainer if /.dockerenv or /run/.containerenv exists, or $KUBERNETES_SERVICE_HOST or
+//     $container is set
+//   - EnvOther otherwise
+//
+func DetectEnvironment() Environment {
+	return envProbe{
+		getenv: os.Getenv,
+		exists: func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
+		terminal: isTerminal(os.Stdout),
+		stdoutID: fileID(os.Stdout),
+	}.detect()
+}
+
+// AutoConfigure configures RootLogger for the environment the program runs in (see
+// DetectEnvironment) and returns the environment:
+//
+//   - EnvTerminal: FDefault, i.e. time, level prefixes and colors
+//   - EnvSystemd: FDefault without time and colors, since the journal records the time
+//   - EnvCI: date, time with milliseconds and colors, which CI systems show (unless $NO_COLOR
+//     is set), and level prefixes
+//   - EnvContainer: JSON (see FormatJSON) on stdout, for logging drivers which parse it
+//   - EnvOther: date, time with milliseconds and level prefixes
+//
+// This replaces setting up RootLogger in each program. Call it before logging, e.g. first
+// thing in main. Use ApplyConfig or LoadConfig afterwards to override parts of it.
+func AutoConfigure() Environment {
+	env := DetectEnvironment()
+	ApplyConfig(autoConfig(env, os.Getenv("NO_COLOR") != ""))
+	return env
+}
+
+// autoConfig returns the configuration of RootLogger for env; see AutoConfigure
+func autoConfig(env Environment, noColor bool) Config {
+	var c Config
+	feats := FDefault
+	switch env {
+	case EnvSystemd:
+		feats &^= FTime | FColorAuto
+	case EnvCI:
+		feats = feats&^FColorAuto | FDate | FMilliseconds
+		if !noColor {
+			feats |= FColor
+		}
+	case EnvContainer:
+		c.Output = &OutputConfig{Path: "stdout", Format: "json"}
+	case EnvOther:
+		feats |= FDate | FMilliseconds
+	}
+	c.Features = &feats
+	return c
+}
+
+// envProbe holds what DetectEnvironment looks at, so that it can be tested
+type envProbe struct {
+	getenv   func(name string) string
+	exists   func(path string) bool
+	terminal bool   // stdout is a terminal
+	stdoutID string // "device:inode" of stdout, as in $JOURNAL_STREAM; "" if unknown
+}
+
+func (p envProbe) detect() Environment {
+	if p.terminal {
+		return EnvTerminal
+	}
+	if js := p.getenv("JOURNAL_STREAM"); js != "" && js == p.stdoutID {
+		return EnvSystemd
+	}
+	if ci := strings.ToLower(p.getenv("CI")); ci != "" && ci != "false" && ci != "0" {
+		return EnvCI
+	}
+	for _, name := range []string{"GITHUB_ACTIONS", "GITLAB_CI", "JENKINS_URL", "TF_BUILD", "BUILDKITE"} {
+		if p.getenv(name) != "" {
+			return EnvCI
+		}
+	}
+	if p.getenv("KUBERNETES_SERVICE_HOST") != "" || p.getenv("container") != "" ||
+		p.exists("/.dockerenv") || p.exists("/run/.containerenv") {
+		return EnvContainer
+	}
+	return EnvOther
+}
@@ -0,0 +1,10 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package log
+
+import "os"
+
+// fileID returns "" since there is no systemd journal on this platform
+func fileID(f *os.File) string {
+	return ""
+}
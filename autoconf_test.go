@@ -0,0 +1,50 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestDetectEnvironment(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	probe := func(terminal bool, files []string, env ...string) envProbe {
+		return envProbe{
+			getenv: func(name string) string {
+				for i := 0; i+1 < len(env); i += 2 {
+					if env[i] == name {
+						return env[i+1]
+					}
+				}
+				return ""
+			},
+			exists: func(path string) bool {
+				for _, f := range files {
+					if f == path {
+						return true
+					}
+				}
+				return false
+			},
+			terminal: terminal,
+			stdoutID: "8:1234",
+		}
+	}
+	assert.Eq("terminal", probe(true, nil, "CI", "true").detect(), EnvTerminal)
+	assert.Eq("systemd", probe(false, nil, "JOURNAL_STREAM", "8:1234").detect(), EnvSystemd)
+	assert.Eq("inherited JOURNAL_STREAM", probe(false, nil, "JOURNAL_STREAM", "8:99").detect(), EnvOther)
+	assert.Eq("ci", probe(false, []string{"/.dockerenv"}, "CI", "true").detect(), EnvCI)
+	assert.Eq("ci=false", probe(false, nil, "CI", "false").detect(), EnvOther)
+	assert.Eq("github", probe(false, nil, "GITHUB_ACTIONS", "true").detect(), EnvCI)
+	assert.Eq("docker", probe(false, []string{"/.dockerenv"}).detect(), EnvContainer)
+	assert.Eq("kubernetes", probe(false, nil, "KUBERNETES_SERVICE_HOST", "10.0.0.1").detect(), EnvContainer)
+	assert.Eq("other", probe(false, nil).detect(), EnvOther)
+	assert.Eq("String", EnvContainer.String(), "container")
+
+	c := autoConfig(EnvSystemd, false)
+	assert.Eq("systemd features", *c.Features, FDefault&^(FTime|FColorAuto))
+	c = autoConfig(EnvCI, true)
+	assert.Eq("ci without color", *c.Features&(FColor|FColorAuto), Features(0))
+	c = autoConfig(EnvContainer, false)
+	assert.Eq("container format", c.Output.Format, "json")
+}
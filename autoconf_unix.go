@@ -0,0 +1,22 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package log
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileID returns "device:inode" of f, as in $JOURNAL_STREAM, or "" if f can't be stat'ed
+func fileID(f *os.File) string {
+	st, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	sys, ok := st.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", sys.Dev, sys.Ino)
+}
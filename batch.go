@@ -0,0 +1,153 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Batch collects messages which are logged as one unit; see Logger.Batch
+type Batch struct {
+	l       *Logger
+	records []*logRecord
+}
+
+// Batch calls f to collect messages and then logs them together. The messages of a batch
+// appear contiguously in the output, without messages from other goroutines in between, and
+// are queued with a single channel send. This is useful for dumping tables or multi-step
+// reports:
+//
+//   logger.Batch(func(b *log.Batch) {
+//     b.Info("%-10s %5s", "name", "count")
+//     for _, e := range entries {
+//       b.Info("%-10s %5d", e.Name, e.Count)
+//     }
+//   })
+//
+// Messages are timestamped when they are added to the batch. If messages of any level in the
+// batch are written synchronously (see FSync), Batch returns after the batch has been written.
+func (l *Logger) Batch(f func(b *Batch)) {
+	b := &Batch{l: l}
+	f(b)
+	l.submitBatch(b.records)
+}
+
+// Debug adds a debug message to the batch
+func (b *Batch) Debug(format string, v ...interface{}) {
+	if m := b.add(LevelDebug, format, v...); m != nil && b.l.GetFeatures()&FDebugOrigin != 0 {
+		m.appendOrigin(1)
+	}
+}
+
+// Info adds an info message to the batch
+func (b *Batch) Info(format string, v ...interface{}) { b.add(LevelInfo, format, v...) }
+
+// Warn adds a warning message to the batch
+func (b *Batch) Warn(format string, v ...interface{}) { b.add(LevelWarn, format, v...) }
+
+// Error adds an error message to the batch
+func (b *Batch) Error(format string, v ...interface{}) { b.add(LevelError, format, v...) }
+
+// Columns adds one message of level per key/value pair in kv, with the keys padded to the
+// width of the longest key. See Logger.Columns
+func (b *Batch) Columns(level Level, kv ...interface{}) {
+	if b.l.GetLevel() > level {
+		return
+	}
+	keys := make([]string, (len(kv)+1)/2)
+	width := 0
+	for i := range keys {
+		keys[i] = fmt.Sprint(kv[i*2])
+		if n := utf8.RuneCountInString(keys[i]); n > width {
+			width = n
+		}
+	}
+	for i, key := range keys {
+		var value interface{} = ""
+		if i*2+1 < len(kv) {
+			value = kv[i*2+1]
+		}
+		pad := strings.Repeat(" ", width-utf8.RuneCountInString(key))
+		b.add(level, "%s:%s %v", key, pad, value)
+	}
+}
+
+// Len returns the number of messages in the batch
+func (b *Batch) Len() int { return len(b.records) }
+
+func (b *Batch) add(level Level, format string, v ...interface{}) *logRecord {
+	if b.l.GetLevel() > level {
+		return nil
+	}
+	m := b.l.newRecord(level)
+	m.appendf(format, v...)
+	b.records = append(b.records, m)
+	return m
+}
+
+// submitBatch is like submit but queues records as one ctlBatch record
+func (l *Logger) submitBatch(records []*logRecord) {
+	q := l.q
+	i := 0
+	for _, m := range records {
+		if q.filtered(m) {
+			m.free()
+			continue
+		}
+		q.redact(m)
+		if m.level == LevelError {
+			q.report(m) // before locking q.mu since the reporter might log
+		}
+		records[i] = m
+		i++
+	}
+	records = records[:i]
+	if len(records) == 0 {
+		return
+	}
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		for _, m := range records {
+			l.metrics.drop()
+			m.free()
+		}
+		return
+	}
+	sync := false
+	var walErr error
+	for _, m := range records {
+		l.count(m)
+		sync = sync || l.writesSync(m.level)
+		if q.wal != nil {
+			if err := q.wal.append(m); err != nil {
+				walErr = err
+			}
+		}
+	}
+	m := logRecordFree.Get().(*logRecord)
+	m.logger = l
+	m.level = ctlBatch
+	m.ctlarg = records
+	q.enqueue(m)
+	q.mu.RUnlock()
+	q.writeFailed(walErr)
+	if sync {
+		l.Sync()
+	}
+}
+
+// Columns logs key/value pairs in kv as one message per pair, with keys padded to the width of
+// the longest key. This makes output like configuration dumps readable:
+//
+//   logger.Columns(log.LevelInfo, "name", cfg.Name, "listen", cfg.Addr, "workers", cfg.Workers)
+//   // name:    app
+//   // listen:  :8080
+//   // workers: 4
+//
+// Keys and values are formatted with fmt.Sprint. The messages are logged as a Batch.
+func (l *Logger) Columns(level Level, kv ...interface{}) {
+	if l.GetLevel() <= level {
+		l.Batch(func(b *Batch) { b.Columns(level, kv...) })
+	}
+}
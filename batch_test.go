@@ -0,0 +1,83 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestBatch(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixWarn)
+	defer l.Close()
+
+	l.Batch(func(b *Batch) {
+		b.Debug("not logged")
+		b.Info("a")
+		b.Warn("b %d", 1)
+		assert.Eq("len", b.Len(), 2)
+	})
+	l.Sync()
+	assert.Eq("output", w.String(), "a\n[warn] b 1\n")
+	assert.Eq("metrics", l.Metrics().Records[LevelWarn], uint64(1))
+
+	// batches are not interleaved with messages from other goroutines
+	w.Reset()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				l.Info("x")
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		l.Batch(func(b *Batch) {
+			for j := 0; j < 10; j++ {
+				b.Info("batch")
+			}
+		})
+	}
+	wg.Wait()
+	l.Sync()
+	run := 0
+	for _, line := range strings.Split(strings.TrimSpace(w.String()), "\n") {
+		if line == "batch" {
+			run++
+		} else {
+			if run%10 != 0 {
+				t.Fatalf("interleaved batch (%d lines)", run)
+			}
+			run = 0
+		}
+	}
+	assert.Eq("last run", run%10, 0)
+
+	// empty batch
+	l.Batch(func(b *Batch) {})
+	// closed logger
+	l.Close()
+	l.Batch(func(b *Batch) { b.Info("dropped") })
+	assert.Eq("dropped", l.Metrics().Dropped, uint64(1))
+}
+
+func TestColumns(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+	l.Columns(LevelInfo, "name", "app", "listen", ":8080", "wörkers", 4, "odd")
+	l.Columns(LevelDebug, "not", "logged")
+	l.Sync()
+	assert.Eq("output", w.String(),
+		"name:    app\n"+
+			"listen:  :8080\n"+
+			"wörkers: 4\n"+
+			"odd:     \n")
+}
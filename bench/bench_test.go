@@ -0,0 +1,147 @@
+package bench
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rsms/go-log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	constMsg  = "The quick brown fox jumps over the lazy dog"
+	formatMsg = "The quick brown %s jumps over the lazy %s %d"
+)
+
+func newGoLog(b *testing.B, level log.Level) *log.Logger {
+	l := log.NewLogger(ioutil.Discard, "", level, log.FTime|log.FMicroseconds|log.FPrefixInfo)
+	b.Cleanup(func() { l.Close() })
+	return l
+}
+
+func newZap(level zapcore.Level) *zap.SugaredLogger {
+	ec := zap.NewProductionEncoderConfig()
+	core := zapcore.NewCore(zapcore.NewConsoleEncoder(ec), zapcore.AddSync(ioutil.Discard), level)
+	return zap.New(core).Sugar()
+}
+
+func newZerolog(level zerolog.Level) zerolog.Logger {
+	return zerolog.New(zerolog.ConsoleWriter{Out: ioutil.Discard, NoColor: true}).
+		Level(level).With().Timestamp().Logger()
+}
+
+func BenchmarkDisabled(b *testing.B) {
+	b.Run("go-log", func(b *testing.B) {
+		l := newGoLog(b, log.LevelError)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Info(formatMsg, "fox", "dog", i)
+		}
+	})
+	b.Run("zap", func(b *testing.B) {
+		l := newZap(zapcore.ErrorLevel)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Infof(formatMsg, "fox", "dog", i)
+		}
+	})
+	b.Run("zerolog", func(b *testing.B) {
+		l := newZerolog(zerolog.ErrorLevel)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Info().Msgf(formatMsg, "fox", "dog", i)
+		}
+	})
+}
+
+func BenchmarkConstant(b *testing.B) {
+	b.Run("go-log", func(b *testing.B) {
+		l := newGoLog(b, log.LevelInfo)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Info(constMsg)
+		}
+		l.Sync()
+	})
+	b.Run("zap", func(b *testing.B) {
+		l := newZap(zapcore.InfoLevel)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Info(constMsg)
+		}
+	})
+	b.Run("zerolog", func(b *testing.B) {
+		l := newZerolog(zerolog.InfoLevel)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Info().Msg(constMsg)
+		}
+	})
+}
+
+func BenchmarkFormatted(b *testing.B) {
+	b.Run("go-log", func(b *testing.B) {
+		l := newGoLog(b, log.LevelInfo)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Info(formatMsg, "fox", "dog", i)
+		}
+		l.Sync()
+	})
+	b.Run("zap", func(b *testing.B) {
+		l := newZap(zapcore.InfoLevel)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Infof(formatMsg, "fox", "dog", i)
+		}
+	})
+	b.Run("zerolog", func(b *testing.B) {
+		l := newZerolog(zerolog.InfoLevel)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.Info().Msgf(formatMsg, "fox", "dog", i)
+		}
+	})
+}
+
+// BenchmarkThroughput logs from concurrent goroutines and reports records per second
+func BenchmarkThroughput(b *testing.B) {
+	run := func(b *testing.B, logf func(i int), sync func()) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		start := time.Now()
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				logf(i)
+				i++
+			}
+		})
+		sync()
+		b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "records/s")
+	}
+	b.Run("go-log", func(b *testing.B) {
+		l := newGoLog(b, log.LevelInfo)
+		run(b, func(i int) { l.Info(formatMsg, "fox", "dog", i) }, func() { l.Sync() })
+	})
+	b.Run("zap", func(b *testing.B) {
+		l := newZap(zapcore.InfoLevel)
+		run(b, func(i int) { l.Infof(formatMsg, "fox", "dog", i) }, func() { l.Sync() })
+	})
+	b.Run("zerolog", func(b *testing.B) {
+		l := newZerolog(zerolog.InfoLevel)
+		run(b, func(i int) { l.Info().Msgf(formatMsg, "fox", "dog", i) }, func() {})
+	})
+}
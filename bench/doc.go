@@ -0,0 +1,7 @@
+// Package bench compares the performance of go-log with other popular loggers.
+// It is a separate module so that go-log itself does not depend on the loggers compared against.
+//
+// Run with:
+//   cd bench && go test -bench . -benchmem
+//
+package bench
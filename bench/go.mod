@@ -0,0 +1,13 @@
+module github.com/rsms/go-log/bench
+
+go 1.15
+
+require (
+	github.com/rs/zerolog v1.20.0
+	github.com/rsms/go-log v0.0.0
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	go.uber.org/zap v1.16.0
+)
+
+replace github.com/rsms/go-log => ../
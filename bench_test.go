@@ -0,0 +1,137 @@
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestZeroAlloc(t *testing.T) {
+	l := NewLogger(ioutil.Discard, "", LevelInfo, FTime|FPrefixInfo)
+	defer l.Close()
+	warmUp := func() {
+		// fill the record free list
+		for i := 0; i < 2*cap(l.q.ch); i++ {
+			l.Info("warm up")
+		}
+		l.Sync()
+	}
+	warmUp()
+
+	if n := testing.AllocsPerRun(100, func() { l.Debug("disabled") }); n != 0 {
+		t.Errorf("disabled level: %v allocations per call; expected 0", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { l.Debug("disabled %d", 1) }); n != 0 {
+		t.Errorf("disabled level with args: %v allocations per call; expected 0", n)
+	}
+	warmUp()
+	if n := testing.AllocsPerRun(100, func() { l.Info("constant") }); n != 0 {
+		t.Errorf("constant message: %v allocations per call; expected 0", n)
+	}
+	l.Sync()
+}
+
+func TestEnabled(t *testing.T) {
+	l := NewLogger(ioutil.Discard, "", LevelWarn, 0)
+	defer l.Close()
+	if l.Enabled(LevelInfo) {
+		t.Errorf("LevelInfo should not be enabled")
+	}
+	if !l.Enabled(LevelWarn) || !l.Enabled(LevelError) {
+		t.Errorf("LevelWarn and LevelError should be enabled")
+	}
+}
+
+func benchLogger(b *testing.B, level Level) *Logger {
+	l := NewLogger(ioutil.Discard, "", level, FTime|FMicroseconds|FPrefixInfo)
+	b.Cleanup(func() { l.Close() })
+	b.ReportAllocs()
+	b.ResetTimer()
+	return l
+}
+
+func BenchmarkDisabled(b *testing.B) {
+	l := benchLogger(b, LevelError)
+	for i := 0; i < b.N; i++ {
+		l.Info("The quick brown fox jumps over the lazy dog")
+	}
+}
+
+func BenchmarkDisabledWithArgs(b *testing.B) {
+	l := benchLogger(b, LevelError)
+	for i := 0; i < b.N; i++ {
+		l.Info("The quick brown %s jumps over the lazy %s", "fox", "dog")
+	}
+}
+
+func BenchmarkConstant(b *testing.B) {
+	l := benchLogger(b, LevelInfo)
+	for i := 0; i < b.N; i++ {
+		l.Info("The quick brown fox jumps over the lazy dog")
+	}
+	l.Sync()
+}
+
+func BenchmarkFormatted(b *testing.B) {
+	l := benchLogger(b, LevelInfo)
+	for i := 0; i < b.N; i++ {
+		l.Info("The quick brown %s jumps over the lazy %s %d", "fox", "dog", i)
+	}
+	l.Sync()
+}
+
+func BenchmarkConstantParallel(b *testing.B) {
+	l := benchLogger(b, LevelInfo)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Info("The quick brown fox jumps over the lazy dog")
+		}
+	})
+	l.Sync()
+}
+
+// BenchmarkThroughput measures how many records per second can be logged by concurrent
+// goroutines and written, including waiting for the writeLoop to finish.
+// Use it to evaluate tuning like SetBufferPoolLimits, Options.QueueSize and Options.Shards,
+// e.g. with -cpu 1,8,32.
+func BenchmarkThroughput(b *testing.B) {
+	for _, c := range []struct{ queueSize, shards int }{{64, 1}, {1024, 1}, {64, 8}} {
+		b.Run(fmt.Sprintf("queue=%d,shards=%d", c.queueSize, c.shards), func(b *testing.B) {
+			l := New(Options{
+				Writer:    ioutil.Discard,
+				Level:     LevelInfo,
+				Features:  FTime | FMicroseconds | FPrefixInfo,
+				QueueSize: c.queueSize,
+				Shards:    c.shards,
+			})
+			defer l.Close()
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := time.Now()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					l.Info("The quick brown %s jumps over the lazy %s %d", "fox", "dog", i)
+					i++
+				}
+			})
+			l.Sync()
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "records/s")
+		})
+	}
+}
+
+func BenchmarkFormatHeader(b *testing.B) {
+	l := NewLogger(ioutil.Discard, "[prefix]", LevelInfo, FDate|FTime|FMicroseconds|FPrefixInfo)
+	defer l.Close()
+	m := l.newRecord(LevelInfo)
+	defer m.free()
+	buf := make([]byte, 0, 128)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = buf[:0]
+		l.formatHeader(&buf, m)
+	}
+}
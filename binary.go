@@ -0,0 +1,321 @@
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Binary log format
+//
+// Records are encoded as protocol buffers messages, each preceded by its length as a varint
+// (the "delimited" stream format of protobuf libraries), with the following schema:
+//
+//   message Record {
+//     sfixed64 time     = 1; // Unix time in nanoseconds
+//     int32    level    = 2;
+//     string   prefix   = 3;
+//     string   msg      = 4;
+//     repeated string scope  = 5;
+//     repeated Field  fields = 6;
+//     bytes    trace_id = 7;
+//     bytes    span_id  = 8;
+//     string   caller   = 9;
+//     string   template = 10;
+//   }
+//   message Field {
+//     string key   = 1;
+//     string value = 2;
+//   }
+//
+// This is much cheaper to produce and parse than text and can be decoded by other programs
+// using the schema above.
+
+const (
+	binTime     = 1<<3 | 1 // field 1, 64-bit
+	binLevel    = 2<<3 | 0 // field 2, varint
+	binPrefix   = 3<<3 | 2 // field 3, length-delimited
+	binMsg      = 4<<3 | 2
+	binScope    = 5<<3 | 2
+	binField    = 6<<3 | 2
+	binTraceID  = 7<<3 | 2
+	binSpanID   = 8<<3 | 2
+	binCaller   = 9<<3 | 2
+	binTemplate = 10<<3 | 2
+
+	binFieldKey   = 1<<3 | 2
+	binFieldValue = 2<<3 | 2
+)
+
+// maxBinaryRecordSize limits the size of records accepted by BinaryReader
+const maxBinaryRecordSize = 64 << 20
+
+// BinaryWriter writes records in the binary log format. Read them with BinaryReader.
+//
+//   f, _ := os.Create("app.logb")
+//   logger.SetWriter(log.NewBinaryWriter(f))
+//
+type BinaryWriter struct {
+	w io.Writer
+}
+
+// NewBinaryWriter creates a BinaryWriter writing to w
+func NewBinaryWriter(w io.Writer) *BinaryWriter {
+	return &BinaryWriter{w: w}
+}
+
+func (bw *BinaryWriter) writeRecord(m *logRecord) error {
+	// encode the body after space for the largest possible length prefix, then move it
+	// into place after the actual prefix
+	b := getBuffer()
+	b.B = append(b.B[:0], make([]byte, binary.MaxVarintLen64)...)
+	b.B = appendBinaryRecordBody(b.B, m)
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], uint64(len(b.B)-binary.MaxVarintLen64))
+	copy(b.B, prefix[:n])
+	b.B = b.B[:n+copy(b.B[n:], b.B[binary.MaxVarintLen64:])]
+	_, err := writeBuffer(bw.w, b)
+	return err
+}
+
+// Write writes p as the message of a LevelInfo record
+func (bw *BinaryWriter) Write(p []byte) (int, error) {
+	r := Record{Level: LevelInfo, Time: time.Now(), Msg: string(p)}
+	var buf []byte
+	FormatBinary(&buf, &r)
+	if _, err := bw.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync syncs the underlying writer if it has a Sync method. See FFsync
+func (bw *BinaryWriter) Sync() error {
+	return syncWriter(bw.w)
+}
+
+// Close closes the underlying writer, if it implements io.Closer (except for os.Stdout and
+// os.Stderr)
+func (bw *BinaryWriter) Close() error {
+	if c, ok := bw.w.(io.Closer); ok && bw.w != os.Stdout && bw.w != os.Stderr {
+		return c.Close()
+	}
+	return nil
+}
+
+// FormatBinary is a Formatter which encodes records in the binary log format.
+// See BinaryWriter and TeeSink.
+func FormatBinary(buf *[]byte, r *Record) {
+	var body []byte
+	body = appendBinaryTime(body, r.Time)
+	body = appendBinaryVarint(body, binLevel, uint64(r.Level))
+	body = appendBinaryString(body, binPrefix, r.Prefix)
+	body = appendBinaryString(body, binMsg, r.Msg)
+	for _, s := range r.Scope {
+		body = appendBinaryString(body, binScope, s)
+	}
+	for _, f := range sortedFields(r.Fields) {
+		body = appendBinaryField(body, f)
+	}
+	if traceID, err := hex.DecodeString(r.TraceID); err == nil && len(traceID) > 0 {
+		body = appendBinaryBytes(body, binTraceID, traceID)
+		if spanID, err := hex.DecodeString(r.SpanID); err == nil {
+			body = appendBinaryBytes(body, binSpanID, spanID)
+		}
+	}
+	if r.Caller != "" {
+		body = appendBinaryString(body, binCaller, r.Caller)
+	}
+	body = appendBinaryString(body, binTemplate, r.Template)
+	*buf = appendBinaryDelimited(*buf, body)
+}
+
+func appendBinaryRecordBody(body []byte, m *logRecord) []byte {
+	body = appendBinaryTime(body, m.time)
+	body = appendBinaryVarint(body, binLevel, uint64(m.level))
+	body = appendBinaryString(body, binPrefix, m.logger.Prefix)
+	body = appendBinaryBytes(body, binMsg, m.msg)
+	for _, s := range m.scope {
+		body = appendBinaryString(body, binScope, s)
+	}
+	for _, f := range m.structuredFields() {
+		body = appendBinaryField(body, f)
+	}
+	if m.trace.IsValid() {
+		body = appendBinaryBytes(body, binTraceID, m.trace.TraceID[:])
+		body = appendBinaryBytes(body, binSpanID, m.trace.SpanID[:])
+	}
+	if len(m.origin) > 0 {
+		body = appendBinaryBytes(body, binCaller, m.origin)
+	}
+	body = appendBinaryString(body, binTemplate, m.template)
+	return body
+}
+
+func appendBinaryDelimited(buf, body []byte) []byte {
+	buf = appendUvarint(buf, uint64(len(body)))
+	return append(buf, body...)
+}
+
+func appendBinaryTime(buf []byte, t time.Time) []byte {
+	buf = append(buf, binTime)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(t.UnixNano()))
+	return append(buf, b[:]...)
+}
+
+func appendBinaryVarint(buf []byte, tag byte, v uint64) []byte {
+	return appendUvarint(append(buf, tag), v)
+}
+
+func appendBinaryString(buf []byte, tag byte, s string) []byte {
+	if s == "" {
+		return buf
+	}
+	buf = appendUvarint(append(buf, tag), uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendBinaryBytes(buf []byte, tag byte, b []byte) []byte {
+	if len(b) == 0 {
+		return buf
+	}
+	buf = appendUvarint(append(buf, tag), uint64(len(b)))
+	return append(buf, b...)
+}
+
+func appendBinaryField(buf []byte, f Field) []byte {
+	var body []byte
+	body = appendBinaryString(body, binFieldKey, f.Key)
+	body = appendBinaryString(body, binFieldValue, f.Value)
+	buf = appendUvarint(append(buf, binField), uint64(len(body)))
+	return append(buf, body...)
+}
+
+// ErrBinaryFormat is returned by BinaryReader for malformed input
+var ErrBinaryFormat = errors.New("log: malformed binary log record")
+
+// BinaryReader reads records written by BinaryWriter or FormatBinary
+type BinaryReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// NewBinaryReader creates a BinaryReader reading from r
+func NewBinaryReader(r io.Reader) *BinaryReader {
+	return &BinaryReader{r: bufio.NewReader(r)}
+}
+
+// Read reads the next record. Returns io.EOF when there are no more records.
+func (br *BinaryReader) Read() (*Record, error) {
+	size, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, ErrBinaryFormat
+	}
+	if size > maxBinaryRecordSize {
+		return nil, fmt.Errorf("%w: record too large (%d bytes)", ErrBinaryFormat, size)
+	}
+	if cap(br.buf) < int(size) {
+		br.buf = make([]byte, size)
+	}
+	body := br.buf[:size]
+	if _, err := io.ReadFull(br.r, body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBinaryFormat, err)
+	}
+	return decodeBinaryRecord(body)
+}
+
+func decodeBinaryRecord(b []byte) (*Record, error) {
+	r := &Record{}
+	for len(b) > 0 {
+		tag := b[0]
+		b = b[1:]
+		switch tag & 7 {
+		case 0: // varint
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, ErrBinaryFormat
+			}
+			b = b[n:]
+			if tag == binLevel {
+				r.Level = Level(v)
+			}
+		case 1: // 64-bit
+			if len(b) < 8 {
+				return nil, ErrBinaryFormat
+			}
+			if tag == binTime {
+				r.Time = time.Unix(0, int64(binary.LittleEndian.Uint64(b)))
+			}
+			b = b[8:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return nil, ErrBinaryFormat
+			}
+			v := b[n : n+int(size)]
+			b = b[n+int(size):]
+			switch tag {
+			case binPrefix:
+				r.Prefix = string(v)
+			case binMsg:
+				r.Msg = string(v)
+			case binScope:
+				r.Scope = append(r.Scope, string(v))
+			case binField:
+				f, err := decodeBinaryField(v)
+				if err != nil {
+					return nil, err
+				}
+				if r.Fields == nil {
+					r.Fields = make(map[string]string)
+				}
+				r.Fields[f.Key] = f.Value
+			case binTraceID:
+				r.TraceID = hex.EncodeToString(v)
+			case binSpanID:
+				r.SpanID = hex.EncodeToString(v)
+			case binCaller:
+				r.Caller = string(v)
+			case binTemplate:
+				r.Template = string(v)
+			}
+		default:
+			return nil, ErrBinaryFormat
+		}
+	}
+	return r, nil
+}
+
+func decodeBinaryField(b []byte) (Field, error) {
+	var f Field
+	for len(b) > 0 {
+		tag := b[0]
+		size, n := binary.Uvarint(b[1:])
+		if tag&7 != 2 || n <= 0 || uint64(len(b)-1-n) < size {
+			return f, ErrBinaryFormat
+		}
+		v := string(b[1+n : 1+n+int(size)])
+		b = b[1+n+int(size):]
+		switch tag {
+		case binFieldKey:
+			f.Key = v
+		case binFieldValue:
+			f.Value = v
+		}
+	}
+	return f, nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
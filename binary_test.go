@@ -0,0 +1,81 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestBinaryFormat(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	buf := &bytes.Buffer{}
+	teebuf := &bytes.Buffer{}
+	tw := NewTeeWriter(
+		TeeSink{W: NewBinaryWriter(buf)},
+		TeeSink{W: teebuf, Format: FormatBinary},
+	)
+	l := NewLogger(tw, "[p]", LevelDebug, FDefault)
+	defer l.Close()
+
+	var tc TraceContext
+	tc.TraceID[0], tc.SpanID[7] = 1, 2
+	ctx := ContextWithFields(ContextWithTrace(context.Background(), tc), "k", "v")
+	l.Warn("hello %d", 1)
+	defer l.WithScope("cmd")()
+	l.InfoContext(ctx, strings.Repeat("x", 300))
+	l.Sync()
+
+	for _, src := range []*bytes.Buffer{buf, teebuf} {
+		br := NewBinaryReader(bytes.NewReader(src.Bytes()))
+		r1, err := br.Read()
+		assert.NoErr("read", err)
+		assert.Eq("level", r1.Level, LevelWarn)
+		assert.Eq("msg", r1.Msg, "hello 1")
+		assert.Eq("prefix", r1.Prefix, "[p]")
+		assert.Ok("time", r1.Time.UnixNano() > 0)
+
+		r2, err := br.Read()
+		assert.NoErr("read", err)
+		assert.Eq("long msg", len(r2.Msg), 300)
+		assert.Eq("scope", strings.Join(r2.Scope, ">"), "cmd")
+		assert.Eq("field", r2.Fields["k"], "v")
+		assert.Eq("trace id", r2.TraceID, "01000000000000000000000000000000")
+		assert.Eq("span id", r2.SpanID, "0000000000000002")
+
+		_, err = br.Read()
+		assert.Eq("EOF", err, io.EOF)
+	}
+
+	// truncated input
+	data := buf.Bytes()
+	_, err := NewBinaryReader(bytes.NewReader(data[:len(data)-1])).Read()
+	assert.NoErr("first record intact", err)
+	br := NewBinaryReader(bytes.NewReader(data[:len(data)-1]))
+	br.Read()
+	_, err = br.Read()
+	assert.Ok("truncated record", errors.Is(err, ErrBinaryFormat))
+}
+
+func TestBinaryCaller(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	buf := &bytes.Buffer{}
+	teebuf := &bytes.Buffer{}
+	tw := NewTeeWriter(
+		TeeSink{W: NewBinaryWriter(buf)},
+		TeeSink{W: teebuf, Format: FormatBinary},
+	)
+	l := NewLogger(tw, "", LevelDebug, FDefault)
+	l.Debug("dbg")
+	l.Close()
+	for _, src := range []*bytes.Buffer{buf, teebuf} {
+		r, err := NewBinaryReader(bytes.NewReader(src.Bytes())).Read()
+		assert.NoErr("read", err)
+		assert.Eq("msg", r.Msg, "dbg")
+		assert.Ok("caller", strings.Contains(r.Caller, "binary_test.go:"))
+	}
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+// countingWriter records each call to Write
+type countingWriter struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func (w *countingWriter) Writes() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.writes...)
+}
+
+func TestBuffer(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &countingWriter{}
+	l := NewLogger(w, "", LevelInfo, 0).WithBuffer(1024, 0)
+	defer l.Close()
+
+	for i := 0; i < 10; i++ {
+		l.Info("line %d", i)
+	}
+	l.Sync()
+	writes := w.Writes()
+	assert.Eq("number of writes", len(writes), 1)
+	assert.Eq("first line", writes[0][:7], "line 0\n")
+
+	// full buffer is flushed without Sync
+	l.WithBuffer(16, 0)
+	l.Info("0123456789")
+	l.Info("0123456789")
+	l.Info("x")
+	deadline := time.Now().Add(time.Second)
+	for len(w.Writes()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	writes = w.Writes()
+	assert.Eq("write of full buffer", strings.Join(writes[1:], "|"), "0123456789\n0123456789\n")
+	l.Sync()
+	assert.Eq("write on sync", strings.Join(w.Writes()[2:], "|"), "x\n")
+
+	// records for different writers are not mixed up
+	w2 := &bytes.Buffer{}
+	sub := l.SubLogger("[sub]")
+	sub.SetWriter(w2)
+	l.Info("a")
+	sub.Info("b")
+	l.Sync()
+	assert.Eq("sub writer", w2.String(), "[sub] b\n")
+	assert.Eq("writes", strings.Join(w.Writes()[3:], "|"), "a\n")
+
+	// disable buffering
+	l.WithBuffer(0, 0)
+	l.Info("c")
+	l.Info("d")
+	l.Sync()
+	assert.Eq("unbuffered writes", strings.Join(w.Writes()[4:], "|"), "c\n|d\n")
+}
+
+func TestBufferFlushInterval(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &countingWriter{}
+	l := NewLogger(w, "", LevelInfo, 0).WithBuffer(1024, 5*time.Millisecond)
+	defer l.Close()
+
+	l.Info("hello")
+	deadline := time.Now().Add(time.Second)
+	for len(w.Writes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Eq("flushed by ticker", strings.Join(w.Writes(), "|"), "hello\n")
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Buffer is a pooled byte buffer holding formatted log output.
+// It implements io.Writer and io.StringWriter so that it can be appended to directly.
+type Buffer struct {
+	B []byte
+}
+
+// BufferWriter can be implemented by writers which want to take ownership of output buffers
+// rather than copying the bytes passed to Write, e.g. writers that queue output for later.
+//
+// When a logger's writer implements BufferWriter, WriteBuffer is called instead of Write.
+// WriteBuffer takes ownership of b: the logger never touches b again and the writer must
+// call b.Release when it is done with b, after which it must not retain or use b.B.
+type BufferWriter interface {
+	WriteBuffer(b *Buffer) error
+}
+
+var bufferFree = sync.Pool{
+	New: func() interface{} {
+		return &Buffer{B: make([]byte, 0, atomic.LoadInt32(&bufferInitSize))}
+	},
+}
+
+// limits of pooled buffers; see SetBufferPoolLimits
+var (
+	bufferInitSize int32 = 256
+	bufferMaxSize  int32 = 64 << 10
+)
+
+// SetBufferPoolLimits tunes the pool of buffers which formatted output is written to.
+// New buffers are allocated with capacity initSize, and buffers which have grown beyond maxSize
+// are discarded rather than reused, so that a rare huge message does not pin memory.
+//
+// The defaults (256 and 64kB) suit messages of up to a few hundred bytes. When messages are
+// usually longer, raising initSize avoids growing buffers; compare with BenchmarkThroughput
+// and messages typical for your program. Values <= 0 leave the corresponding limit unchanged.
+func SetBufferPoolLimits(initSize, maxSize int) {
+	if initSize > 0 {
+		atomic.StoreInt32(&bufferInitSize, int32(initSize))
+	}
+	if maxSize > 0 {
+		atomic.StoreInt32(&bufferMaxSize, int32(maxSize))
+	}
+}
+
+func getBuffer() *Buffer {
+	return bufferFree.Get().(*Buffer)
+}
+
+// Release returns b to the buffer pool. b must not be used after calling Release.
+func (b *Buffer) Release() {
+	// see logRecord.free
+	if cap(b.B) > int(atomic.LoadInt32(&bufferMaxSize)) {
+		return
+	}
+	b.B = b.B[:0]
+	bufferFree.Put(b)
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.B = append(b.B, p...)
+	return len(p), nil
+}
+
+func (b *Buffer) WriteString(s string) (int, error) {
+	b.B = append(b.B, s...)
+	return len(s), nil
+}
+
+func (b *Buffer) Len() int      { return len(b.B) }
+func (b *Buffer) Bytes() []byte { return b.B }
+
+// writeBuffer hands b off to w if w is a BufferWriter, or otherwise writes b to w and
+// releases b. Either way, the caller must not use b after the call.
+func writeBuffer(w io.Writer, b *Buffer) (int, error) {
+	if bw, ok := w.(BufferWriter); ok {
+		n := len(b.B)
+		if err := bw.WriteBuffer(b); err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
+	n, err := w.Write(b.B)
+	b.Release()
+	return n, err
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+// retainingWriter keeps all buffers it is given until release is called
+type retainingWriter struct {
+	bufs []*Buffer
+}
+
+func (w *retainingWriter) Write(p []byte) (int, error) {
+	panic("Write called on a BufferWriter")
+}
+
+func (w *retainingWriter) WriteBuffer(b *Buffer) error {
+	w.bufs = append(w.bufs, b)
+	return nil
+}
+
+func (w *retainingWriter) release() {
+	for _, b := range w.bufs {
+		b.Release()
+	}
+	w.bufs = nil
+}
+
+func TestBufferWriter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &retainingWriter{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+
+	for i := 0; i < 100; i++ {
+		l.Info("message %d", i)
+	}
+	l.Sync()
+	if assert.Eq("buffers", len(w.bufs), 100) {
+		for i, b := range w.bufs {
+			assert.Eq("buffer %d", string(b.Bytes()), fmt.Sprintf("message %d\n", i), i)
+		}
+	}
+	w.release()
+
+	// buffered output is handed off as well
+	l.WithBuffer(1024, 0)
+	l.Info("a")
+	l.Info("b")
+	l.Sync()
+	if assert.Eq("buffers", len(w.bufs), 1) {
+		assert.Eq("buffer", string(w.bufs[0].Bytes()), "a\nb\n")
+	}
+	w.release()
+}
+
+func TestBufferWriteString(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	b := getBuffer()
+	defer b.Release()
+	var sw io.StringWriter = b
+	sw.WriteString("hello ")
+	fmt.Fprintf(b, "%d", 123)
+	assert.Eq("contents", string(b.Bytes()), "hello 123")
+	assert.Eq("len", b.Len(), 9)
+}
+
+func TestSetBufferPoolLimits(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	defer SetBufferPoolLimits(256, 64<<10)
+	SetBufferPoolLimits(0, 100)
+	assert.Eq("init size unchanged", bufferInitSize, int32(256))
+	b := getBuffer()
+	b.B = make([]byte, 0, 200)
+	b.Release() // discarded since it exceeds maxSize
+	assert.Eq("max size", bufferMaxSize, int32(100))
+}
@@ -0,0 +1,319 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Publisher delivers a batch of messages to a message bus, like a Kafka topic or a NATS
+// subject. See BusSink
+type Publisher interface {
+	Publish(msgs [][]byte) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface.
+// This is the way to publish to Kafka, using a Kafka client of your choice:
+//
+//   kw := &kafka.Writer{Addr: kafka.TCP("localhost:9092"), Topic: "logs"}
+//   sink := log.NewBusSink(log.PublisherFunc(func(msgs [][]byte) error {
+//     kmsgs := make([]kafka.Message, len(msgs))
+//     for i, msg := range msgs {
+//       kmsgs[i].Value = msg
+//     }
+//     return kw.WriteMessages(context.Background(), kmsgs...)
+//   }), nil)
+//
+type PublisherFunc func(msgs [][]byte) error
+
+func (f PublisherFunc) Publish(msgs [][]byte) error { return f(msgs) }
+
+// BusOptions configures a BusSink. The zero value of each field selects its default.
+type BusOptions struct {
+	Format        Formatter     // encoding of messages; FormatJSON (default) or FormatBinary
+	BatchSize     int           // max messages per Publish call (default 256)
+	FlushInterval time.Duration // max time a message waits before being published (default 1s)
+	MaxQueue      int           // max messages waiting to be published (default 8192)
+}
+
+// BusSink is a Sink which publishes records to a message bus, for services whose log pipeline
+// is a message bus rather than files. Each record is encoded as one message and messages are
+// published in batches from a background goroutine, so logging never waits for the bus.
+//
+//   sink := log.NewBusSink(log.NewNATSPublisher("localhost:4222", "logs.myservice"), nil)
+//   logger.SetWriter(log.SinkWriter(sink))
+//
+// When the queue is full, because the bus is slow or unreachable, the oldest messages are
+// dropped.
+type BusSink struct {
+	pub  Publisher
+	opts BusOptions
+
+	mu      sync.Mutex
+	queue   [][]byte
+	dropped uint64
+	err     error         // last publish error
+	wakeup  chan struct{} // signals the run goroutine
+	flushch chan chan error
+	closed  bool
+	done    chan struct{}
+}
+
+// NewBusSink creates a sink publishing with pub. opts may be nil.
+func NewBusSink(pub Publisher, opts *BusOptions) *BusSink {
+	s := &BusSink{
+		pub:     pub,
+		wakeup:  make(chan struct{}, 1),
+		flushch: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Format == nil {
+		s.opts.Format = FormatJSON
+	}
+	if s.opts.BatchSize <= 0 {
+		s.opts.BatchSize = 256
+	}
+	if s.opts.FlushInterval <= 0 {
+		s.opts.FlushInterval = time.Second
+	}
+	if s.opts.MaxQueue < s.opts.BatchSize {
+		s.opts.MaxQueue = 8192
+		if s.opts.MaxQueue < s.opts.BatchSize {
+			s.opts.MaxQueue = s.opts.BatchSize
+		}
+	}
+	go s.run()
+	return s
+}
+
+// Write encodes r and queues it for publishing.
+// Returns the error of the last failed publish, if any.
+func (s *BusSink) Write(r *Record) error {
+	var msg []byte
+	s.opts.Format(&msg, r)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return fmt.Errorf("log: bus sink closed")
+	}
+	if len(s.queue) >= s.opts.MaxQueue {
+		copy(s.queue, s.queue[1:])
+		s.queue = s.queue[:len(s.queue)-1]
+		s.dropped++
+	}
+	s.queue = append(s.queue, msg)
+	if len(s.queue) >= s.opts.BatchSize {
+		s.signal()
+	}
+	err := s.err
+	s.err = nil
+	s.mu.Unlock()
+	return err
+}
+
+// Dropped returns the number of messages dropped because the queue was full
+func (s *BusSink) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Sync publishes all queued messages and returns the first error encountered
+func (s *BusSink) Sync() error {
+	ch := make(chan error)
+	select {
+	case s.flushch <- ch:
+		return <-ch
+	case <-s.done:
+		return nil
+	}
+}
+
+// Close publishes all queued messages and stops the sink.
+// The publisher is closed too, if it implements io.Closer.
+func (s *BusSink) Close() error {
+	err := s.Sync()
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.wakeup)
+	}
+	s.mu.Unlock()
+	<-s.done
+	if c, ok := s.pub.(interface{ Close() error }); ok {
+		if err2 := c.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// signal wakes up run without blocking. s.mu must be held, since Close closes s.wakeup.
+func (s *BusSink) signal() {
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (s *BusSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case _, ok := <-s.wakeup:
+			s.publish(false)
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			s.publish(true)
+		case ch := <-s.flushch:
+			ch <- s.publish(true)
+		}
+	}
+}
+
+// publish sends queued messages in batches. Unless all is true, only full batches are sent.
+func (s *BusSink) publish(all bool) error {
+	var firstErr error
+	for {
+		s.mu.Lock()
+		n := len(s.queue)
+		if n == 0 || (!all && n < s.opts.BatchSize) {
+			s.mu.Unlock()
+			return firstErr
+		}
+		if n > s.opts.BatchSize {
+			n = s.opts.BatchSize
+		}
+		batch := make([][]byte, n)
+		copy(batch, s.queue)
+		s.queue = s.queue[:copy(s.queue, s.queue[n:])]
+		s.mu.Unlock()
+
+		if err := s.pub.Publish(batch); err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+}
+
+// NATSPublisher publishes messages to a subject of a NATS server, using the NATS client
+// protocol over TCP. It connects when first used and reconnects after a failed publish.
+type NATSPublisher struct {
+	addr    string
+	subject string
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	err  error // error reported by the server
+}
+
+// NewNATSPublisher returns a publisher to subject of the NATS server at addr ("host:port")
+func NewNATSPublisher(addr, subject string) *NATSPublisher {
+	return &NATSPublisher{addr: addr, subject: subject, timeout: 10 * time.Second}
+}
+
+// Publish sends msgs to the server
+func (p *NATSPublisher) Publish(msgs [][]byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+	var buf []byte
+	for _, msg := range msgs {
+		buf = append(buf, "PUB "...)
+		buf = append(buf, p.subject...)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(len(msg)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, msg...)
+		buf = append(buf, "\r\n"...)
+	}
+	p.conn.SetWriteDeadline(time.Now().Add(p.timeout))
+	if _, err := p.conn.Write(buf); err != nil {
+		p.conn.Close()
+		p.conn = nil
+		return err
+	}
+	err := p.err
+	p.err = nil
+	return err
+}
+
+// Close closes the connection to the server
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
+
+// connect dials the server and performs the handshake. p.mu must be held.
+func (p *NATSPublisher) connect() error {
+	conn, err := net.DialTimeout("tcp", p.addr, p.timeout)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(p.timeout))
+	line, err := r.ReadString('\n')
+	if err == nil && !strings.HasPrefix(line, "INFO ") {
+		err = fmt.Errorf("log: unexpected NATS greeting %q", strings.TrimSpace(line))
+	}
+	if err == nil {
+		_, err = conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false}\r\n"))
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+	p.conn = conn
+	go p.read(conn, r)
+	return nil
+}
+
+// read answers pings from the server and records errors it reports, until conn is closed
+func (p *NATSPublisher) read(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			p.mu.Lock()
+			if p.conn == conn {
+				conn.SetWriteDeadline(time.Now().Add(p.timeout))
+				conn.Write([]byte("PONG\r\n"))
+			}
+			p.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			p.mu.Lock()
+			p.err = fmt.Errorf("log: NATS error: %s", strings.TrimSpace(line[4:]))
+			p.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestBusSink(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var mu sync.Mutex
+	var batches [][][]byte
+	pub := PublisherFunc(func(msgs [][]byte) error {
+		mu.Lock()
+		batches = append(batches, msgs)
+		mu.Unlock()
+		return nil
+	})
+	sink := NewBusSink(pub, &BusOptions{BatchSize: 2})
+	l := NewLogger(SinkWriter(sink), "", LevelInfo, 0)
+	l.Info("a")
+	l.Warn("b")
+	l.Info("c")
+	l.Sync()
+	assert.NoErr("sync", sink.Sync())
+
+	mu.Lock()
+	assert.Eq("batches", len(batches), 2)
+	assert.Eq("batch size", len(batches[0]), 2)
+	var r Record
+	assert.NoErr("decode", json.Unmarshal(batches[0][1], &r))
+	assert.Eq("msg", r.Msg, "b")
+	assert.Eq("level", r.Level, LevelWarn)
+	mu.Unlock()
+	l.Close()
+
+	// binary encoding
+	var msgs [][]byte
+	sink = NewBusSink(PublisherFunc(func(m [][]byte) error {
+		msgs = append(msgs, m...)
+		return nil
+	}), &BusOptions{Format: FormatBinary})
+	sink.Write(&Record{Level: LevelError, Msg: "x"})
+	assert.NoErr("close", sink.Close())
+	assert.Eq("msgs", len(msgs), 1)
+	r2, err := decodeBinaryRecord(msgs[0][1:])
+	assert.NoErr("decode binary", err)
+	assert.Eq("binary msg", r2.Msg, "x")
+	assert.Ok("closed", sink.Write(&Record{}) != nil)
+}
+
+func TestBusSinkWriteClose(t *testing.T) {
+	pub := PublisherFunc(func(msgs [][]byte) error { return nil })
+	for i := 0; i < 10; i++ {
+		sink := NewBusSink(pub, &BusOptions{BatchSize: 1})
+		// writes racing with Close must not send on the closed wakeup channel
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					sink.Write(&Record{Level: LevelInfo, Msg: "x"})
+				}
+			}()
+		}
+		sink.Close()
+		wg.Wait()
+	}
+}
+
+func TestNATSPublisher(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoErr("listen", err)
+	defer ln.Close()
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- strings.TrimSpace(line)
+		}
+	}()
+
+	p := NewNATSPublisher(ln.Addr().String(), "logs.test")
+	assert.NoErr("publish", p.Publish([][]byte{[]byte("hello"), []byte("hi")}))
+	assert.Ok("connect", strings.HasPrefix(<-lines, "CONNECT "))
+	assert.Eq("pub 1", <-lines, "PUB logs.test 5")
+	assert.Eq("payload 1", <-lines, "hello")
+	assert.Eq("pub 2", <-lines, "PUB logs.test 2")
+	assert.Eq("payload 2", <-lines, "hi")
+	assert.NoErr("close", p.Close())
+}
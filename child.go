@@ -0,0 +1,59 @@
+package log
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// ChildOptions overrides settings of a logger created with Logger.Child.
+// Settings which are nil are inherited from the parent.
+type ChildOptions struct {
+	Prefix   string    // appended to the prefix of the parent, as for SubLogger
+	Writer   io.Writer // nil to write to the parent's writer
+	Level    *Level
+	Features *Features
+}
+
+// Child is like SubLogger but can override the writer, level and features of the child.
+//
+// A child which writes to the parent's writer shares the parent's queue, as a sub-logger does,
+// so that messages of the two are written in the order they were logged. A child with a
+// different writer gets its own queue and write goroutine, so that it is not affected by
+// buffering (see WithBuffer), duplicate suppression or a slow writer of the parent. It still
+// uses the redactors and filter of the parent at the time Child is called.
+// Close such a child when it is no longer needed, which also closes its writer.
+//
+// Example:
+//
+//   level := log.LevelDebug
+//   audit := logger.Child(log.ChildOptions{Prefix: "[audit]", Writer: f, Level: &level})
+//   defer audit.Close()
+//
+func (l *Logger) Child(opts ChildOptions) *Logger {
+	l2 := l.SubLogger(opts.Prefix)
+	if opts.Level != nil {
+		l2.Level = *opts.Level
+	}
+	if opts.Features != nil {
+		l2.Features = *opts.Features
+	}
+	if opts.Writer != nil && !sameWriter(opts.Writer, l.Writer()) {
+		q := newQueue(cap(l.q.ch), l.q.shardCount())
+		if rs := l.q.redactors.Load(); rs != nil {
+			q.redactors.Store(rs)
+		}
+		if f := l.q.filter.Load(); f != nil {
+			q.filter.Store(f)
+		}
+		q.onError = l.q.onError
+		q.ordered = atomic.LoadInt32(&l.q.ordered)
+		q.priority = l.q.priority
+		l2.q = q
+		l2.metrics = new(metrics)
+		l2.w.Store(newWriterRef(opts.Writer))
+		openQueues.add(l2)
+		go l2.writeLoop()
+	}
+	l2.RefreshAutoFeatures()
+	return l2
+}
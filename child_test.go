@@ -0,0 +1,36 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestChild(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w, w2 := &bytes.Buffer{}, &bytes.Buffer{}
+	var feats Features = FPrefixInfo
+	l := NewLogger(w, "[p]", LevelInfo, feats)
+	defer l.Close()
+	l.AddRedactor(RegexRedactor(`secret`))
+
+	shared := l.Child(ChildOptions{Prefix: "[shared]"})
+	assert.Ok("shares queue", shared.q == l.q)
+
+	debug, noFeats := LevelDebug, Features(0)
+	own := l.Child(ChildOptions{Prefix: "[own]", Writer: w2, Level: &debug, Features: &noFeats})
+	assert.Ok("own queue", own.q != l.q)
+
+	l.Debug("not logged")
+	shared.Info("a")
+	own.Debug("b secret")
+	own.SubLogger("[sub]").Info("c")
+	assert.NoErr("Close", own.Close())
+	own.Info("after close")
+	l.Info("d")
+	l.Sync()
+
+	assert.Eq("w", w.String(), "[info] [p][shared] a\n[info] [p] d\n")
+	assert.Eq("w2", w2.String(), "[p][own] b "+Redacted+"\n[p][own][sub] c\n")
+}
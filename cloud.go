@@ -0,0 +1,483 @@
+package log
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CloudWatchOptions configures a CloudWatchSink. The zero value of each field selects its
+// default, which for region and credentials is taken from the standard AWS environment
+// variables, as set for example in AWS Lambda.
+type CloudWatchOptions struct {
+	Region          string        // default $AWS_REGION or $AWS_DEFAULT_REGION
+	AccessKeyID     string        // default $AWS_ACCESS_KEY_ID
+	SecretAccessKey string        // default $AWS_SECRET_ACCESS_KEY
+	SessionToken    string        // default $AWS_SESSION_TOKEN
+	Endpoint        string        // default "https://logs.{region}.amazonaws.com"
+	Client          *http.Client  // defaults to a client with a 10s timeout
+	Format          Formatter     // encoding of messages (default FormatJSON)
+	BatchSize       int           // max events per request (default 1000, max 10000)
+	FlushInterval   time.Duration // max time a record waits before being sent (default 5s)
+	MaxQueue        int           // max records waiting to be sent (default 8192)
+}
+
+// CloudWatchSink is a Sink which sends records to AWS CloudWatch Logs with PutLogEvents.
+// Records are sent in batches from a background goroutine. Requests are signed with AWS
+// Signature Version 4; the package does not depend on the AWS SDK.
+//
+//   sink, err := log.NewCloudWatchSink("/myservice", "instance-1", nil)
+//   ...
+//   logger.SetWriter(log.SinkWriter(sink))
+//
+// The log stream is created when needed. When the queue is full, because CloudWatch is slow
+// or unreachable, the oldest records are dropped.
+type CloudWatchSink struct {
+	group, stream string
+	opts          CloudWatchOptions
+	seqToken      string // owned by the run goroutine
+	created       bool   // log stream created; owned by the run goroutine
+
+	mu      sync.Mutex
+	queue   []Record
+	dropped uint64
+	err     error         // last send error
+	wakeup  chan struct{} // signals the run goroutine
+	flushch chan chan error
+	closed  bool
+	done    chan struct{}
+}
+
+// NewCloudWatchSink creates a sink sending to the log stream stream of the log group group.
+// When group or stream are empty, $AWS_LAMBDA_LOG_GROUP_NAME and $AWS_LAMBDA_LOG_STREAM_NAME
+// are used. opts may be nil.
+// Returns an error if the region or credentials are not configured.
+func NewCloudWatchSink(group, stream string, opts *CloudWatchOptions) (*CloudWatchSink, error) {
+	s := &CloudWatchSink{
+		group:   group,
+		stream:  stream,
+		wakeup:  make(chan struct{}, 1),
+		flushch: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	envDefault := func(v *string, names ...string) {
+		for _, name := range names {
+			if *v == "" {
+				*v = os.Getenv(name)
+			}
+		}
+	}
+	envDefault(&s.group, "AWS_LAMBDA_LOG_GROUP_NAME")
+	envDefault(&s.stream, "AWS_LAMBDA_LOG_STREAM_NAME")
+	envDefault(&s.opts.Region, "AWS_REGION", "AWS_DEFAULT_REGION")
+	envDefault(&s.opts.AccessKeyID, "AWS_ACCESS_KEY_ID")
+	envDefault(&s.opts.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+	envDefault(&s.opts.SessionToken, "AWS_SESSION_TOKEN")
+	if s.group == "" || s.stream == "" {
+		return nil, fmt.Errorf("log: CloudWatch log group and stream required")
+	}
+	if s.opts.Region == "" {
+		return nil, fmt.Errorf("log: AWS region not configured")
+	}
+	if s.opts.AccessKeyID == "" || s.opts.SecretAccessKey == "" {
+		return nil, fmt.Errorf("log: AWS credentials not configured")
+	}
+	if s.opts.Endpoint == "" {
+		s.opts.Endpoint = "https://logs." + s.opts.Region + ".amazonaws.com"
+	}
+	if s.opts.Client == nil {
+		s.opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if s.opts.Format == nil {
+		s.opts.Format = FormatJSON
+	}
+	if s.opts.BatchSize <= 0 || s.opts.BatchSize > 10000 {
+		s.opts.BatchSize = 1000
+	}
+	if s.opts.FlushInterval <= 0 {
+		s.opts.FlushInterval = 5 * time.Second
+	}
+	if s.opts.MaxQueue < s.opts.BatchSize {
+		s.opts.MaxQueue = 8192
+		if s.opts.MaxQueue < s.opts.BatchSize {
+			s.opts.MaxQueue = s.opts.BatchSize
+		}
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write queues r for sending. Returns the error of the last failed request, if any.
+func (s *CloudWatchSink) Write(r *Record) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return fmt.Errorf("log: CloudWatch sink closed")
+	}
+	if len(s.queue) >= s.opts.MaxQueue {
+		copy(s.queue, s.queue[1:])
+		s.queue = s.queue[:len(s.queue)-1]
+		s.dropped++
+	}
+	s.queue = append(s.queue, *r)
+	if len(s.queue) >= s.opts.BatchSize {
+		s.signal()
+	}
+	err := s.err
+	s.err = nil
+	s.mu.Unlock()
+	return err
+}
+
+// Dropped returns the number of records dropped because the queue was full
+func (s *CloudWatchSink) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Sync sends all queued records and returns the first error encountered
+func (s *CloudWatchSink) Sync() error {
+	ch := make(chan error)
+	select {
+	case s.flushch <- ch:
+		return <-ch
+	case <-s.done:
+		return nil
+	}
+}
+
+// Close sends all queued records and stops the sink
+func (s *CloudWatchSink) Close() error {
+	err := s.Sync()
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.wakeup)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return err
+}
+
+// signal wakes up run without blocking. s.mu must be held, since Close closes s.wakeup.
+func (s *CloudWatchSink) signal() {
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (s *CloudWatchSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case _, ok := <-s.wakeup:
+			s.flush(false)
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			s.flush(true)
+		case ch := <-s.flushch:
+			ch <- s.flush(true)
+		}
+	}
+}
+
+// flush sends queued records in batches. Unless all is true, only full batches are sent.
+func (s *CloudWatchSink) flush(all bool) error {
+	var firstErr error
+	for {
+		s.mu.Lock()
+		n := len(s.queue)
+		if n == 0 || (!all && n < s.opts.BatchSize) {
+			s.mu.Unlock()
+			return firstErr
+		}
+		if n > s.opts.BatchSize {
+			n = s.opts.BatchSize
+		}
+		batch := make([]Record, n)
+		copy(batch, s.queue)
+		s.queue = s.queue[:copy(s.queue, s.queue[n:])]
+		s.mu.Unlock()
+
+		if err := s.put(batch); err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+}
+
+type cloudWatchEvent struct {
+	Timestamp int64  `json:"timestamp"` // milliseconds since the epoch
+	Message   string `json:"message"`
+}
+
+// cloudWatchMaxBatchBytes is the max size of a PutLogEvents request, counted as the sum of
+// the messages plus 26 bytes per event
+const cloudWatchMaxBatchBytes = 1048576
+
+// put sends batch with one or more PutLogEvents requests
+func (s *CloudWatchSink) put(batch []Record) error {
+	events := make([]cloudWatchEvent, len(batch))
+	var buf []byte
+	for i := range batch {
+		buf = buf[:0]
+		s.opts.Format(&buf, &batch[i])
+		events[i] = cloudWatchEvent{
+			Timestamp: batch[i].Time.UnixNano() / int64(time.Millisecond),
+			Message:   strings.TrimSuffix(string(buf), "\n"),
+		}
+	}
+	// events of a request must be in chronological order
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp < events[j].Timestamp })
+	for len(events) > 0 {
+		n, size := 0, 0
+		for n < len(events) {
+			size += len(events[n].Message) + 26
+			if size > cloudWatchMaxBatchBytes && n > 0 {
+				break
+			}
+			n++
+		}
+		if err := s.putEvents(events[:n]); err != nil {
+			return err
+		}
+		events = events[n:]
+	}
+	return nil
+}
+
+func (s *CloudWatchSink) putEvents(events []cloudWatchEvent) error {
+	if !s.created {
+		err := s.call("CreateLogStream", map[string]string{
+			"logGroupName":  s.group,
+			"logStreamName": s.stream,
+		}, nil)
+		if err != nil && !isCloudWatchError(err, "ResourceAlreadyExistsException") {
+			return err
+		}
+		s.created = true
+	}
+	req := struct {
+		LogGroupName  string            `json:"logGroupName"`
+		LogStreamName string            `json:"logStreamName"`
+		LogEvents     []cloudWatchEvent `json:"logEvents"`
+		SequenceToken string            `json:"sequenceToken,omitempty"`
+	}{s.group, s.stream, events, s.seqToken}
+	var res struct {
+		NextSequenceToken string `json:"nextSequenceToken"`
+	}
+	err := s.call("PutLogEvents", &req, &res)
+	if e, ok := err.(*cloudWatchError); ok && e.ExpectedSequenceToken != "" {
+		if e.isType("DataAlreadyAcceptedException") {
+			s.seqToken = e.ExpectedSequenceToken
+			return nil
+		}
+		if e.isType("InvalidSequenceTokenException") {
+			// another writer sent to the stream; retry with the token it expects
+			req.SequenceToken = e.ExpectedSequenceToken
+			err = s.call("PutLogEvents", &req, &res)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	s.seqToken = res.NextSequenceToken
+	return nil
+}
+
+// cloudWatchError is an error response of the CloudWatch Logs API
+type cloudWatchError struct {
+	Type                  string `json:"__type"`
+	Message               string `json:"message"`
+	ExpectedSequenceToken string `json:"expectedSequenceToken"`
+}
+
+func (e *cloudWatchError) Error() string {
+	return fmt.Sprintf("log: CloudWatch: %s: %s", e.Type, e.Message)
+}
+
+// isType returns true if e is of type typ. The type may be prefixed by a namespace, like
+// "com.amazonaws.logs#InvalidSequenceTokenException"
+func (e *cloudWatchError) isType(typ string) bool {
+	return e.Type == typ || strings.HasSuffix(e.Type, "#"+typ)
+}
+
+func isCloudWatchError(err error, typ string) bool {
+	e, ok := err.(*cloudWatchError)
+	return ok && e.isType(typ)
+}
+
+// call makes a CloudWatch Logs API request
+func (s *CloudWatchSink) call(action string, req, res interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	hreq, err := http.NewRequest("POST", s.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	hreq.Header.Set("X-Amz-Target", "Logs_20140328."+action)
+	signAWSv4(hreq, body, time.Now(), s.opts.Region, "logs",
+		s.opts.AccessKeyID, s.opts.SecretAccessKey, s.opts.SessionToken)
+	hres, err := s.opts.Client.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer hres.Body.Close()
+	data, err := ioutil.ReadAll(hres.Body)
+	if err != nil {
+		return err
+	}
+	if hres.StatusCode < 200 || hres.StatusCode > 299 {
+		e := &cloudWatchError{}
+		if json.Unmarshal(data, e) != nil || e.Type == "" {
+			return fmt.Errorf("log: CloudWatch %s failed: %s", action, hres.Status)
+		}
+		return e
+	}
+	if res != nil && len(data) > 0 {
+		return json.Unmarshal(data, res)
+	}
+	return nil
+}
+
+// signAWSv4 signs req with AWS Signature Version 4.
+// See https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func signAWSv4(req *http.Request, body []byte, t time.Time, region, service,
+	accessKeyID, secretAccessKey, sessionToken string) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+	bodyHash := sha256.Sum256(body)
+
+	names := []string{"host"}
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := req.URL.Host
+		if name != "host" {
+			value = strings.TrimSpace(req.Header.Get(name))
+		}
+		canonicalHeaders.WriteString(name + ":" + value + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" +
+		hex.EncodeToString(requestHash[:])
+
+	hmacSHA256 := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// FormatGoogleCloud is a Formatter which formats records as JSON objects in the structured
+// logging format of Google Cloud Logging, one per line. Cloud Run, Cloud Functions and GKE
+// ingest such lines written to stdout or stderr, so there is no need for an API client:
+//
+//   logger.SetWriter(log.NewTeeWriter(log.TeeSink{W: os.Stdout, Format: log.FormatGoogleCloud}))
+//
+// The level is mapped to "severity" and fields are included as top-level properties of the
+// JSON payload. When $GOOGLE_CLOUD_PROJECT is set, the trace ID of records logged with a
+// context (see LogContext) is included, correlating log entries with Cloud Trace.
+func FormatGoogleCloud(buf *[]byte, r *Record) {
+	entry := make(map[string]interface{}, len(r.Fields)+6)
+	for k, v := range r.Fields {
+		entry[k] = v
+	}
+	entry["severity"] = googleCloudSeverity(r.Level)
+	entry["message"] = r.Prefix + r.Msg
+	if !r.Time.IsZero() {
+		entry["time"] = r.Time.Format(time.RFC3339Nano)
+	}
+	if r.Caller != "" {
+		loc := map[string]string{"file": r.Caller}
+		if i := strings.LastIndexByte(r.Caller, ':'); i != -1 {
+			if _, err := strconv.Atoi(r.Caller[i+1:]); err == nil {
+				loc["file"], loc["line"] = r.Caller[:i], r.Caller[i+1:]
+			}
+		}
+		entry["logging.googleapis.com/sourceLocation"] = loc
+	}
+	if project := os.Getenv("GOOGLE_CLOUD_PROJECT"); project != "" && r.TraceID != "" {
+		entry["logging.googleapis.com/trace"] = "projects/" + project + "/traces/" + r.TraceID
+		entry["logging.googleapis.com/spanId"] = r.SpanID
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data, _ = json.Marshal(err.Error())
+	}
+	*buf = append(*buf, data...)
+	*buf = append(*buf, '\n')
+}
+
+// googleCloudSeverity returns the Google Cloud Logging severity of level
+func googleCloudSeverity(level Level) string {
+	switch level {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARNING"
+	case LevelError:
+		return "ERROR"
+	}
+	return "DEFAULT"
+}
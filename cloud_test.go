@@ -0,0 +1,157 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestSignAWSv4(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	// "get-vanilla" from the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	signAWSv4(req, nil, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC), "us-east-1", "service",
+		"AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "")
+	assert.Eq("authorization", req.Header.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, "+
+			"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31")
+}
+
+func TestCloudWatchSink(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var mu sync.Mutex
+	var actions []string
+	var tokens []string
+	var messages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+		actions = append(actions, action)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(403)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		switch action {
+		case "CreateLogStream":
+			w.WriteHeader(400)
+			w.Write([]byte(`{"__type":"ResourceAlreadyExistsException","message":"exists"}`))
+		case "PutLogEvents":
+			var req struct {
+				LogEvents []struct {
+					Timestamp int64
+					Message   string
+				}
+				SequenceToken string
+			}
+			json.Unmarshal(body, &req)
+			if len(tokens) == 1 && req.SequenceToken == "t1" {
+				// simulate another writer
+				tokens = append(tokens, req.SequenceToken)
+				w.WriteHeader(400)
+				w.Write([]byte(`{"__type":"com.amazonaws.logs#InvalidSequenceTokenException",` +
+					`"message":"bad token","expectedSequenceToken":"t2"}`))
+				return
+			}
+			tokens = append(tokens, req.SequenceToken)
+			for _, e := range req.LogEvents {
+				messages = append(messages, e.Message)
+			}
+			w.Write([]byte(`{"nextSequenceToken":"t` + string(rune('1'+len(tokens)-1)) + `"}`))
+		}
+	}))
+	defer srv.Close()
+
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "" {
+		_, err := NewCloudWatchSink("g", "s", &CloudWatchOptions{Region: "us-east-1"})
+		assert.Err("no credentials", "credentials", err)
+	}
+
+	sink, err := NewCloudWatchSink("g", "s", &CloudWatchOptions{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+	})
+	assert.NoErr("NewCloudWatchSink", err)
+	t0 := time.Unix(1600000000, 0)
+	sink.Write(&Record{Level: LevelInfo, Time: t0.Add(time.Second), Msg: "b"})
+	sink.Write(&Record{Level: LevelInfo, Time: t0, Msg: "a"})
+	assert.NoErr("sync", sink.Sync())
+	sink.Write(&Record{Level: LevelWarn, Time: t0, Msg: "c"})
+	assert.NoErr("close", sink.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Eq("actions", strings.Join(actions, ","),
+		"CreateLogStream,PutLogEvents,PutLogEvents,PutLogEvents")
+	assert.Eq("tokens", strings.Join(tokens, ","), ",t1,t2")
+	assert.Eq("messages", len(messages), 3)
+	var r Record
+	assert.NoErr("json message", json.Unmarshal([]byte(messages[0]), &r))
+	assert.Eq("chronological order", r.Msg, "a")
+}
+
+func TestFormatGoogleCloud(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf []byte
+	FormatGoogleCloud(&buf, &Record{
+		Level:  LevelWarn,
+		Time:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Prefix: "[db] ",
+		Msg:    "slow query",
+		Fields: map[string]string{"ms": "300"},
+		Caller: "db/query.go:12",
+	})
+	assert.Ok("newline", strings.HasSuffix(string(buf), "}\n"))
+	var entry map[string]interface{}
+	assert.NoErr("json", json.Unmarshal(buf, &entry))
+	assert.Eq("severity", entry["severity"], "WARNING")
+	assert.Eq("message", entry["message"], "[db] slow query")
+	assert.Eq("time", entry["time"], "2020-01-02T03:04:05Z")
+	assert.Eq("field", entry["ms"], "300")
+	loc := entry["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	assert.Eq("file", loc["file"], "db/query.go")
+	assert.Eq("line", loc["line"], "12")
+	assert.Eq("error severity", googleCloudSeverity(LevelError), "ERROR")
+}
+
+func TestCloudWatchSinkWriteClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	for i := 0; i < 10; i++ {
+		sink, err := NewCloudWatchSink("g", "s", &CloudWatchOptions{
+			Region:          "us-east-1",
+			AccessKeyID:     "AKID",
+			SecretAccessKey: "secret",
+			Endpoint:        srv.URL,
+			BatchSize:       1,
+		})
+		testutil.NewAssert(t).NoErr("NewCloudWatchSink", err)
+		// writes racing with Close must not send on the closed wakeup channel
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					sink.Write(&Record{Level: LevelInfo, Time: time.Now(), Msg: "x"})
+				}
+			}()
+		}
+		sink.Close()
+		wg.Wait()
+	}
+}
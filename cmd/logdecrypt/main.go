@@ -0,0 +1,77 @@
+// Command logdecrypt decrypts log files written with encryption (see log.FileOptions.Encrypt)
+// and generates key pairs for them:
+//
+//   logdecrypt -genkey app        # writes app.key (private) and app.pub (public)
+//   logdecrypt -key app.key app.log app.log.1 | less
+//   logdecrypt -key app.key < app.log | logpretty
+//
+// Keys are stored hex-encoded. The contents of app.pub are passed to log.FileOptions.Encrypt
+// after decoding, e.g. with hex.DecodeString.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/rsms/go-log"
+)
+
+func main() {
+	keyfile := flag.String("key", "", "file with the private key")
+	genkey := flag.String("genkey", "", "generate a key pair in `name`.key and name.pub")
+	flag.Parse()
+
+	if *genkey != "" {
+		pub, priv, err := log.GenerateEncryptionKey()
+		if err == nil {
+			err = ioutil.WriteFile(*genkey+".key", []byte(hex.EncodeToString(priv)+"\n"), 0600)
+		}
+		if err == nil {
+			err = ioutil.WriteFile(*genkey+".pub", []byte(hex.EncodeToString(pub)+"\n"), 0644)
+		}
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if *keyfile == "" {
+		fmt.Fprintln(os.Stderr, "logdecrypt: missing -key")
+		flag.Usage()
+		os.Exit(2)
+	}
+	data, err := ioutil.ReadFile(*keyfile)
+	if err != nil {
+		fatal(err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		fatal(fmt.Errorf("%s: %v", *keyfile, err))
+	}
+	if flag.NArg() == 0 {
+		if _, err := io.Copy(os.Stdout, log.NewDecryptingReader(os.Stdin, key)); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fatal(err)
+		}
+		_, err = io.Copy(os.Stdout, log.NewDecryptingReader(f, key))
+		f.Close()
+		if err != nil {
+			fatal(fmt.Errorf("%s: %v", name, err))
+		}
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "logdecrypt: %v\n", err)
+	os.Exit(1)
+}
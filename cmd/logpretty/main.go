@@ -0,0 +1,45 @@
+// Command logpretty reads JSON or logfmt logs from stdin and writes them to stdout in the
+// human-readable, colorized format of go-log:
+//
+//   kubectl logs -f myapp | logpretty
+//   logpretty -date -utc < app.log.json
+//
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rsms/go-log"
+)
+
+func main() {
+	date := flag.Bool("date", false, "include dates")
+	utc := flag.Bool("utc", false, "show times in UTC rather than the local time zone")
+	color := flag.String("color", "auto", "use colors: auto, always or never")
+	flag.Parse()
+
+	var feats log.Features = log.FTime | log.FMilliseconds |
+		log.FPrefixDebug | log.FPrefixInfo | log.FPrefixWarn | log.FPrefixError
+	if *date {
+		feats |= log.FDate
+	}
+	if *utc {
+		feats |= log.FUTC
+	}
+	switch *color {
+	case "auto":
+		feats |= log.FColorAuto
+	case "always":
+		feats |= log.FColor
+	case "never":
+	default:
+		fmt.Fprintf(os.Stderr, "logpretty: invalid -color %q\n", *color)
+		os.Exit(2)
+	}
+	if err := log.Prettify(os.Stdout, os.Stdin, feats); err != nil {
+		fmt.Fprintf(os.Stderr, "logpretty: %v\n", err)
+		os.Exit(1)
+	}
+}
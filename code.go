@@ -0,0 +1,31 @@
+package log
+
+// ErrorC logs an error with a code, like "E1042", which identifies the kind of error
+// independently of the wording of the message. In text output the code follows the prefix:
+//
+//   logger.ErrorC("E1042", "disk %s is full", disk)
+//   // "[error] E1042 disk /dev/sda1 is full"
+//
+// Structured output (e.g. FormatJSON) has the code in the field "code", so that support teams
+// and alerting rules can key off stable codes rather than message strings.
+func (l *Logger) ErrorC(code, format string, v ...interface{}) {
+	if l.GetLevel() <= LevelError {
+		l.logC(LevelError, code, format, v...)
+	}
+}
+
+// WarnC is like ErrorC but logs at LevelWarn
+func (l *Logger) WarnC(code, format string, v ...interface{}) {
+	if l.GetLevel() <= LevelWarn {
+		l.logC(LevelWarn, code, format, v...)
+	}
+}
+
+func (l *Logger) logC(level Level, code, format string, v ...interface{}) {
+	m := l.newRecord(level)
+	m.code = code
+	if !m.deferf(format, v) {
+		m.appendf(format, v...)
+	}
+	l.submit(m)
+}
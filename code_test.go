@@ -0,0 +1,27 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestErrorC(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "[db]", LevelInfo, FPrefixWarn|FPrefixError)
+	l.ErrorC("E1042", "disk %s is full", "sda1")
+	l.WarnC("W7", "slow")
+	assert.NoErr("sync", l.Sync())
+	assert.Eq("text", buf.String(), "[error] [db] E1042 disk sda1 is full\n[warn] [db] W7 slow\n")
+
+	sink := NewMemorySink(10)
+	l.SetWriter(sink)
+	l.WithFields("disk", "sda1").ErrorC("E1042", "disk full")
+	assert.NoErr("close", l.Close())
+	r := sink.Last(1)[0]
+	assert.Eq("msg", r.Msg, "disk full")
+	assert.Eq("code field", r.Fields["code"], "E1042")
+	assert.Eq("other field", r.Fields["disk"], "sda1")
+}
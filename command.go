@@ -0,0 +1,98 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// maxCommandLine is the length at which CommandOutput logs a line even if it does not end with
+// a newline, which bounds the memory used for output without line breaks
+const maxCommandLine = 64 * 1024
+
+// CommandOutput returns a writer which logs each line written to it as a message of the given
+// level, with prefix prepended to it. It is meant to be used as the Stdout or Stderr of an
+// exec.Cmd (see RunAndLog). Close logs the last line if it did not end with a newline.
+//
+//   cmd := exec.Command("make", "all")
+//   cmd.Stderr = logger.CommandOutput(log.LevelWarn, "make: ")
+//
+func (l *Logger) CommandOutput(level Level, prefix string) io.WriteCloser {
+	return &commandWriter{l: l, level: level, prefix: prefix}
+}
+
+// RunAndLog runs cmd, logging each line it writes to stdout with LevelInfo and to stderr with
+// LevelWarn. Streams which are already set, e.g. to a writer from CommandOutput with a prefix,
+// are left as they are. It returns the error of cmd.Run.
+//
+//   err := logger.SubLogger("[git]").RunAndLog(exec.Command("git", "fetch"))
+//
+func (l *Logger) RunAndLog(cmd *exec.Cmd) error {
+	var writers []io.Closer
+	if cmd.Stdout == nil {
+		w := l.CommandOutput(LevelInfo, "")
+		cmd.Stdout = w
+		writers = append(writers, w)
+	}
+	if cmd.Stderr == nil {
+		w := l.CommandOutput(LevelWarn, "")
+		cmd.Stderr = w
+		writers = append(writers, w)
+	}
+	err := cmd.Run()
+	for _, w := range writers {
+		w.Close()
+	}
+	return err
+}
+
+type commandWriter struct {
+	l      *Logger
+	level  Level
+	prefix string
+	mu     sync.Mutex
+	buf    []byte // incomplete line
+}
+
+func (w *commandWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		end := i
+		if i == -1 {
+			end = len(p)
+		}
+		if room := maxCommandLine - len(w.buf); end > room {
+			w.buf = append(w.buf, p[:room]...)
+			w.flush()
+			p = p[room:]
+			continue
+		}
+		w.buf = append(w.buf, p[:end]...)
+		if i == -1 {
+			break
+		}
+		w.flush()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+func (w *commandWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.flush()
+	}
+	return nil
+}
+
+// flush logs w.buf as a line. w.mu must be held.
+func (w *commandWriter) flush() {
+	line := bytes.TrimSuffix(w.buf, []byte{'\r'})
+	w.l.Log(w.level, "%s%s", w.prefix, string(line))
+	w.buf = w.buf[:0]
+}
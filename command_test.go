@@ -0,0 +1,57 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestCommandOutput(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixWarn)
+	defer l.Close()
+
+	cw := l.CommandOutput(LevelWarn, "out: ")
+	fmt.Fprint(cw, "a\nb")
+	fmt.Fprint(cw, "c\r\n\nd")
+	cw.Close()
+	l.Sync()
+	assert.Eq("output", w.String(), "[warn] out: a\n[warn] out: bc\n[warn] out: \n[warn] out: d\n")
+
+	w.Reset()
+	cw = l.CommandOutput(LevelInfo, "")
+	fmt.Fprint(cw, strings.Repeat("x", maxCommandLine+1))
+	cw.Close()
+	l.Sync()
+	assert.Eq("long line split", strings.Count(w.String(), "\n"), 2)
+}
+
+func TestRunAndLog(t *testing.T) {
+	if os.Getenv("GO_LOG_TEST_COMMAND") == "1" {
+		fmt.Fprint(os.Stdout, "hello\nworld")
+		fmt.Fprintln(os.Stderr, "oops")
+		os.Exit(3)
+	}
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixWarn)
+	defer l.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunAndLog$")
+	cmd.Env = append(os.Environ(), "GO_LOG_TEST_COMMAND=1")
+	err := l.SubLogger("[cmd]").RunAndLog(cmd)
+	exitErr, ok := err.(*exec.ExitError)
+	assert.Ok("exit error", ok)
+	assert.Eq("exit code", exitErr.ExitCode(), 3)
+	l.Sync()
+	out := w.String()
+	assert.Ok("stdout", strings.Contains(out, "[cmd] hello\n"))
+	assert.Ok("stdout without newline", strings.HasSuffix(out, "[cmd] world\n")) // logged by Close
+	assert.Ok("stderr", strings.Contains(out, "[warn] [cmd] oops\n"))
+}
@@ -0,0 +1,39 @@
+package log
+
+// DebugIf logs a debug message if cond is true. The message is not formatted otherwise.
+func (l *Logger) DebugIf(cond bool, format string, v ...interface{}) {
+	if cond {
+		l.LogDebug(1, format, v...)
+	}
+}
+
+// WarnIfErr logs "msg: err" as a warning if err is not nil
+func (l *Logger) WarnIfErr(err error, msg string) {
+	if err != nil && l.GetLevel() <= LevelWarn {
+		l.log(LevelWarn, "%s: %v", msg, err)
+	}
+}
+
+// ErrorIfErr logs "msg: err" as an error if err is not nil
+func (l *Logger) ErrorIfErr(err error, msg string) {
+	if err != nil && l.GetLevel() <= LevelError {
+		l.log(LevelError, "%s: %v", msg, err)
+	}
+}
+
+// CheckErr logs err as an error and returns true if err is not nil. This shortens the common
+// pattern of logging and bailing out on errors:
+//
+//   if logger.CheckErr(f.Close()) {
+//     return
+//   }
+//
+func (l *Logger) CheckErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	if l.GetLevel() <= LevelError {
+		l.log(LevelError, "%v", err)
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestConditional(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	var feats Features = FPrefixDebug | FPrefixWarn | FPrefixError
+	l := NewLogger(w, "", LevelDebug, feats)
+	defer l.Close()
+
+	err := errors.New("oops")
+	l.DebugIf(false, "no")
+	l.DebugIf(true, "yes %d", 1)
+	l.WarnIfErr(nil, "no")
+	l.WarnIfErr(err, "saving")
+	l.ErrorIfErr(nil, "no")
+	l.ErrorIfErr(err, "loading")
+	assert.Ok("CheckErr(nil)", !l.CheckErr(nil))
+	assert.Ok("CheckErr(err)", l.CheckErr(err))
+	l.Sync()
+	assert.Eq("output", w.String(), ""+
+		"[debug] yes 1\n"+
+		"[warn] saving: oops\n"+
+		"[error] loading: oops\n"+
+		"[error] oops\n")
+}
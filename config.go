@@ -0,0 +1,187 @@
+package log
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Config describes the configuration of RootLogger and named loggers (see GetLogger).
+// It is usually loaded from a JSON file with LoadConfig:
+//
+//   {
+//     "level": "info",
+//     "features": "default",
+//     "output": { "path": "/var/log/app.log", "max_size": 10485760, "max_backups": 5 },
+//     "loggers": {
+//       "db":          { "level": "warn" },
+//       "server.http": { "level": "debug", "output": { "path": "stderr", "format": "json" } }
+//     }
+//   }
+//
+type Config struct {
+	LoggerConfig                         // RootLogger
+	Loggers      map[string]LoggerConfig `json:"loggers,omitempty"` // named loggers
+}
+
+// LoggerConfig is the configuration of one logger. Settings which are nil are left unchanged.
+type LoggerConfig struct {
+	Level    *Level        `json:"level,omitempty"`    // e.g. "info", or "inherit"
+	Features *Features     `json:"features,omitempty"` // e.g. "default"; see ParseFeatures
+	Output   *OutputConfig `json:"output,omitempty"`
+}
+
+// OutputConfig describes where and how a logger writes messages
+type OutputConfig struct {
+	Path       string `json:"path"`                  // file path, "stdout" or "stderr"
+	Format     string `json:"format,omitempty"`      // "text" (the default) or "json"
+	MaxSize    int64  `json:"max_size,omitempty"`    // see FileOptions
+	MaxBackups int    `json:"max_backups,omitempty"` // see FileOptions
+	Compress   string `json:"compress,omitempty"`    // "gzip", or "" for no compression
+	Lock       bool   `json:"lock,omitempty"`        // see FileOptions
+}
+
+// configOutputs holds the writers opened by ApplyConfig, which are closed when replaced
+var configOutputs = struct {
+	sync.Mutex
+	m map[*Logger]io.Closer
+}{m: make(map[*Logger]io.Closer)}
+
+// LoadConfig reads a JSON configuration file and applies it with ApplyConfig
+func LoadConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return ApplyConfig(cfg)
+}
+
+// ApplyConfig configures RootLogger and named loggers according to cfg.
+// Outputs are opened before anything is changed, so if an output can't be opened an error is
+// returned and no logger is changed. Files opened by a previous call are closed when replaced.
+//
+// Named loggers without an output of their own, whether created before or after the call,
+// write to the output of their parent and thus, ultimately, to that of RootLogger (see
+// GetLogger). Note that a sub-logger created with SubLogger (rather than GetLogger) gets its
+// writer from its parent when it is created and so is not affected by a later change of the
+// parent's output.
+func ApplyConfig(cfg Config) error {
+	type change struct {
+		l   *Logger
+		lc  LoggerConfig
+		w   io.Writer
+		own io.Closer // non-nil if w was opened by us
+	}
+	names := make([]string, 0, len(cfg.Loggers))
+	for name := range cfg.Loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names) // parents before children
+
+	changes := make([]change, 0, len(names)+1)
+	changes = append(changes, change{l: RootLogger, lc: cfg.LoggerConfig})
+	for _, name := range names {
+		changes = append(changes, change{l: GetLogger(name), lc: cfg.Loggers[name]})
+	}
+	for i := range changes {
+		c := &changes[i]
+		if c.lc.Output == nil {
+			continue
+		}
+		w, own, err := openConfigOutput(c.lc.Output)
+		if err != nil {
+			for _, c := range changes[:i] {
+				if c.own != nil {
+					c.own.Close()
+				}
+			}
+			return err
+		}
+		c.w, c.own = w, own
+	}
+
+	configOutputs.Lock()
+	defer configOutputs.Unlock()
+	for _, c := range changes {
+		if c.lc.Level != nil {
+			c.l.SetLevel(*c.lc.Level)
+		}
+		if c.lc.Features != nil {
+			c.l.SetFeatures(*c.lc.Features)
+		}
+		if c.w != nil {
+			c.l.SetWriter(c.w)
+			if prev := configOutputs.m[c.l]; prev != nil {
+				prev.Close()
+			}
+			delete(configOutputs.m, c.l)
+			if c.own != nil {
+				configOutputs.m[c.l] = c.own
+			}
+		}
+	}
+	return nil
+}
+
+// openConfigOutput opens the writer described by oc
+func openConfigOutput(oc *OutputConfig) (io.Writer, io.Closer, error) {
+	var w io.Writer
+	var own io.Closer
+	switch oc.Path {
+	case "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	case "":
+		return nil, nil, fmt.Errorf("log output is missing a path")
+	default:
+		opts := &FileOptions{MaxSize: oc.MaxSize, MaxBackups: oc.MaxBackups, Lock: oc.Lock}
+		switch oc.Compress {
+		case "":
+		case "gzip":
+			opts.Compress = Gzip(gzip.DefaultCompression)
+		default:
+			return nil, nil, fmt.Errorf("unknown log output compression %q", oc.Compress)
+		}
+		f, err := OpenFile(oc.Path, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, own = f, f
+	}
+	switch oc.Format {
+	case "", "text":
+	case "json":
+		w = NewTeeWriter(TeeSink{W: w, Format: FormatJSON})
+	default:
+		if own != nil {
+			own.Close()
+		}
+		return nil, nil, fmt.Errorf("unknown log output format %q", oc.Format)
+	}
+	return w, own, nil
+}
+
+// WatchConfig loads the configuration file at path with LoadConfig and loads it again whenever
+// the process receives SIGHUP, so that logging can be reconfigured without a restart.
+// Errors from reloading are logged to RootLogger. Call stop to stop watching for SIGHUP.
+// On platforms without SIGHUP, like js/wasm and Plan 9, the file is only loaded once.
+func WatchConfig(path string) (stop func(), err error) {
+	if err := LoadConfig(path); err != nil {
+		return nil, err
+	}
+	return onHangup(func() {
+		if err := LoadConfig(path); err != nil {
+			RootLogger.Error("failed to reload log config: %v", err)
+		}
+	}), nil
+}
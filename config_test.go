@@ -0,0 +1,78 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestConfig(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir, err := ioutil.TempDir("", "log-test")
+	assert.NoErr("TempDir", err)
+	defer os.RemoveAll(dir)
+
+	rootLevel, rootFeatures, rootWriter := RootLogger.GetLevel(), RootLogger.GetFeatures(),
+		RootLogger.Writer()
+	defer func() {
+		RootLogger.SetLevel(rootLevel)
+		RootLogger.SetFeatures(rootFeatures)
+		RootLogger.SetWriter(rootWriter)
+		configOutputs.Lock()
+		for l, c := range configOutputs.m { // so that ReopenFiles doesn't reopen them
+			c.Close()
+			delete(configOutputs.m, l)
+		}
+		configOutputs.Unlock()
+	}()
+
+	early := GetLogger("cfgtest2") // created before the config is applied
+	cfgfile := filepath.Join(dir, "log.json")
+	ioutil.WriteFile(cfgfile, []byte(`{
+		"level": "warn",
+		"features": "prefixwarn, prefixinfo",
+		"output": { "path": "`+filepath.Join(dir, "root.log")+`" },
+		"loggers": {
+			"cfgtest": { "level": "info", "output": { "path": "`+filepath.Join(dir, "a.log")+`", "format": "json" } },
+			"cfgtest2": { "level": "warn" }
+		}
+	}`), 0644)
+	assert.NoErr("LoadConfig", LoadConfig(cfgfile))
+
+	Info("not logged")
+	Warn("root")
+	GetLogger("cfgtest").Info("hello")
+	early.Info("not logged")
+	early.Warn("early")
+	GetLogger("cfgtest3").Warn("late") // created after the config is applied
+	Sync()
+	read := func(name string) string {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+	assert.Eq("root.log", read("root.log"), ""+
+		"[warn] root\n"+
+		"[warn] [cfgtest2] early\n"+
+		"[warn] [cfgtest3] late\n")
+	assert.Ok("a.log %q", strings.Contains(read("a.log"), `"msg":"hello"`), read("a.log"))
+
+	// errors leave loggers unchanged
+	level := Level(LevelError)
+	err = ApplyConfig(Config{LoggerConfig: LoggerConfig{
+		Level:  &level,
+		Output: &OutputConfig{Path: "stderr", Format: "xml"},
+	}})
+	assert.Err("bad format", "unknown log output format", err)
+	assert.Eq("level", RootLogger.GetLevel(), LevelWarn)
+	err = ApplyConfig(Config{LoggerConfig: LoggerConfig{
+		Output: &OutputConfig{Path: filepath.Join(dir, "b.log"), Compress: "lz4"},
+	}})
+	assert.Err("bad compression", "unknown log output compression", err)
+
+	_, err = ParseFeatures("time,bogus")
+	assert.Err("bad feature", `unknown log feature "bogus"`, err)
+}
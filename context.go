@@ -0,0 +1,107 @@
+package log
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// TraceContext identifies a span of a distributed trace, as defined by W3C Trace Context and
+// used by OpenTelemetry.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// IsValid returns true if tc has a non-zero trace ID
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{}
+}
+
+type traceContextKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying tc.
+// The default trace extractor (see SetTraceExtractor) returns tc for the new context.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+var traceExtractor atomic.Value // func(context.Context) TraceContext
+
+// SetTraceExtractor sets the function used by the context-aware logging functions like
+// LogContext to find the trace context of a context.Context. The default extractor only
+// knows about contexts created with ContextWithTrace. To use OpenTelemetry spans:
+//
+//   log.SetTraceExtractor(func(ctx context.Context) log.TraceContext {
+//     sc := trace.SpanContextFromContext(ctx)
+//     return log.TraceContext{TraceID: sc.TraceID(), SpanID: sc.SpanID()}
+//   })
+//
+func SetTraceExtractor(f func(ctx context.Context) TraceContext) {
+	traceExtractor.Store(f)
+}
+
+// TraceFromContext returns the trace context of ctx, using the trace extractor
+func TraceFromContext(ctx context.Context) TraceContext {
+	if f, ok := traceExtractor.Load().(func(context.Context) TraceContext); ok && f != nil {
+		return f(ctx)
+	}
+	tc, _ := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc
+}
+
+// LogContext is like Log but associates the message with the trace context of ctx, which
+// sinks like OTLPExporter use to correlate log records with traces, and includes the fields
+// of ctx (see ContextWithFields).
+//
+// If the logger's queue is full, which happens when its writer can't keep up, LogContext waits
+// for room in the queue only until ctx is done; then the message is dropped (and counted in
+// Metrics.Dropped). A request handler logging with the request's context thus never blocks
+// beyond the request's deadline because of a slow writer. This does not apply to messages
+// which are written synchronously (see FSync).
+func (l *Logger) LogContext(ctx context.Context, level Level, format string, v ...interface{}) {
+	if l.GetLevel() <= level {
+		m := l.newRecord(level)
+		m.setContext(ctx)
+		m.appendf(format, v...)
+		l.submit(m)
+	}
+}
+
+func (l *Logger) ErrorContext(ctx context.Context, format string, v ...interface{}) {
+	l.LogContext(ctx, LevelError, format, v...)
+}
+
+func (l *Logger) WarnContext(ctx context.Context, format string, v ...interface{}) {
+	l.LogContext(ctx, LevelWarn, format, v...)
+}
+
+func (l *Logger) InfoContext(ctx context.Context, format string, v ...interface{}) {
+	l.LogContext(ctx, LevelInfo, format, v...)
+}
+
+func (l *Logger) DebugContext(ctx context.Context, format string, v ...interface{}) {
+	if l.GetLevel() <= LevelDebug {
+		m := l.newRecord(LevelDebug)
+		m.setContext(ctx)
+		m.appendf(format, v...)
+		if l.GetFeatures()&FDebugOrigin != 0 {
+			m.appendOrigin(1)
+		}
+		l.submit(m)
+	}
+}
+
+// setContext associates m with the trace context and fields of ctx
+func (m *logRecord) setContext(ctx context.Context) {
+	m.trace = TraceFromContext(ctx)
+	if ctx.Done() != nil {
+		m.ctx = ctx
+	}
+	if fields := FieldsFromContext(ctx); len(fields) > 0 {
+		if len(m.fields) == 0 {
+			m.fields = fields
+		} else {
+			m.fields = append(append(make([]Field, 0, len(m.fields)+len(fields)), m.fields...), fields...)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestLogContextCancel(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	bw := &blockingWriter{unblock: make(chan struct{})}
+	l := New(Options{Writer: bw, QueueSize: 1})
+
+	// fill the queue (and the writeLoop)
+	ctx, cancel := context.WithCancel(context.Background())
+	for l.Metrics().QueueDepth < 1 {
+		l.Info("filler")
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	done := make(chan struct{})
+	go func() {
+		l.InfoContext(ctx, "abandoned")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("LogContext blocked after ctx was cancelled")
+	}
+	assert.Eq("dropped", l.Metrics().Dropped, uint64(1))
+	close(bw.unblock)
+	assert.NoErr("close", l.Close())
+}
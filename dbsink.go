@@ -0,0 +1,285 @@
+package log
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// DBOptions configures a DBSink. The zero value of each field selects its default.
+type DBOptions struct {
+	Dialect       string        // "sqlite" (default) or "postgres"
+	Table         string        // name of the table (default "logs")
+	BatchSize     int           // max records inserted per transaction (default 256)
+	FlushInterval time.Duration // max time a record waits before being inserted (default 1s)
+	MaxQueue      int           // max records waiting to be inserted (default 8192)
+	Retention     time.Duration // delete records older than this, hourly (0 = keep forever)
+}
+
+// DBSink is a Sink which inserts records into a database table, for small deployments that
+// want queryable logs without a log management system. Records are inserted in batches from
+// a background goroutine. The table is created if it does not exist, with the columns
+//
+//   ts      timestamp  time of the record
+//   level   text       name of the level, e.g. "info"
+//   prefix  text
+//   msg     text
+//   fields  JSONB (postgres) or JSON text (sqlite); NULL if the record has no fields
+//
+// DBSink uses database/sql and does not depend on a driver; open db with the driver of your
+// choice:
+//
+//   db, err := sql.Open("sqlite3", "logs.db")
+//   ...
+//   sink, err := log.NewDBSink(db, &log.DBOptions{Retention: 7 * 24 * time.Hour})
+//   ...
+//   logger.SetWriter(log.SinkWriter(sink))
+//
+// When the queue is full, because the database is slow or unreachable, the oldest records
+// are dropped.
+type DBSink struct {
+	db     *sql.DB
+	opts   DBOptions
+	insert string // INSERT statement
+	sweep  string // DELETE statement for retention
+
+	mu      sync.Mutex
+	queue   []Record
+	dropped uint64
+	err     error         // last insert error
+	wakeup  chan struct{} // signals the run goroutine
+	flushch chan chan error
+	closed  bool
+	done    chan struct{}
+}
+
+var sqlIdentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewDBSink creates a sink inserting into db, creating the table if needed. opts may be nil.
+func NewDBSink(db *sql.DB, opts *DBOptions) (*DBSink, error) {
+	s := &DBSink{
+		db:      db,
+		wakeup:  make(chan struct{}, 1),
+		flushch: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Dialect == "" {
+		s.opts.Dialect = "sqlite"
+	}
+	if s.opts.Table == "" {
+		s.opts.Table = "logs"
+	}
+	if s.opts.BatchSize <= 0 {
+		s.opts.BatchSize = 256
+	}
+	if s.opts.FlushInterval <= 0 {
+		s.opts.FlushInterval = time.Second
+	}
+	if s.opts.MaxQueue < s.opts.BatchSize {
+		s.opts.MaxQueue = 8192
+		if s.opts.MaxQueue < s.opts.BatchSize {
+			s.opts.MaxQueue = s.opts.BatchSize
+		}
+	}
+	if !sqlIdentRe.MatchString(s.opts.Table) {
+		return nil, fmt.Errorf("log: invalid table name %q", s.opts.Table)
+	}
+	t := s.opts.Table
+	var schema []string
+	switch s.opts.Dialect {
+	case "sqlite":
+		schema = []string{
+			"CREATE TABLE IF NOT EXISTS " + t + " (id INTEGER PRIMARY KEY, ts TIMESTAMP NOT NULL, " +
+				"level TEXT NOT NULL, prefix TEXT NOT NULL, msg TEXT NOT NULL, fields TEXT)",
+			"CREATE INDEX IF NOT EXISTS " + t + "_ts ON " + t + " (ts)",
+		}
+		s.insert = "INSERT INTO " + t + " (ts, level, prefix, msg, fields) VALUES (?, ?, ?, ?, ?)"
+		s.sweep = "DELETE FROM " + t + " WHERE ts < ?"
+	case "postgres":
+		schema = []string{
+			"CREATE TABLE IF NOT EXISTS " + t + " (id BIGSERIAL PRIMARY KEY, " +
+				"ts TIMESTAMPTZ NOT NULL, level TEXT NOT NULL, prefix TEXT NOT NULL, msg TEXT NOT NULL, " +
+				"fields JSONB)",
+			"CREATE INDEX IF NOT EXISTS " + t + "_ts ON " + t + " (ts)",
+		}
+		s.insert = "INSERT INTO " + t + " (ts, level, prefix, msg, fields) VALUES ($1, $2, $3, $4, $5)"
+		s.sweep = "DELETE FROM " + t + " WHERE ts < $1"
+	default:
+		return nil, fmt.Errorf("log: unsupported database dialect %q", s.opts.Dialect)
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write queues r for inserting. Returns the error of the last failed insert, if any.
+func (s *DBSink) Write(r *Record) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return fmt.Errorf("log: database sink closed")
+	}
+	if len(s.queue) >= s.opts.MaxQueue {
+		copy(s.queue, s.queue[1:])
+		s.queue = s.queue[:len(s.queue)-1]
+		s.dropped++
+	}
+	s.queue = append(s.queue, *r)
+	if len(s.queue) >= s.opts.BatchSize {
+		s.signal()
+	}
+	err := s.err
+	s.err = nil
+	s.mu.Unlock()
+	return err
+}
+
+// Dropped returns the number of records dropped because the queue was full
+func (s *DBSink) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Sync inserts all queued records and returns the first error encountered
+func (s *DBSink) Sync() error {
+	ch := make(chan error)
+	select {
+	case s.flushch <- ch:
+		return <-ch
+	case <-s.done:
+		return nil
+	}
+}
+
+// Sweep deletes records older than the retention period. This is done hourly when
+// DBOptions.Retention is set; Sweep does it now.
+func (s *DBSink) Sweep() error {
+	if s.opts.Retention <= 0 {
+		return nil
+	}
+	_, err := s.db.Exec(s.sweep, time.Now().Add(-s.opts.Retention))
+	return err
+}
+
+// Close inserts all queued records and stops the sink. It does not close the database.
+func (s *DBSink) Close() error {
+	err := s.Sync()
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.wakeup)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return err
+}
+
+// signal wakes up run without blocking. s.mu must be held, since Close closes s.wakeup.
+func (s *DBSink) signal() {
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (s *DBSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	var sweepch <-chan time.Time
+	if s.opts.Retention > 0 {
+		s.setErr(s.Sweep())
+		sweeper := time.NewTicker(time.Hour)
+		defer sweeper.Stop()
+		sweepch = sweeper.C
+	}
+	for {
+		select {
+		case _, ok := <-s.wakeup:
+			s.flush(false)
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			s.flush(true)
+		case ch := <-s.flushch:
+			ch <- s.flush(true)
+		case <-sweepch:
+			s.setErr(s.Sweep())
+		}
+	}
+}
+
+func (s *DBSink) setErr(err error) {
+	if err != nil {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+	}
+}
+
+// flush inserts queued records in batches. Unless all is true, only full batches are inserted.
+func (s *DBSink) flush(all bool) error {
+	var firstErr error
+	for {
+		s.mu.Lock()
+		n := len(s.queue)
+		if n == 0 || (!all && n < s.opts.BatchSize) {
+			s.mu.Unlock()
+			return firstErr
+		}
+		if n > s.opts.BatchSize {
+			n = s.opts.BatchSize
+		}
+		batch := make([]Record, n)
+		copy(batch, s.queue)
+		s.queue = s.queue[:copy(s.queue, s.queue[n:])]
+		s.mu.Unlock()
+
+		if err := s.insertBatch(batch); err != nil {
+			s.setErr(err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+}
+
+func (s *DBSink) insertBatch(batch []Record) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(s.insert)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for i := range batch {
+		r := &batch[i]
+		var fields interface{}
+		if len(r.Fields) > 0 {
+			if data, err := json.Marshal(r.Fields); err == nil {
+				fields = string(data)
+			}
+		}
+		if _, err := stmt.Exec(r.Time, r.Level.String(), r.Prefix, r.Msg, fields); err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+	stmt.Close()
+	return tx.Commit()
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+// testDB is a database/sql driver which records the statements it executes
+type testDB struct {
+	mu    sync.Mutex
+	execs []testExec
+}
+
+type testExec struct {
+	query string
+	args  []driver.Value
+}
+
+func (d *testDB) Open(name string) (driver.Conn, error) { return testDBConn{d}, nil }
+
+func (d *testDB) log() []testExec {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]testExec(nil), d.execs...)
+}
+
+// reset forgets the statements executed so far
+func (d *testDB) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.execs = nil
+}
+
+type testDBConn struct{ d *testDB }
+
+func (c testDBConn) Prepare(query string) (driver.Stmt, error) { return testDBStmt{c.d, query}, nil }
+func (c testDBConn) Close() error                              { return nil }
+func (c testDBConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c testDBConn) Commit() error                             { return nil }
+func (c testDBConn) Rollback() error                           { return nil }
+
+type testDBStmt struct {
+	d     *testDB
+	query string
+}
+
+func (s testDBStmt) Close() error  { return nil }
+func (s testDBStmt) NumInput() int { return -1 }
+func (s testDBStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	s.d.execs = append(s.d.execs, testExec{s.query, args})
+	s.d.mu.Unlock()
+	return driver.RowsAffected(1), nil
+}
+func (s testDBStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var testDBDriver = &testDB{}
+
+func init() { sql.Register("logtest", testDBDriver) }
+
+func TestDBSink(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	t.Cleanup(testDBDriver.reset) // for -count=N
+	db, err := sql.Open("logtest", "")
+	assert.NoErr("open", err)
+	defer db.Close()
+
+	_, err = NewDBSink(db, &DBOptions{Table: "logs; DROP TABLE x"})
+	assert.Err("invalid table name", "invalid table name", err)
+	_, err = NewDBSink(db, &DBOptions{Dialect: "oracle"})
+	assert.Err("unsupported dialect", "unsupported", err)
+
+	sink, err := NewDBSink(db, &DBOptions{Dialect: "postgres", Retention: time.Hour})
+	assert.NoErr("NewDBSink", err)
+	l := NewLogger(SinkWriter(sink), "[app] ", LevelInfo, 0)
+	l.Info("hello")
+	l.Warn("x")
+	l.Sync()
+	assert.NoErr("sync", sink.Sync())
+	assert.NoErr("close", sink.Close())
+
+	execs := testDBDriver.log()
+	assert.Ok("create table", strings.HasPrefix(execs[0].query, "CREATE TABLE IF NOT EXISTS logs "))
+	assert.Ok("jsonb", strings.Contains(execs[0].query, "fields JSONB"))
+	assert.Ok("create index", strings.HasPrefix(execs[1].query, "CREATE INDEX IF NOT EXISTS logs_ts"))
+	assert.Eq("sweep", execs[2].query, "DELETE FROM logs WHERE ts < $1")
+	var inserts []testExec
+	for _, e := range execs {
+		if strings.HasPrefix(e.query, "INSERT INTO logs ") {
+			inserts = append(inserts, e)
+		}
+	}
+	assert.Eq("inserts", len(inserts), 2)
+	assert.Eq("level", inserts[1].args[1], "warn")
+	assert.Eq("prefix", inserts[1].args[2], "[app] ")
+	assert.Eq("msg", inserts[1].args[3], "x")
+	assert.Eq("no fields", inserts[1].args[4], nil)
+
+	// fields are stored as JSON
+	sink, err = NewDBSink(db, nil)
+	assert.NoErr("NewDBSink", err)
+	sink.Write(&Record{Level: LevelInfo, Msg: "f", Fields: map[string]string{"k": "1"}})
+	assert.NoErr("close", sink.Close())
+	execs = testDBDriver.log()
+	last := execs[len(execs)-1]
+	assert.Eq("sqlite insert", last.query,
+		"INSERT INTO logs (ts, level, prefix, msg, fields) VALUES (?, ?, ?, ?, ?)")
+	assert.Eq("fields", last.args[4], `{"k":"1"}`)
+}
+
+func TestDBSinkWriteClose(t *testing.T) {
+	t.Cleanup(testDBDriver.reset) // for -count=N
+	db, err := sql.Open("logtest", "")
+	testutil.NewAssert(t).NoErr("open", err)
+	defer db.Close()
+	for i := 0; i < 10; i++ {
+		sink, err := NewDBSink(db, &DBOptions{BatchSize: 1})
+		testutil.NewAssert(t).NoErr("NewDBSink", err)
+		// writes racing with Close must not send on the closed wakeup channel
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					sink.Write(&Record{Level: LevelInfo, Msg: "x"})
+				}
+			}()
+		}
+		sink.Close()
+		wg.Wait()
+	}
+}
@@ -0,0 +1,134 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler returns an http.Handler which serves the records held by s, for example to let
+// operators look at the recent logs of a running program:
+//
+//   sink := log.NewMemorySink(10000)
+//   log.RootLogger.SetWriter(log.NewTeeWriter(
+//     log.TeeSink{W: os.Stdout, Features: log.FDefault},
+//     log.TeeSink{W: sink},
+//   ))
+//   http.Handle("/debug/logs", sink.Handler())
+//
+// The sink must receive records rather than formatted text for the level and prefix filters
+// below to work; io.MultiWriter, for example, passes on only text, which MemorySink stores as
+// LevelInfo records.
+//
+// Records are written as plain text, or as JSON when the "format" query parameter is "json"
+// or the request accepts "application/json". Other query parameters:
+//
+//   level=warn    only include records of this level or higher
+//   prefix=[foo]  only include records with a prefix containing this string
+//   n=100         only include the 100 most recent (matching) records
+//   follow=1      keep the response open, streaming new records as they are logged.
+//                 In JSON format, records are then written one JSON object per line.
+//
+func (s *MemorySink) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+func (s *MemorySink) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	minLevel := LevelDebug
+	if v := q.Get("level"); v != "" {
+		var err error
+		if minLevel, err = ParseLevel(v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := -1
+	if v := q.Get("n"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+	}
+	prefix := q.Get("prefix")
+	asJSON := q.Get("format") == "json" ||
+		(q.Get("format") == "" && strings.Contains(r.Header.Get("Accept"), "application/json"))
+	follow := q.Get("follow") != "" && q.Get("follow") != "0"
+
+	filter := func(records []Record) []Record {
+		filtered := records[:0]
+		for _, r := range records {
+			if r.Level >= minLevel && strings.Contains(r.Prefix, prefix) {
+				filtered = append(filtered, r)
+			}
+		}
+		return filtered
+	}
+
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	records, seq, changed := s.since(0)
+	records = filter(records)
+	if limit >= 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+
+	if !follow {
+		if asJSON {
+			if records == nil {
+				records = []Record{}
+			}
+			json.NewEncoder(w).Encode(records)
+		} else {
+			w.Write(formatRecordsText(records))
+		}
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for {
+		if asJSON {
+			for _, r := range records {
+				enc.Encode(r)
+			}
+		} else if _, err := w.Write(formatRecordsText(records)); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+		records, seq, changed = s.since(seq)
+		records = filter(records)
+	}
+}
+
+// formatRecordsText formats records as "2006-01-02 15:04:05.000000 [level] prefix msg"
+func formatRecordsText(records []Record) []byte {
+	var buf []byte
+	for _, r := range records {
+		buf = r.Time.AppendFormat(buf, "2006-01-02 15:04:05.000000 ")
+		buf = append(buf, levelPrefixPlain[r.Level]...)
+		if r.Prefix != "" {
+			buf = append(buf, r.Prefix...)
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, strings.TrimSuffix(r.Msg, "\n")...)
+		if len(r.Fields) > 0 {
+			appendFields(&buf, sortedFields(r.Fields), false)
+		}
+		buf = append(buf, '\n')
+	}
+	return buf
+}
@@ -0,0 +1,91 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestMemorySinkHandler(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(100)
+	l := NewLogger(sink, "", LevelDebug, 0)
+	defer l.Close()
+	l.Info("hello")
+	l.SubLogger("[db]").Warn("slow query")
+	l.Error("oh no")
+	l.Sync()
+
+	get := func(url string, header ...string) string {
+		req := httptest.NewRequest("GET", url, nil)
+		if len(header) > 0 {
+			req.Header.Set(header[0], header[1])
+		}
+		rec := httptest.NewRecorder()
+		sink.Handler().ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+	stripTimes := func(s string) string {
+		lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+		for i, line := range lines {
+			lines[i] = line[len("2006-01-02 15:04:05.000000 "):]
+		}
+		return strings.Join(lines, "|")
+	}
+
+	assert.Eq("text", stripTimes(get("/debug/logs")),
+		"[info] hello|[warn] [db] slow query|[error] oh no")
+	assert.Eq("level", stripTimes(get("/debug/logs?level=warn")),
+		"[warn] [db] slow query|[error] oh no")
+	assert.Eq("prefix", stripTimes(get("/debug/logs?prefix=db")), "[warn] [db] slow query")
+	assert.Eq("n", stripTimes(get("/debug/logs?n=1")), "[error] oh no")
+
+	var records []Record
+	err := json.Unmarshal([]byte(get("/debug/logs?format=json")), &records)
+	if assert.NoErr("json", err) && assert.Eq("json records", len(records), 3) {
+		assert.Eq("json level", records[1].Level, LevelWarn)
+		assert.Eq("json prefix", records[1].Prefix, "[db]")
+		assert.Eq("json msg", records[1].Msg, "slow query")
+	}
+	assert.Eq("json via Accept", get("/debug/logs?level=error", "Accept", "application/json")[:2],
+		`[{`)
+	assert.Eq("json empty", get("/debug/logs?format=json&prefix=nope"), "[]\n")
+}
+
+func TestMemorySinkHandlerFollow(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(100)
+	l := NewLogger(sink, "", LevelDebug, 0)
+	defer l.Close()
+	l.Info("before")
+	l.Sync()
+
+	srv := httptest.NewServer(sink.Handler())
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"?follow=1&format=json&level=info", nil)
+	res, err := http.DefaultClient.Do(req)
+	if !assert.NoErr("request", err) {
+		return
+	}
+	defer res.Body.Close()
+	lines := bufio.NewScanner(res.Body)
+
+	var r Record
+	assert.Ok("first line", lines.Scan())
+	json.Unmarshal(lines.Bytes(), &r)
+	assert.Eq("first record", r.Msg, "before")
+
+	l.Debug("filtered out")
+	l.Info("after")
+	assert.Ok("second line", lines.Scan())
+	json.Unmarshal(lines.Bytes(), &r)
+	assert.Eq("streamed record", r.Msg, "after")
+}
@@ -0,0 +1,155 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// SuppressDuplicates enables collapsing of consecutive identical messages. When a message is
+// logged several times in a row, only the first one is written, followed by a summary like
+// "last message repeated 41 times in 2.5s" when a different message is logged, when window
+// has passed since the first repetition, or on Sync and Close. The message is then written
+// again if it's repeated once more, making a retry loop show up about once per window.
+//
+// Messages are identical when they have the same level, prefix, scope, fields, writer and text.
+// A window of zero or less disables duplicate suppression.
+//
+// Duplicate suppression applies to the writeLoop shared by l, its parent and its sub-loggers.
+// Records logged synchronously (see FSync) are not affected.
+// Returns l for convenience, e.g. log.NewLogger(...).SuppressDuplicates(10*time.Second)
+func (l *Logger) SuppressDuplicates(window time.Duration) *Logger {
+	m := logRecordFree.Get().(*logRecord)
+	m.level = ctlDuplicates
+	m.ctlarg = window
+	if !l.q.send(m) {
+		m.free()
+	}
+	return l
+}
+
+// duplicateFilter is the state of duplicate suppression, owned by a writeLoop
+type duplicateFilter struct {
+	window  time.Duration
+	last    logRecord // copy of the last written record; valid if last.logger != nil
+	n       int       // number of suppressed repetitions of last
+	end     time.Time // time of the latest repetition
+	timer   *time.Timer
+	timerch <-chan time.Time // nil when no repetitions are pending
+}
+
+func (d *duplicateFilter) configure(window time.Duration) {
+	d.stop()
+	d.window = window
+	d.n = 0
+	d.last.logger = nil
+}
+
+func (d *duplicateFilter) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+		d.timerch = nil
+	}
+}
+
+// suppress returns true if m is a repetition of the last record, in which case m is freed.
+// Otherwise m is remembered as the last record; the caller should flush and write m.
+func (d *duplicateFilter) suppress(m *logRecord) bool {
+	// never suppress records with callbacks (see LogCB) or unique IDs (see ErrorID)
+	if d.window <= 0 || m.done != nil || m.id != "" {
+		return false
+	}
+	if d.isRepetition(m) {
+		if d.n == 0 {
+			d.timer = time.NewTimer(d.window)
+			d.timerch = d.timer.C
+		}
+		d.n++
+		d.end = m.time
+		m.free()
+		return true
+	}
+	return false
+}
+
+func (d *duplicateFilter) isRepetition(m *logRecord) bool {
+	last := &d.last
+	return last.logger != nil &&
+		last.level == m.level &&
+		last.logger.Prefix == m.logger.Prefix &&
+		sameWriter(last.logger.Writer(), m.logger.Writer()) &&
+		last.raw == m.raw &&
+		last.code == m.code &&
+		bytes.Equal(last.msg, m.msg) &&
+		bytes.Equal(last.origin, m.origin) &&
+		sameScope(last.scope, m.scope) &&
+		sameFields(last.fields, m.fields) &&
+		sameFields(last.static, m.static)
+}
+
+func sameScope(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameFields(a, b []Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// remember makes m the record which following records are compared with
+func (d *duplicateFilter) remember(m *logRecord) {
+	if d.window > 0 {
+		d.last.logger = m.logger
+		d.last.level = m.level
+		d.last.time = m.time
+		d.last.scope = m.scope
+		d.last.fields = m.fields
+		d.last.static = m.static
+		d.last.props = m.props
+		d.last.raw = m.raw
+		d.last.code = m.code
+		d.last.msg = append(d.last.msg[:0], m.msg...)
+		d.last.origin = append(d.last.origin[:0], m.origin...)
+	}
+}
+
+// flush returns a summary record of suppressed repetitions, or nil if there are none.
+// If forget is true, the last record is forgotten so that its next repetition is written.
+func (d *duplicateFilter) flush(forget bool) *logRecord {
+	d.stop()
+	var m *logRecord
+	if d.n > 0 {
+		m = logRecordFree.Get().(*logRecord)
+		m.logger = d.last.logger
+		m.level = d.last.level
+		m.time = d.end
+		m.scope = d.last.scope
+		m.fields = d.last.fields
+		m.static = d.last.static
+		m.props = d.last.props
+		m.code = d.last.code
+		span := d.end.Sub(d.last.time).Round(time.Millisecond)
+		m.msg = append(m.msg, fmt.Sprintf("last message repeated %d times in %s", d.n, span)...)
+		d.n = 0
+	}
+	if forget {
+		d.last.logger = nil
+	}
+	return m
+}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestSuppressDuplicates(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0).SuppressDuplicates(time.Hour)
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		l.Info("retrying")
+	}
+	l.Warn("retrying") // different level
+	l.Warn("retrying")
+	l.Info("done")
+	l.Info("done")
+	l.Sync()
+	re := regexp.MustCompile(`in \d+(\.\d+)?m?s\n`)
+	assert.Eq("output", re.ReplaceAllString(w.String(), "in T\n"), ""+
+		"retrying\n"+
+		"last message repeated 4 times in T\n"+
+		"retrying\n"+
+		"last message repeated 1 times in T\n"+
+		"done\n"+
+		"last message repeated 1 times in T\n")
+
+	// after the window has passed, the message is written again
+	w.Reset()
+	l.SuppressDuplicates(10 * time.Millisecond)
+	l.Info("a")
+	l.Info("a")
+	time.Sleep(50 * time.Millisecond)
+	l.Info("a")
+	l.Sync()
+	assert.Eq("output after window", re.ReplaceAllString(w.String(), "in T\n"), ""+
+		"a\n"+
+		"last message repeated 1 times in T\n"+
+		"a\n")
+
+	// messages with different fields are not repetitions
+	w.Reset()
+	l.SuppressDuplicates(time.Hour)
+	l.WithFields("user", "alice").Info("denied")
+	l.WithFields("user", "bob").Info("denied")
+	l.WithFields("user", "bob").Info("denied")
+	l.Sync()
+	re = regexp.MustCompile(`in \d+(\.\d+)?m?s `)
+	assert.Eq("fields", re.ReplaceAllString(w.String(), "in T "), ""+
+		"denied user=alice\n"+
+		"denied user=bob\n"+
+		"last message repeated 1 times in T user=bob\n")
+
+	// disabled
+	w.Reset()
+	l.SuppressDuplicates(0)
+	l.Info("b")
+	l.Info("b")
+	l.Sync()
+	assert.Eq("disabled", w.String(), "b\nb\n")
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Safe marks v as safe to format after the logging call returns, i.e. v is not modified
+// concurrently and does not refer to memory which is. Messages are normally formatted on the
+// goroutine that logs them, since arguments like pointers or slices may change as soon as the
+// logging function returns. When all arguments are plain values (booleans, numbers, strings and
+// time.Duration) or marked with Safe, formatting is instead deferred to the logger's write loop,
+// moving the cost of formatting off of the calling goroutine:
+//
+//   logger.Info("%s took %v (%d bytes)", log.Safe(req.ID), time.Since(start), n)
+//
+// Formatting is never deferred for messages which are written synchronously (see FSync) or which
+// a filter, expectation or error reporter needs to inspect when the message is logged.
+func Safe(v interface{}) interface{} { return safeValue{v} }
+
+// safeValue wraps a value passed to Safe. It is unwrapped when formatting is deferred and
+// formats like the value it wraps otherwise.
+type safeValue struct{ v interface{} }
+
+func (s safeValue) Format(f fmt.State, verb rune) {
+	directive := []byte{'%'}
+	for _, c := range "+-# 0" {
+		if f.Flag(int(c)) {
+			directive = append(directive, byte(c))
+		}
+	}
+	if w, ok := f.Width(); ok {
+		directive = strconv.AppendInt(directive, int64(w), 10)
+	}
+	if p, ok := f.Precision(); ok {
+		directive = append(directive, '.')
+		directive = strconv.AppendInt(directive, int64(p), 10)
+	}
+	directive = append(directive, string(verb)...)
+	fmt.Fprintf(f, string(directive), s.v)
+}
+
+// deferf prepares m for formatting format with v in the write loop and returns true, or returns
+// false if any value in v might change after the logging call returns, in which case the caller
+// must format the message right away.
+func (m *logRecord) deferf(format string, v []interface{}) bool {
+	if len(v) == 0 || hasWrapVerb(format) {
+		return false
+	}
+	for _, x := range v {
+		switch x.(type) {
+		case nil, bool, string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32,
+			uint64, uintptr, float32, float64, complex64, complex128, time.Duration, Bytes, Dur, Count,
+			safeValue:
+		default:
+			return false
+		}
+	}
+	m.argfmt = format
+	for _, x := range v {
+		if s, ok := x.(safeValue); ok {
+			x = s.v
+		}
+		m.args = append(m.args, x)
+	}
+	return true
+}
+
+// deferred returns true if m has arguments which have yet to be formatted; see render
+func (m *logRecord) deferred() bool { return len(m.args) != 0 }
+
+// render formats deferred arguments (see deferf) into m.msg
+func (m *logRecord) render() {
+	fmt.Fprintf(m, m.argfmt, m.args...)
+	m.clearArgs()
+}
+
+func (m *logRecord) clearArgs() {
+	for i := range m.args {
+		m.args[i] = nil
+	}
+	m.args = m.args[:0]
+	m.argfmt = ""
+}
+
+// defers returns true if m may be queued without formatting it first
+func (l *Logger) defers(m *logRecord) bool {
+	q := l.q
+	if f, _ := q.filter.Load().(func(Level, string) bool); f != nil {
+		return false
+	}
+	if l.writesSync(m.level) || q.expect.active() || q.wal != nil {
+		return false
+	}
+	if m.level == LevelError {
+		if r, _ := q.reporter.Load().(*errorReporter); r != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestDeferredFormatting(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	m := &logRecord{}
+	assert.Ok("values", m.deferf("%d %s %v %v", []interface{}{1, "a", time.Second, nil}))
+	assert.Eq("args", len(m.args), 4)
+	m.render()
+	assert.Eq("msg", string(m.msg), "1 a 1s <nil>")
+	assert.Eq("rendered", m.deferred(), false)
+
+	m = &logRecord{}
+	x := 1
+	assert.Ok("pointer", !m.deferf("%v", []interface{}{&x}))
+	assert.Ok("slice", !m.deferf("%v", []interface{}{[]int{1}}))
+	assert.Ok("error", !m.deferf("%v", []interface{}{fmt.Errorf("e")}))
+	assert.Ok("%w", !m.deferf("%w", []interface{}{1}))
+	assert.Ok("no args", !m.deferf("100%", nil))
+	assert.Ok("safe", m.deferf("%v", []interface{}{Safe([]int{1})}))
+	assert.Eq("safe is unwrapped", fmt.Sprintf("%T", m.args[0]), "[]int")
+}
+
+func TestDeferredLogging(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+	l.AddRedactor(RegexRedactor(`secret\d`))
+
+	thing := &Things{1}
+	l.Info("%d %s %v", 1, "secret1", time.Millisecond)
+	l.Info("%+v", thing) // formatted right away
+	thing.Field = 2
+	l.Info("[%6.2f|%-3d|%x]", Safe(3.14159), Safe(7), Safe("hi"))
+	l.Sync()
+	assert.Eq("output", w.String(), "1 [REDACTED] 1ms\n&{Field:1}\n[  3.14|7  |6869]\n")
+
+	// Safe formats like the value it wraps when formatting is not deferred
+	w.Reset()
+	l.SetFilter(func(level Level, msg string) bool { return msg != "skip 1" })
+	l.Info("skip %d", 1)
+	l.Info("[%6.2f|%-3d|%x]", Safe(3.14159), Safe(7), Safe("hi"))
+	l.Sync()
+	assert.Eq("filtered", w.String(), "[  3.14|7  |6869]\n")
+}
@@ -0,0 +1,37 @@
+package log
+
+import "io/ioutil"
+
+// Discard is a logger which discards all messages. It is meant for libraries which accept a
+// *Logger but are used without logging:
+//
+//   func NewClient(logger *log.Logger) *Client {
+//     return &Client{logger: log.OrDiscard(logger)}
+//   }
+//
+// Unlike a logger writing to ioutil.Discard, Discard has no write goroutine and its level is
+// LevelDisable, so logging returns right away without formatting messages or allocating.
+// Sync and Close return immediately. Sub-loggers of Discard discard messages as well, even if
+// their level is changed.
+var Discard = newDiscardLogger()
+
+func newDiscardLogger() *Logger {
+	q := &queue{
+		ch:     make(chan *logRecord),
+		done:   make(chan struct{}),
+		closed: true,
+	}
+	close(q.ch)
+	close(q.done)
+	l := &Logger{Level: LevelDisable, q: q, metrics: new(metrics)}
+	l.w.Store(newWriterRef(ioutil.Discard))
+	return l
+}
+
+// OrDiscard returns l, or Discard if l is nil
+func OrDiscard(l *Logger) *Logger {
+	if l == nil {
+		return Discard
+	}
+	return l
+}
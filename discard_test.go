@@ -0,0 +1,30 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestDiscard(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	allocs := testing.AllocsPerRun(100, func() {
+		Discard.Debug("a %d", 1)
+		Discard.Info("b %s", "c")
+		Discard.Error("d")
+	})
+	assert.Eq("allocations", allocs, 0.0)
+	assert.NoErr("sync", Discard.Sync())
+
+	sub := Discard.SubLogger("[sub]")
+	sub.SetLevel(LevelDebug)
+	sub.Info("dropped")
+	called := false
+	sub.LogCB(LevelInfo, func(err error) { called = err == ErrClosed }, "e")
+	assert.Ok("callback", called)
+	assert.NoErr("close sub-logger", sub.Close())
+	assert.NoErr("close", Discard.Close())
+
+	assert.Eq("nil", OrDiscard(nil), Discard)
+	assert.Eq("non-nil", OrDiscard(RootLogger), RootLogger)
+}
@@ -0,0 +1,188 @@
+package log
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Dump logs a multi-line rendering of v at LevelDebug, showing the contents of structs, maps,
+// slices and pointers, including unexported fields. v is not inspected unless debug messages
+// are enabled.
+//
+//   logger.Dump("config", cfg)
+//   // [debug] config: main.Config{
+//   //   Name: "app",
+//   //   Ports: []int{80, 443},
+//   // }
+//
+func (l *Logger) Dump(label string, v interface{}) {
+	if l.GetLevel() <= LevelDebug {
+		m := l.newRecord(LevelDebug)
+		m.msg = append(m.msg, label...)
+		m.msg = append(m.msg, ": "...)
+		d := dumper{buf: m.msg, visited: make(map[uintptr]bool)}
+		d.dump(reflect.ValueOf(v), 0)
+		m.msg = d.buf
+		l.submit(m)
+	}
+}
+
+// Hexdump logs b at LevelDebug as lines of offset, hexadecimal bytes and ASCII, like
+// "hexdump -C". The dump is not produced unless debug messages are enabled.
+func (l *Logger) Hexdump(label string, b []byte) {
+	if l.GetLevel() <= LevelDebug {
+		m := l.newRecord(LevelDebug)
+		m.msg = append(m.msg, label...)
+		m.msg = append(m.msg, " ("...)
+		m.msg = strconv.AppendInt(m.msg, int64(len(b)), 10)
+		m.msg = append(m.msg, " bytes):\n"...)
+		w := hex.Dumper(m)
+		w.Write(b)
+		w.Close()
+		l.submit(m)
+	}
+}
+
+// dumpMaxDepth limits how deep Dump descends into nested values
+const dumpMaxDepth = 10
+
+type dumper struct {
+	buf     []byte
+	visited map[uintptr]bool // pointers on the current path, for detecting cycles
+}
+
+func (d *dumper) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		d.buf = append(d.buf, "  "...)
+	}
+}
+
+func (d *dumper) dump(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		d.buf = append(d.buf, "nil"...)
+		return
+	}
+	if depth > dumpMaxDepth {
+		d.buf = append(d.buf, "..."...)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		d.buf = strconv.AppendBool(d.buf, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		d.buf = strconv.AppendInt(d.buf, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		d.buf = strconv.AppendUint(d.buf, v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		d.buf = strconv.AppendFloat(d.buf, v.Float(), 'g', -1, 64)
+	case reflect.Complex64, reflect.Complex128:
+		d.buf = append(d.buf, fmt.Sprint(v.Complex())...)
+	case reflect.String:
+		d.buf = strconv.AppendQuote(d.buf, v.String())
+	case reflect.Ptr:
+		if v.IsNil() {
+			d.buf = append(d.buf, "("+v.Type().String()+")(nil)"...)
+			return
+		}
+		if d.visited[v.Pointer()] {
+			d.buf = append(d.buf, "<cycle "+v.Type().String()+">"...)
+			return
+		}
+		d.visited[v.Pointer()] = true
+		d.buf = append(d.buf, '&')
+		d.dump(v.Elem(), depth)
+		delete(d.visited, v.Pointer())
+	case reflect.Interface:
+		d.dump(v.Elem(), depth)
+	case reflect.Struct:
+		d.buf = append(d.buf, v.Type().String()...)
+		if v.NumField() == 0 {
+			d.buf = append(d.buf, "{}"...)
+			return
+		}
+		d.buf = append(d.buf, "{\n"...)
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			d.indent(depth + 1)
+			d.buf = append(d.buf, t.Field(i).Name...)
+			d.buf = append(d.buf, ": "...)
+			d.dump(v.Field(i), depth+1)
+			d.buf = append(d.buf, ",\n"...)
+		}
+		d.indent(depth)
+		d.buf = append(d.buf, '}')
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			d.buf = append(d.buf, v.Type().String()+"(nil)"...)
+			return
+		}
+		d.buf = append(d.buf, v.Type().String()...)
+		if isScalarKind(v.Type().Elem().Kind()) {
+			// short form on one line, e.g. []int{1, 2, 3}
+			d.buf = append(d.buf, '{')
+			for i := 0; i < v.Len(); i++ {
+				if i > 0 {
+					d.buf = append(d.buf, ", "...)
+				}
+				d.dump(v.Index(i), depth+1)
+			}
+			d.buf = append(d.buf, '}')
+			return
+		}
+		d.buf = append(d.buf, "{\n"...)
+		for i := 0; i < v.Len(); i++ {
+			d.indent(depth + 1)
+			d.dump(v.Index(i), depth+1)
+			d.buf = append(d.buf, ",\n"...)
+		}
+		d.indent(depth)
+		d.buf = append(d.buf, '}')
+	case reflect.Map:
+		if v.IsNil() {
+			d.buf = append(d.buf, v.Type().String()+"(nil)"...)
+			return
+		}
+		d.buf = append(d.buf, v.Type().String()...)
+		d.buf = append(d.buf, "{\n"...)
+		keys := v.MapKeys()
+		keyStrings := make([]string, len(keys))
+		for i, k := range keys {
+			kd := dumper{visited: d.visited}
+			kd.dump(k, dumpMaxDepth) // keys on one line
+			keyStrings[i] = string(kd.buf)
+		}
+		order := make([]int, len(keys))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return keyStrings[order[i]] < keyStrings[order[j]] })
+		for _, i := range order {
+			d.indent(depth + 1)
+			d.buf = append(d.buf, keyStrings[i]...)
+			d.buf = append(d.buf, ": "...)
+			d.dump(v.MapIndex(keys[i]), depth+1)
+			d.buf = append(d.buf, ",\n"...)
+		}
+		d.indent(depth)
+		d.buf = append(d.buf, '}')
+	default: // chan, func, unsafe pointer
+		if v.IsNil() {
+			d.buf = append(d.buf, "("+v.Type().String()+")(nil)"...)
+		} else {
+			d.buf = append(d.buf, fmt.Sprintf("(%s)(%#x)", v.Type(), v.Pointer())...)
+		}
+	}
+}
+
+func isScalarKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+type dumpTestNode struct {
+	Name     string
+	ports    []int
+	Attrs    map[string]interface{}
+	Next     *dumpTestNode
+	Children []*dumpTestNode
+}
+
+func TestDump(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelDebug, FPrefixDebug)
+	defer l.Close()
+
+	n := &dumpTestNode{Name: "a", ports: []int{80, 443}, Attrs: map[string]interface{}{"b": 2, "a": "x"}}
+	n.Next = n
+	l.Dump("node", n)
+	l.Sync()
+	assert.Eq("dump", w.String(), `[debug] node: &log.dumpTestNode{
+  Name: "a",
+  ports: []int{80, 443},
+  Attrs: map[string]interface {}{
+    "a": "x",
+    "b": 2,
+  },
+  Next: <cycle *log.dumpTestNode>,
+  Children: []*log.dumpTestNode(nil),
+}
+`)
+
+	w.Reset()
+	l.Hexdump("packet", []byte("hello, world\x00\x01"))
+	l.Sync()
+	lines := strings.Split(w.String(), "\n")
+	assert.Eq("hexdump header", lines[0], "[debug] packet (14 bytes):")
+	assert.Eq("hexdump", lines[1], "00000000  68 65 6c 6c 6f 2c 20 77  6f 72 6c 64 00 01        |hello, world..|")
+
+	// not evaluated when disabled
+	w.Reset()
+	l.Level = LevelInfo
+	l.Dump("x", n)
+	l.Hexdump("x", nil)
+	l.Sync()
+	assert.Eq("disabled", w.Len(), 0)
+}
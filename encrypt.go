@@ -0,0 +1,250 @@
+package log
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Encrypted logs consist of one or more streams, each of which is encrypted with its own key:
+//
+//   stream     = magic ephemeral-public-key chunk* last-chunk
+//   chunk      = uint32be(len(ciphertext)) ciphertext
+//   last-chunk = uint32be(len(ciphertext) | 1<<31) ciphertext
+//
+// The key of a stream is derived with ECDH (P-256) from an ephemeral key pair and the
+// recipient's public key, so that only the holder of the private key can decrypt the log, not
+// the program writing it. Chunks are sealed with AES-256-GCM. As in the STREAM construction,
+// the nonce of a chunk is its index and a flag marking the last chunk, so chunks can't be
+// reordered, and a stream which is cut short, or whose last chunk is marked as not being
+// the last one, fails to decrypt. Like compressed files (see FileOptions.Compress), an
+// encrypted file may be appended to with a new stream. Removing whole streams from the end of
+// a file is not detected.
+
+const (
+	encMagic       = "GLE1"
+	encPubKeySize  = 65 // uncompressed P-256 point
+	encChunkSize   = 64 * 1024
+	encMaxSealSize = encChunkSize + 16 // with GCM tag
+	encLastChunk   = 1 << 31           // flag of the length of the last chunk of a stream
+)
+
+// ErrEncryptedFormat is returned when reading data which is not a valid encrypted log
+var ErrEncryptedFormat = errors.New("log: malformed encrypted log")
+
+var (
+	errEncPublicKey  = errors.New("log: invalid encryption public key")
+	errEncPrivateKey = errors.New("log: invalid encryption private key")
+)
+
+// EncryptingWriter encrypts data written to it for the holder of a private key; see
+// GenerateEncryptionKey. Data is encrypted in chunks of up to 64 KiB, so data written is
+// held in memory until a chunk is full or Flush or Close is called.
+type EncryptingWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	n      uint64 // index of next chunk
+	buf    []byte // plaintext of the current chunk
+	chunk  []byte // length + ciphertext
+	closed bool   // the last chunk has been written
+}
+
+// NewEncryptingWriter starts an encrypted stream on w for publicKey and writes its header.
+// Close must be called to write the last chunk; it does not close w. Until then, a reader of
+// the stream fails with io.ErrUnexpectedEOF after the data of the chunks written so far.
+func NewEncryptingWriter(w io.Writer, publicKey []byte) (*EncryptingWriter, error) {
+	ephemeral, shared, err := encAgree(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newEncAEAD(shared, ephemeral, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append([]byte(encMagic), ephemeral...)); err != nil {
+		return nil, err
+	}
+	return &EncryptingWriter{w: w, aead: aead, buf: make([]byte, 0, encChunkSize)}, nil
+}
+
+// newEncAEAD derives the key of a stream from the ECDH shared secret
+func newEncAEAD(shared, ephemeral, publicKey []byte) (cipher.AEAD, error) {
+	h := sha256.New()
+	h.Write([]byte("go-log encryption"))
+	h.Write(shared)
+	h.Write(ephemeral)
+	h.Write(publicKey)
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encNonce returns the nonce of chunk n, which is the last chunk of its stream if last is true
+func encNonce(n uint64, last bool, nonce []byte) []byte {
+	for i := range nonce {
+		nonce[i] = 0
+	}
+	if last {
+		nonce[len(nonce)-9] = 1
+	}
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], n)
+	return nonce
+}
+
+func (e *EncryptingWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("log: write to closed EncryptingWriter")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		k := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+k]
+		p = p[k:]
+		if len(e.buf) == cap(e.buf) {
+			if err := e.seal(false); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Flush encrypts and writes buffered data as a chunk
+func (e *EncryptingWriter) Flush() error {
+	if len(e.buf) == 0 || e.closed {
+		return nil
+	}
+	return e.seal(false)
+}
+
+// Close writes any buffered data as the last chunk of the stream, which may be empty.
+// The underlying writer is not closed.
+func (e *EncryptingWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+// seal encrypts and writes buffered data as a chunk
+func (e *EncryptingWriter) seal(last bool) error {
+	var nonce [12]byte
+	e.chunk = append(e.chunk[:0], 0, 0, 0, 0)
+	e.chunk = e.aead.Seal(e.chunk, encNonce(e.n, last, nonce[:]), e.buf, nil)
+	size := uint32(len(e.chunk) - 4)
+	if last {
+		size |= encLastChunk
+	}
+	binary.BigEndian.PutUint32(e.chunk, size)
+	e.n++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(e.chunk)
+	return err
+}
+
+// NewDecryptingReader returns a reader of the plaintext of encrypted data read from r, which
+// must have been encrypted for privateKey. Reading fails with ErrEncryptedFormat if the data
+// is malformed or was encrypted for another key, and with io.ErrUnexpectedEOF if a stream ends
+// without its last chunk, e.g. when the program writing it crashed or the data was truncated.
+func NewDecryptingReader(r io.Reader, privateKey []byte) io.Reader {
+	return &decryptingReader{r: bufio.NewReader(r), priv: privateKey}
+}
+
+type decryptingReader struct {
+	r    *bufio.Reader
+	priv []byte
+	aead cipher.AEAD // nil before the first stream header
+	n    uint64
+	last bool   // the last chunk of the stream has been read
+	buf  []byte // decrypted data not yet read
+	err  error
+}
+
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.readChunk()
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// readChunk decrypts the next chunk into d.buf, reading a stream header first if needed
+func (d *decryptingReader) readChunk() error {
+	if d.aead == nil {
+		if err := d.readHeader(); err != nil {
+			return err
+		}
+	}
+	size, err := d.r.Peek(4)
+	if len(size) < 4 {
+		if err == io.EOF && len(size) == 0 && d.last {
+			return io.EOF
+		}
+		return io.ErrUnexpectedEOF
+	}
+	if string(size) == encMagic { // a stream appended to the previous one
+		if !d.last {
+			return io.ErrUnexpectedEOF
+		}
+		d.aead = nil
+		return nil
+	}
+	if d.last {
+		return ErrEncryptedFormat
+	}
+	n := binary.BigEndian.Uint32(size)
+	d.r.Discard(4)
+	last := n&encLastChunk != 0
+	n &^= encLastChunk
+	if n > encMaxSealSize {
+		return ErrEncryptedFormat
+	}
+	chunk := make([]byte, n)
+	if _, err := io.ReadFull(d.r, chunk); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	var nonce [12]byte
+	plain, err := d.aead.Open(chunk[:0], encNonce(d.n, last, nonce[:]), chunk, nil)
+	if err != nil {
+		return ErrEncryptedFormat
+	}
+	d.n++
+	d.last = last
+	d.buf = plain
+	return nil
+}
+
+func (d *decryptingReader) readHeader() error {
+	var hdr [len(encMagic) + encPubKeySize]byte
+	if _, err := io.ReadFull(d.r, hdr[:]); err != nil {
+		if err == io.EOF {
+			return err
+		}
+		return io.ErrUnexpectedEOF
+	}
+	if string(hdr[:len(encMagic)]) != encMagic {
+		return ErrEncryptedFormat
+	}
+	ephemeral := hdr[len(encMagic):]
+	shared, publicKey, err := encAgreed(d.priv, ephemeral)
+	if err != nil {
+		return err
+	}
+	aead, err := newEncAEAD(shared, ephemeral, publicKey)
+	if err != nil {
+		return err
+	}
+	d.aead, d.n, d.last = aead, 0, false
+	return nil
+}
@@ -0,0 +1,58 @@
+//go:build go1.20
+// +build go1.20
+
+package log
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+)
+
+// GenerateEncryptionKey returns a new key pair for encrypted logs. The public key is given to
+// programs writing logs (see NewEncryptingWriter and FileOptions.Encrypt) and the private key
+// is kept by whoever reads them (see NewDecryptingReader and the logdecrypt command).
+func GenerateEncryptionKey() (publicKey, privateKey []byte, err error) {
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv.PublicKey().Bytes(), priv.Bytes(), nil
+}
+
+// encAgree generates an ephemeral key pair and returns its public key and the secret it shares
+// with publicKey
+func encAgree(publicKey []byte) (ephemeral, shared []byte, err error) {
+	curve := ecdh.P256()
+	pub, err := curve.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, errEncPublicKey
+	}
+	priv, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	shared, err = priv.ECDH(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv.PublicKey().Bytes(), shared, nil
+}
+
+// encAgreed returns the secret privateKey shares with the ephemeral public key of a stream,
+// and the public key of privateKey
+func encAgreed(privateKey, ephemeral []byte) (shared, publicKey []byte, err error) {
+	curve := ecdh.P256()
+	priv, err := curve.NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, errEncPrivateKey
+	}
+	pub, err := curve.NewPublicKey(ephemeral)
+	if err != nil {
+		return nil, nil, ErrEncryptedFormat
+	}
+	shared, err = priv.ECDH(pub)
+	if err != nil {
+		return nil, nil, ErrEncryptedFormat
+	}
+	return shared, priv.PublicKey().Bytes(), nil
+}
@@ -0,0 +1,55 @@
+//go:build !go1.20
+// +build !go1.20
+
+package log
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+)
+
+// Before Go 1.20 and crypto/ecdh, ECDH is done with crypto/elliptic. Keys and shared secrets
+// are the same as those of encrypt_ecdh.go.
+
+// GenerateEncryptionKey returns a new key pair for encrypted logs. The public key is given to
+// programs writing logs (see NewEncryptingWriter and FileOptions.Encrypt) and the private key
+// is kept by whoever reads them (see NewDecryptingReader and the logdecrypt command).
+func GenerateEncryptionKey() (publicKey, privateKey []byte, err error) {
+	priv, x, y, err := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return elliptic.Marshal(elliptic.P256(), x, y), priv, nil
+}
+
+// encAgree generates an ephemeral key pair and returns its public key and the secret it shares
+// with publicKey
+func encAgree(publicKey []byte) (ephemeral, shared []byte, err error) {
+	curve := elliptic.P256()
+	x, y := elliptic.Unmarshal(curve, publicKey)
+	if x == nil {
+		return nil, nil, errEncPublicKey
+	}
+	priv, ex, ey, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	sx, _ := curve.ScalarMult(x, y, priv)
+	return elliptic.Marshal(curve, ex, ey), sx.FillBytes(make([]byte, 32)), nil
+}
+
+// encAgreed returns the secret privateKey shares with the ephemeral public key of a stream,
+// and the public key of privateKey
+func encAgreed(privateKey, ephemeral []byte) (shared, publicKey []byte, err error) {
+	curve := elliptic.P256()
+	if len(privateKey) != 32 {
+		return nil, nil, errEncPrivateKey
+	}
+	x, y := elliptic.Unmarshal(curve, ephemeral)
+	if x == nil {
+		return nil, nil, ErrEncryptedFormat
+	}
+	sx, _ := curve.ScalarMult(x, y, privateKey)
+	px, py := curve.ScalarBaseMult(privateKey)
+	return sx.FillBytes(make([]byte, 32)), elliptic.Marshal(curve, px, py), nil
+}
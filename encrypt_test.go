@@ -0,0 +1,124 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestEncryptingWriter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	pub, priv, err := GenerateEncryptionKey()
+	assert.NoErr("GenerateEncryptionKey", err)
+
+	var buf bytes.Buffer
+	e, err := NewEncryptingWriter(&buf, pub)
+	assert.NoErr("NewEncryptingWriter", err)
+	e.Write([]byte("secret 1\n"))
+	assert.NoErr("Flush", e.Flush())
+	big := strings.Repeat("x", encChunkSize+10)
+	e.Write([]byte(big))
+	assert.NoErr("Close", e.Close())
+	assert.Ok("not plaintext", !bytes.Contains(buf.Bytes(), []byte("secret")))
+
+	// a second stream appended to the first
+	e, _ = NewEncryptingWriter(&buf, pub)
+	e.Write([]byte("secret 2\n"))
+	e.Close()
+
+	plain, err := ioutil.ReadAll(NewDecryptingReader(bytes.NewReader(buf.Bytes()), priv))
+	assert.NoErr("decrypt", err)
+	assert.Eq("plaintext", string(plain), "secret 1\n"+big+"secret 2\n")
+
+	_, err = ioutil.ReadAll(NewDecryptingReader(bytes.NewReader(buf.Bytes()[:buf.Len()-3]), priv))
+	assert.Eq("truncated", err, io.ErrUnexpectedEOF)
+
+	_, err = e.Write([]byte("x"))
+	assert.Err("write after close", "closed", err)
+
+	_, otherPriv, _ := GenerateEncryptionKey()
+	_, err = ioutil.ReadAll(NewDecryptingReader(bytes.NewReader(buf.Bytes()), otherPriv))
+	assert.Eq("wrong key", err, ErrEncryptedFormat)
+
+	_, err = NewEncryptingWriter(&buf, []byte("bogus"))
+	assert.Err("invalid public key", "invalid encryption public key", err)
+}
+
+func TestEncryptingWriterTruncation(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	pub, priv, _ := GenerateEncryptionKey()
+	decrypt := func(data []byte) (string, error) {
+		plain, err := ioutil.ReadAll(NewDecryptingReader(bytes.NewReader(data), priv))
+		return string(plain), err
+	}
+
+	var buf bytes.Buffer
+	e, _ := NewEncryptingWriter(&buf, pub)
+	e.Write([]byte("a"))
+	e.Flush()
+	unclosed := buf.Len()
+	e.Write([]byte("b"))
+	e.Close()
+	stream := append([]byte(nil), buf.Bytes()...)
+
+	// the last chunk removed, at a chunk boundary
+	plain, err := decrypt(stream[:unclosed])
+	assert.Eq("truncated", err, io.ErrUnexpectedEOF)
+	assert.Eq("data before truncation", plain, "a")
+
+	// a stream without its last chunk followed by another stream
+	e, _ = NewEncryptingWriter(&buf, pub)
+	e.Write([]byte("c"))
+	e.Close()
+	plain, err = decrypt(append(stream[:unclosed:unclosed], buf.Bytes()[len(stream):]...))
+	assert.Eq("truncated stream", err, io.ErrUnexpectedEOF)
+	assert.Eq("data before truncated stream", plain, "a")
+	plain, err = decrypt(buf.Bytes())
+	assert.NoErr("two streams", err)
+	assert.Eq("two streams", plain, "abc")
+
+	// the last chunk marked as not being the last one
+	forged := append([]byte(nil), stream...)
+	forged[unclosed] &^= 0x80
+	_, err = decrypt(forged)
+	assert.Eq("unmarked last chunk", err, ErrEncryptedFormat)
+
+	// a chunk after the last one
+	hdr := len(encMagic) + encPubKeySize
+	_, err = decrypt(append(append([]byte(nil), stream...), stream[hdr:unclosed]...))
+	assert.Eq("chunk after last", err, ErrEncryptedFormat)
+}
+
+func TestFileWriterEncrypt(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir, err := ioutil.TempDir("", "log-test")
+	assert.NoErr("TempDir", err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	pub, priv, _ := GenerateEncryptionKey()
+
+	opts := &FileOptions{Encrypt: pub, Compress: Gzip(gzip.BestSpeed)}
+	f, err := OpenFile(path, opts)
+	assert.NoErr("OpenFile", err)
+	l := NewLogger(f, "", LevelInfo, 0)
+	l.Info("hello")
+	l.Sync()
+	assert.NoErr("Sync", f.Sync())
+	l.Info("world")
+	assert.NoErr("Close", l.Close())
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoErr("ReadFile", err)
+	z, err := gzip.NewReader(NewDecryptingReader(bytes.NewReader(data), priv))
+	assert.NoErr("gzip", err)
+	plain, err := ioutil.ReadAll(z)
+	assert.NoErr("decrypt", err)
+	assert.Eq("plaintext", string(plain), "hello\nworld\n")
+}
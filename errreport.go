@@ -0,0 +1,134 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorReport describes an error message passed to an error reporter. See Logger.ReportErrors
+type ErrorReport struct {
+	Record
+	Stack      string // stack trace of the goroutine which logged the message
+	Suppressed int    // number of reports dropped by rate limiting since the previous report
+}
+
+// ReportErrors calls f for every message logged at LevelError by l, its parent or sub-loggers,
+// for example to forward errors to an error-reporting service like Sentry:
+//
+//   logger.ReportErrors(func(r log.ErrorReport) {
+//     sentry.CaptureMessage(r.Msg)
+//   }, 10, time.Minute)
+//
+// At most limit reports are made per interval; reports in excess of that are dropped and
+// counted in the next report's Suppressed field. A limit of zero means "no limit".
+// Calling ReportErrors with a nil f stops reporting.
+//
+// f is called on the goroutine which logged the message, before the message is written,
+// and should not block. Errors logged by f itself are not reported.
+func (l *Logger) ReportErrors(f func(r ErrorReport), limit int, interval time.Duration) {
+	var r *errorReporter
+	if f != nil {
+		r = &errorReporter{f: f, limit: limit, interval: interval}
+	}
+	l.q.reporter.Store(r)
+}
+
+// errorReporter holds the state of ReportErrors
+type errorReporter struct {
+	f        func(r ErrorReport)
+	limit    int
+	interval time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	n           int // reports made in the current window
+	suppressed  int
+
+	active goroutineSet // goroutines calling f; errors logged by f are not reported
+}
+
+// allow returns true if a report may be made at time t, along with the number of reports
+// suppressed since the last allowed report.
+func (r *errorReporter) allow(t time.Time) (bool, int) {
+	if r.limit <= 0 {
+		return true, 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t.Sub(r.windowStart) >= r.interval {
+		r.windowStart = t
+		r.n = 0
+	}
+	if r.n >= r.limit {
+		r.suppressed++
+		return false, 0
+	}
+	r.n++
+	suppressed := r.suppressed
+	r.suppressed = 0
+	return true, suppressed
+}
+
+// report calls the reporter function, if any, for m
+func (q *queue) report(m *logRecord) {
+	r, _ := q.reporter.Load().(*errorReporter)
+	if r == nil {
+		return
+	}
+	id := goroutineID()
+	if r.active.contains(id) {
+		return
+	}
+	ok, suppressed := r.allow(m.time)
+	if !ok {
+		return
+	}
+	r.active.add(id)
+	defer r.active.remove(id)
+	r.f(ErrorReport{
+		Record:     m.record(),
+		Stack:      callerStack(),
+		Suppressed: suppressed,
+	})
+}
+
+// callerStack returns a stack trace of the calling goroutine, excluding frames of this package
+// at the top of the stack.
+func callerStack() string {
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	var sb strings.Builder
+	skipping := true
+	for {
+		f, more := frames.Next()
+		if skipping && isLogPackageFrame(f) {
+			if !more {
+				break
+			}
+			continue
+		}
+		skipping = false
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+var logPackagePath = packagePath()
+
+func packagePath() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name() // e.g. "github.com/rsms/go-log.packagePath"
+	return name[:strings.LastIndexByte(name, '.')]
+}
+
+func isLogPackageFrame(f runtime.Frame) bool {
+	return strings.HasPrefix(f.Function, logPackagePath+".") &&
+		!strings.HasSuffix(f.File, "_test.go")
+}
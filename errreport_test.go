@@ -0,0 +1,47 @@
+package log
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestReportErrors(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	l := NewLogger(ioutil.Discard, "[a]", LevelInfo, 0)
+	defer l.Close()
+
+	var reports []ErrorReport
+	l.ReportErrors(func(r ErrorReport) {
+		reports = append(reports, r)
+	}, 2, time.Hour)
+
+	l.Warn("not reported")
+	l.Error("error %d", 1)
+	l.SubLogger("[b]").Error("error 2")
+	l.Error("error 3") // rate limited
+	l.Error("error 4") // rate limited
+
+	if !assert.Eq("reports", len(reports), 2) {
+		return
+	}
+	assert.Eq("msg", reports[0].Msg, "error 1")
+	assert.Eq("level", reports[0].Level, LevelError)
+	assert.Eq("sub-logger prefix", reports[1].Prefix, "[a][b]")
+	stack := reports[0].Stack
+	assert.Ok("stack starts at caller; got %q", strings.HasPrefix(stack, "github.com/rsms/go-log.TestReportErrors\n"), stack)
+
+	// reset the window to observe Suppressed
+	r := l.q.reporter.Load().(*errorReporter)
+	r.windowStart = time.Time{}
+	l.Error("error 5")
+	assert.Eq("reports", len(reports), 3)
+	assert.Eq("suppressed", reports[2].Suppressed, 2)
+
+	l.ReportErrors(nil, 0, 0)
+	l.Error("error 6")
+	assert.Eq("reporting stopped", len(reports), 3)
+}
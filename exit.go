@@ -0,0 +1,106 @@
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+var exitHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+// exitMu is locked by Exit and never unlocked, so that concurrent calls to Exit wait for the
+// first one to end the process
+var exitMu sync.Mutex
+
+var osExit = os.Exit // replaced by tests
+
+// OnExit registers f to be called by Exit before loggers are closed. Hooks are called in reverse
+// order of registration, like deferred functions, and may log.
+func OnExit(f func()) {
+	exitHooks.mu.Lock()
+	exitHooks.hooks = append(exitHooks.hooks, f)
+	exitHooks.mu.Unlock()
+}
+
+// Exit calls the functions registered with OnExit, closes all loggers (see Logger.Close), which
+// writes their queued messages and closes their writers, and then exits the process with code.
+// Exit gives up waiting for loggers after FlushTimeout, so that a wedged writer can't prevent
+// the process from exiting. Use Exit instead of os.Exit to make sure no messages are lost:
+//
+//   if err := run(); err != nil {
+//     log.Error("%v", err)
+//     log.Exit(1)
+//   }
+//
+func Exit(code int) {
+	exitMu.Lock()
+	runExitHooks()
+	closeLoggers(openQueues.loggers(), FlushTimeout)
+	osExit(code)
+}
+
+// ExitOnSignal makes the process call Exit when it receives one of signals, or SIGINT or SIGTERM
+// if no signals are given. The exit code is 128 plus the signal number, as is conventional for
+// processes terminated by a signal. Call stop to restore the default behavior.
+// On Plan 9, only os.Interrupt is handled by default and the exit code is 1.
+func ExitOnSignal(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = defaultExitSignals
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-ch:
+			RootLogger.Info("received %v; exiting", sig)
+			Exit(signalExitCode(sig))
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// runExitHooks calls and unregisters the functions registered with OnExit
+func runExitHooks() {
+	exitHooks.mu.Lock()
+	hooks := exitHooks.hooks
+	exitHooks.hooks = nil
+	exitHooks.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// closeLoggers closes loggers concurrently and waits for at most timeout for them to close
+func closeLoggers(loggers []*Logger, timeout time.Duration) {
+	var wg sync.WaitGroup
+	wg.Add(len(loggers))
+	for _, l := range loggers {
+		go func(l *Logger) {
+			defer wg.Done()
+			l.Close()
+		}(l)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-done:
+	case <-t.C:
+	}
+}
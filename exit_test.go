@@ -0,0 +1,66 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestExitHooks(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	calls := ""
+	OnExit(func() { calls += "1" })
+	OnExit(func() { calls += "2" })
+	runExitHooks()
+	assert.Eq("reverse order", calls, "21")
+	runExitHooks()
+	assert.Eq("called once", calls, "21")
+}
+
+func TestExitCloseLoggers(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &closeRecorder{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	l.Info("a")
+	closeLoggers([]*Logger{l}, time.Second)
+	assert.Eq("written", w.String(), "a\n")
+	assert.Eq("closed", w.closed, 1)
+	_, open := openQueues.m[l.q]
+	assert.Ok("unregistered", !open)
+
+	bw := &blockingWriter{unblock: make(chan struct{})}
+	wedged := NewLogger(bw, "", LevelInfo, 0)
+	wedged.Info("b")
+	start := time.Now()
+	closeLoggers([]*Logger{wedged}, 20*time.Millisecond)
+	assert.Ok("gave up", time.Since(start) < time.Second)
+	close(bw.unblock)
+	wedged.Sync()
+	assert.Eq("written after unblocking", bw.String(), "b\n")
+}
+
+func TestExit(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	defer func() { osExit = os.Exit }()
+	code := -1
+	osExit = func(c int) { code = c }
+
+	// Exit closes all loggers; hide the loggers of other tests
+	root := RootLogger
+	defer func() { RootLogger = root }()
+	w := &bytes.Buffer{}
+	RootLogger = NewLogger(w, "", LevelInfo, 0)
+	openQueues.mu.Lock()
+	open := openQueues.m
+	openQueues.m = map[*queue]*Logger{RootLogger.q: RootLogger}
+	openQueues.mu.Unlock()
+	defer func() { openQueues.m = open }()
+	OnExit(func() { Info("exiting") })
+	Exit(3)
+	exitMu.Unlock()
+	assert.Eq("code", code, 3)
+	assert.Eq("output", w.String(), "exiting\n")
+}
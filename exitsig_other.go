@@ -0,0 +1,21 @@
+//go:build !plan9
+// +build !plan9
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultExitSignals are the signals handled by ExitOnSignal when none are given
+var defaultExitSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// signalExitCode returns the exit code of a process terminated by sig: 128 plus the signal
+// number, or 1 if sig has no number
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
@@ -0,0 +1,12 @@
+package log
+
+import "os"
+
+// defaultExitSignals are the signals handled by ExitOnSignal when none are given.
+// Plan 9 has notes rather than signals, of which only interrupt is portable.
+var defaultExitSignals = []os.Signal{os.Interrupt}
+
+// signalExitCode returns 1 since notes have no numbers
+func signalExitCode(sig os.Signal) int {
+	return 1
+}
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Expect registers an expectation that exactly count records of level with a message matching
+// the regular expression pattern are logged before the next call to Verify.
+// A count of zero expects that no such records are logged.
+//
+// Expectations apply to records logged by l, its parent and sub-loggers, and are meant for
+// tests which treat logging as part of the behavior under test:
+//
+//   logger.Expect(log.LevelWarn, `^retrying`, 2)
+//   logger.Expect(log.LevelError, ``, 0) // no errors
+//   doThing(logger)
+//   if err := logger.Verify(); err != nil {
+//     t.Error(err)
+//   }
+//
+func (l *Logger) Expect(level Level, pattern string, count int) {
+	l.q.expect.add(expectation{
+		level:   level,
+		pattern: regexp.MustCompile(pattern),
+		count:   count,
+	})
+}
+
+// Verify checks that records logged since the first call to Expect match all expectations.
+// If they do not, an error describing expected vs observed records is returned.
+// Verify clears all expectations and observed records.
+func (l *Logger) Verify() error {
+	return l.q.expect.verify()
+}
+
+type expectation struct {
+	level   Level
+	pattern *regexp.Regexp
+	count   int
+}
+
+type observedRecord struct {
+	level  Level
+	prefix string
+	msg    string
+}
+
+// expectations holds the state of Expect and Verify
+type expectations struct {
+	n        int32 // number of expectations; read atomically
+	mu       sync.Mutex
+	expected []expectation
+	observed []observedRecord
+}
+
+func (e *expectations) active() bool {
+	return atomic.LoadInt32(&e.n) != 0
+}
+
+func (e *expectations) add(x expectation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expected = append(e.expected, x)
+	atomic.StoreInt32(&e.n, int32(len(e.expected)))
+}
+
+func (e *expectations) observe(m *logRecord) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.expected) > 0 {
+		e.observed = append(e.observed, observedRecord{m.level, m.logger.Prefix, string(m.msg)})
+	}
+}
+
+func (e *expectations) verify() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	expected, observed := e.expected, e.observed
+	e.expected, e.observed = nil, nil
+	atomic.StoreInt32(&e.n, 0)
+
+	var failed []string
+	for _, x := range expected {
+		n := 0
+		for _, r := range observed {
+			if r.level == x.level && x.pattern.MatchString(r.msg) {
+				n++
+			}
+		}
+		if n != x.count {
+			failed = append(failed, fmt.Sprintf("  - expected %d, got %d %s records matching %q",
+				x.count, n, x.level, x.pattern))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	sb.WriteString("log records did not match expectations:\n")
+	for _, s := range failed {
+		sb.WriteString(s)
+		sb.WriteByte('\n')
+	}
+	if len(observed) == 0 {
+		sb.WriteString("no records were logged")
+	} else {
+		fmt.Fprintf(&sb, "observed %d records:", len(observed))
+		for _, r := range observed {
+			sb.WriteString("\n  ")
+			sb.WriteString(levelPrefixPlain[r.level])
+			if r.prefix != "" {
+				sb.WriteString(r.prefix)
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(strings.TrimRight(r.msg, "\n"))
+		}
+	}
+	return fmt.Errorf("%s", sb.String())
+}
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestExpect(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	l := NewLogger(ioutil.Discard, "", LevelDebug, FDefault)
+	defer l.Close()
+	sub := l.SubLogger("[sub]")
+
+	l.Info("before any expectations") // not observed
+	l.Expect(LevelWarn, `^retrying`, 2)
+	l.Expect(LevelError, ``, 0)
+	l.Expect(LevelDebug, `connect`, 1)
+	l.Warn("retrying (1)")
+	sub.Warn("retrying (2)")
+	l.Info("retrying is a warning, not info")
+	l.Debug("connected")
+	assert.NoErr("verify", l.Verify())
+
+	// expectations are cleared by Verify
+	l.Error("boom")
+	assert.NoErr("verify without expectations", l.Verify())
+
+	l.Expect(LevelWarn, `^retrying`, 2)
+	l.Expect(LevelError, ``, 0)
+	l.Warn("retrying (1)")
+	sub.Error("giving up")
+	err := l.Verify()
+	assert.Err("verify", "", err)
+	if err != nil {
+		assert.Eq("error message", err.Error(), ""+
+			"log records did not match expectations:\n"+
+			"  - expected 2, got 1 warn records matching \"^retrying\"\n"+
+			"  - expected 0, got 1 error records matching \"\"\n"+
+			"observed 2 records:\n"+
+			"  [warn] retrying (1)\n"+
+			"  [error] [sub] giving up")
+	}
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FailoverWriter writes to a primary writer and falls back to a secondary writer when the
+// primary fails repeatedly, for example when a log file is on a disk which has gone away.
+// While failed over, the primary is retried with exponential backoff and is used again as
+// soon as a write to it succeeds. A notice is written to the secondary writer when failing
+// over and when recovering.
+//
+//   f, _ := os.OpenFile("/var/log/app.log", os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+//   logger.SetWriter(log.NewFailoverWriter(f, os.Stderr))
+//
+// Data which fails to be written to the primary is written to the secondary, so nothing is
+// lost as long as the secondary works.
+type FailoverWriter struct {
+	MaxErrors  int           // consecutive errors before failing over. Defaults to 3.
+	MinBackoff time.Duration // initial time to wait before retrying the primary. Defaults to 1s.
+	MaxBackoff time.Duration // maximum time between retries. Defaults to 1 minute.
+
+	primary   io.Writer
+	secondary io.Writer
+	now       func() time.Time
+
+	mu      sync.Mutex
+	errors  int  // consecutive errors of primary
+	failed  bool // writing to secondary
+	backoff time.Duration
+	retryAt time.Time
+}
+
+// NewFailoverWriter creates a FailoverWriter with default settings
+func NewFailoverWriter(primary, secondary io.Writer) *FailoverWriter {
+	return &FailoverWriter{
+		MaxErrors:  3,
+		MinBackoff: time.Second,
+		MaxBackoff: time.Minute,
+		primary:    primary,
+		secondary:  secondary,
+		now:        time.Now,
+	}
+}
+
+// Failed returns true if w is currently writing to the secondary writer
+func (w *FailoverWriter) Failed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.failed
+}
+
+func (w *FailoverWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed {
+		if w.now().Before(w.retryAt) {
+			return w.secondary.Write(p)
+		}
+		if n, err := w.primary.Write(p); err == nil {
+			w.failed = false
+			w.errors = 0
+			w.notice("primary writer recovered")
+			return n, nil
+		}
+		w.backoff *= 2
+		if w.backoff > w.MaxBackoff {
+			w.backoff = w.MaxBackoff
+		}
+		w.retryAt = w.now().Add(w.backoff)
+		return w.secondary.Write(p)
+	}
+	n, err := w.primary.Write(p)
+	if err == nil {
+		w.errors = 0
+		return n, nil
+	}
+	w.errors++
+	if w.errors >= w.MaxErrors {
+		w.failed = true
+		w.backoff = w.MinBackoff
+		w.retryAt = w.now().Add(w.backoff)
+		w.notice(fmt.Sprintf("primary writer failed %d times (%v); writing here instead", w.errors, err))
+	}
+	return w.secondary.Write(p)
+}
+
+// notice writes a message about failover to the secondary writer
+func (w *FailoverWriter) notice(msg string) {
+	fmt.Fprintf(w.secondary, "%s [warn] log: %s\n", w.now().Format("2006-01-02 15:04:05"), msg)
+}
+
+// Sync syncs the writer currently in use, if it has a Sync method. See FFsync
+func (w *FailoverWriter) Sync() error {
+	w.mu.Lock()
+	cw := w.primary
+	if w.failed {
+		cw = w.secondary
+	}
+	w.mu.Unlock()
+	return syncWriter(cw)
+}
+
+// Close closes both writers, if they implement io.Closer (except for os.Stdout and os.Stderr)
+func (w *FailoverWriter) Close() error {
+	var err error
+	for _, cw := range []io.Writer{w.primary, w.secondary} {
+		if c, ok := cw.(io.Closer); ok && cw != os.Stdout && cw != os.Stderr {
+			if e := c.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+// flakyWriter fails all writes while broken is true
+type flakyWriter struct {
+	bytes.Buffer
+	broken bool
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.broken {
+		return 0, errors.New("broken")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFailoverWriter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	primary := &flakyWriter{}
+	secondary := &bytes.Buffer{}
+	now := time.Unix(0, 0)
+	w := NewFailoverWriter(primary, secondary)
+	w.MaxErrors = 2
+	w.now = func() time.Time { return now }
+
+	w.Write([]byte("a\n"))
+	primary.broken = true
+	w.Write([]byte("b\n"))
+	assert.Ok("not failed after one error", !w.Failed())
+	w.Write([]byte("c\n"))
+	assert.Ok("failed after two errors", w.Failed())
+	assert.Eq("primary", primary.String(), "a\n")
+	lines := strings.Split(secondary.String(), "\n")
+	assert.Eq("data written to secondary", lines[0]+lines[2], "bc")
+	assert.Ok("failover notice; got %q", strings.Contains(lines[1], "primary writer failed 2 times (broken)"), lines[1])
+
+	// no retry before backoff has passed
+	primary.broken = false
+	w.Write([]byte("d\n"))
+	assert.Eq("primary not retried", primary.String(), "a\n")
+
+	// failed retry doubles backoff
+	primary.broken = true
+	now = now.Add(time.Second)
+	w.Write([]byte("e\n"))
+	assert.Eq("backoff", w.backoff, 2*time.Second)
+
+	primary.broken = false
+	now = now.Add(2 * time.Second)
+	w.Write([]byte("f\n"))
+	assert.Ok("recovered", !w.Failed())
+	assert.Eq("primary after recovery", primary.String(), "a\nf\n")
+	assert.Ok("recovery notice", strings.HasSuffix(secondary.String(), "primary writer recovered\n"))
+}
@@ -0,0 +1,510 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FileOptions configures a FileWriter
+type FileOptions struct {
+	MaxSize    int64       // rotate the file before it grows beyond this many bytes; 0 = never
+	MaxBackups int         // number of rotated files to keep (at least 1)
+	Perm       os.FileMode // permissions of new files; 0644 if zero
+
+	// Retention of rotated files. MaxTotalSize removes the oldest rotated files while the size
+	// of the file and its rotated files together exceeds it. MaxAge removes rotated files last
+	// written to longer ago than it. Either is checked after each rotation and once a minute by
+	// a goroutine, which is stopped by Close. See also FileWriter.Cleanup. 0 = no limit.
+	MaxTotalSize int64
+	MaxAge       time.Duration
+
+	// Compress makes the FileWriter write a compressed stream, e.g. Gzip(gzip.BestSpeed). It is
+	// called with the file each time the file is opened (including after rotation), and the
+	// stream is closed when the file is closed, so an existing file is appended to with a new
+	// stream. Gzip and zstd decoders read such concatenated streams as one.
+	// MaxSize applies to the compressed size. To use zstd (github.com/klauspost/compress/zstd):
+	//
+	//   Compress: func(w io.Writer) (log.CompressWriter, error) { return zstd.NewWriter(w) },
+	//
+	Compress func(w io.Writer) (CompressWriter, error)
+
+	// Encrypt makes the FileWriter encrypt the file for the holder of the private key of the
+	// public key Encrypt (see GenerateEncryptionKey), so that the file can't be read on the
+	// machine writing it. Like compressed output, an existing file is appended to with a new
+	// stream. When combined with Compress, data is compressed before it's encrypted. Encrypted
+	// files are read with NewDecryptingReader or the logdecrypt command.
+	Encrypt []byte
+
+	// FlushInterval is how long compressed or encrypted data may be held back before it is
+	// flushed to the file, so that tools like "zcat | tail" see recent messages. Sync flushes
+	// as well. 1s if zero.
+	FlushInterval time.Duration
+
+	// Lock makes the FileWriter hold an advisory lock (flock) on the file during each write, so
+	// that several processes can log to the same file, e.g. forked workers, without
+	// interleaving partial lines. When the file is rotated by one process, the others continue
+	// with the new file, and the size of the file written by all of them counts for MaxSize.
+	// Lock can't be combined with Compress or Encrypt, and is only supported on Unix systems.
+	Lock bool
+}
+
+var errFileLocking = errors.New("log: file locking is not supported on this platform")
+
+// CompressWriter is a compressing writer; see FileOptions.Compress
+type CompressWriter interface {
+	io.WriteCloser
+	Flush() error // writes any pending data
+}
+
+// Gzip returns a FileOptions.Compress function which writes gzip streams with the given
+// compression level, e.g. gzip.DefaultCompression
+func Gzip(level int) func(w io.Writer) (CompressWriter, error) {
+	return func(w io.Writer) (CompressWriter, error) {
+		return gzip.NewWriterLevel(w, level)
+	}
+}
+
+// FileWriter appends to a file and optionally rotates it when it grows too large.
+// Rotated files are named path.1 (the most recent), path.2 and so on.
+//
+// Example:
+//
+//   f, err := log.OpenFile("/var/log/app.log", &log.FileOptions{MaxSize: 10 << 20})
+//   if err != nil {
+//     panic(err)
+//   }
+//   logger.SetWriter(f)
+//
+type FileWriter struct {
+	path string
+	opts FileOptions
+
+	mu    sync.Mutex
+	f     *os.File
+	size  int64
+	z     CompressWriter // non-nil when compressing or encrypting; writes to f via fileSizeWriter
+	timer *time.Timer    // pending flush of z
+
+	rotated chan struct{} // signals the janitor (if any) that the file was rotated
+	stop    chan struct{} // closed to stop the janitor
+}
+
+// cleanupInterval is how often the janitor of a FileWriter with retention limits runs
+const cleanupInterval = time.Minute
+
+// OpenFile opens (or creates) the file at path for appending. opts may be nil.
+func OpenFile(path string, opts *FileOptions) (*FileWriter, error) {
+	w := &FileWriter{path: path}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.Perm == 0 {
+		w.opts.Perm = 0644
+	}
+	if w.opts.MaxBackups < 1 {
+		w.opts.MaxBackups = 1
+	}
+	if w.opts.FlushInterval <= 0 {
+		w.opts.FlushInterval = time.Second
+	}
+	if w.opts.Lock {
+		if !fileLocking {
+			return nil, errFileLocking
+		}
+		if w.opts.Compress != nil || len(w.opts.Encrypt) > 0 {
+			return nil, errors.New("log: FileOptions.Lock can't be combined with Compress or Encrypt")
+		}
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	openFiles.Lock()
+	openFiles.m[w] = struct{}{}
+	openFiles.Unlock()
+	if w.opts.MaxTotalSize > 0 || w.opts.MaxAge > 0 {
+		w.rotated = make(chan struct{}, 1)
+		w.stop = make(chan struct{})
+		go w.janitor(w.rotated, w.stop)
+	}
+	return w, nil
+}
+
+// Path returns the path of the file
+func (w *FileWriter) Path() string {
+	return w.path
+}
+
+func (w *FileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.opts.Perm)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.size = st.Size()
+	if len(w.opts.Encrypt) > 0 {
+		enc, err := NewEncryptingWriter((*fileSizeWriter)(w), w.opts.Encrypt)
+		if err != nil {
+			f.Close()
+			w.f = nil
+			return err
+		}
+		w.z = enc
+	}
+	if w.opts.Compress != nil {
+		var out io.Writer = (*fileSizeWriter)(w)
+		if w.z != nil {
+			out = w.z
+		}
+		z, err := w.opts.Compress(out)
+		if err != nil {
+			f.Close()
+			w.f = nil
+			w.z = nil
+			return err
+		}
+		if w.z != nil {
+			z = &chainWriter{z, w.z}
+		}
+		w.z = z
+	}
+	return nil
+}
+
+// chainWriter is a CompressWriter writing to another one, like a compressor writing to an
+// EncryptingWriter. Flush and Close apply to both.
+type chainWriter struct {
+	CompressWriter
+	next CompressWriter
+}
+
+func (c *chainWriter) Flush() error {
+	err := c.CompressWriter.Flush()
+	if e := c.next.Flush(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (c *chainWriter) Close() error {
+	err := c.CompressWriter.Close()
+	if e := c.next.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// closeFile closes the compressor and encryption, if any, and the file
+func (w *FileWriter) closeFile() error {
+	var err error
+	if w.z != nil {
+		if w.timer != nil {
+			w.timer.Stop()
+			w.timer = nil
+		}
+		err = w.z.Close()
+		w.z = nil
+	}
+	if e := w.f.Close(); err == nil {
+		err = e
+	}
+	w.f = nil
+	return err
+}
+
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	if w.opts.Lock {
+		if err := w.lock(); err != nil {
+			return 0, err
+		}
+		defer func() {
+			if w.f != nil { // w.f may have changed by rotation
+				unlockFile(w.f)
+			}
+		}()
+	}
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+		if w.opts.Lock {
+			if err := w.lock(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if w.z != nil {
+		if w.timer == nil {
+			w.timer = time.AfterFunc(w.opts.FlushInterval, w.flushTimer)
+		}
+		return w.z.Write(p)
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// lock locks the file against writes by other processes (see FileOptions.Lock) and updates
+// w.size to include their writes. If another process has rotated the file, the new file is
+// opened and locked instead.
+func (w *FileWriter) lock() error {
+	for {
+		if err := lockFile(w.f); err != nil {
+			return err
+		}
+		st, err := w.f.Stat()
+		if err != nil {
+			unlockFile(w.f)
+			return err
+		}
+		cur, err := os.Stat(w.path)
+		if err == nil && os.SameFile(st, cur) {
+			w.size = st.Size()
+			return nil
+		}
+		if err != nil && !os.IsNotExist(err) {
+			unlockFile(w.f)
+			return err
+		}
+		// rotated by another process; closing the file releases the lock
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+}
+
+// flushTimer flushes the compressor when FlushInterval has passed since the first write after
+// the previous flush
+func (w *FileWriter) flushTimer() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.z != nil {
+		w.z.Flush()
+	}
+	w.timer = nil
+}
+
+// fileSizeWriter writes the output of a compressor to the file, counting its size.
+// w.mu is held since the compressor is only used with it held.
+type fileSizeWriter FileWriter
+
+func (w *fileSizeWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate renames the file to path.1 (after renaming path.1 to path.2 and so on) and starts
+// a new file at path
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	if w.opts.Lock {
+		if err := w.lock(); err != nil {
+			return err
+		}
+		defer func() {
+			if w.f != nil {
+				unlockFile(w.f)
+			}
+		}()
+	}
+	return w.rotate()
+}
+
+// rotate renames the files and opens a new one. With FileOptions.Lock, the file must be locked
+// (see lock, which also checks that it is still the file at path), and it stays locked until
+// it has been renamed. Other processes waiting for the lock thus find the new file when they
+// get it, rather than rotating again and removing or renaming the file rotated by this one.
+func (w *FileWriter) rotate() error {
+	if !w.opts.Lock {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+	}
+	os.Remove(w.backupPath(w.opts.MaxBackups))
+	for i := w.opts.MaxBackups - 1; i > 0; i-- {
+		os.Rename(w.backupPath(i), w.backupPath(i+1))
+	}
+	if err := os.Rename(w.path, w.backupPath(1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if w.opts.Lock {
+		if err := w.closeFile(); err != nil { // releases the lock
+			return err
+		}
+	}
+	if w.rotated != nil {
+		select {
+		case w.rotated <- struct{}{}:
+		default: // already signalled
+		}
+	}
+	return w.open()
+}
+
+// Cleanup removes rotated files which exceed the retention limits MaxTotalSize and MaxAge of
+// FileOptions. This is done automatically after rotation and periodically; calling Cleanup is
+// only needed to apply the limits right away, e.g. when the program starts.
+func (w *FileWriter) Cleanup() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var total int64
+	if st, err := os.Stat(w.path); err == nil {
+		total = st.Size()
+	}
+	now := time.Now()
+	var err error
+	for i := 1; i <= w.opts.MaxBackups; i++ { // newest first
+		path := w.backupPath(i)
+		st, e := os.Stat(path)
+		if e != nil {
+			if !os.IsNotExist(e) && err == nil {
+				err = e
+			}
+			continue
+		}
+		total += st.Size()
+		if w.opts.MaxTotalSize > 0 && total > w.opts.MaxTotalSize ||
+			w.opts.MaxAge > 0 && now.Sub(st.ModTime()) > w.opts.MaxAge {
+			if e := os.Remove(path); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// janitor calls Cleanup after rotation and every cleanupInterval until stop is closed
+func (w *FileWriter) janitor(rotated, stop <-chan struct{}) {
+	t := time.NewTicker(cleanupInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-rotated:
+		case <-stop:
+			return
+		}
+		if err := w.Cleanup(); err != nil {
+			RootLogger.Error("failed to clean up log files of %s: %v", w.path, err)
+		}
+	}
+}
+
+// Reopen closes the file and opens path again. This is used with external log rotation tools
+// like logrotate, which rename the file and then signal the process to reopen it (see
+// HandleSignals.) Writes made during Reopen wait for it to finish and are not lost.
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// backupPath returns the path of the nth rotated file
+func (w *FileWriter) backupPath(n int) string {
+	return w.path + "." + strconv.Itoa(n)
+}
+
+// Sync commits the file to stable storage, after flushing the compressor if any. See FFsync
+func (w *FileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	if w.z != nil {
+		if err := w.z.Flush(); err != nil {
+			return err
+		}
+	}
+	return w.f.Sync()
+}
+
+// Close closes the file. Writes after Close fail with os.ErrClosed.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+	if w.f == nil {
+		return nil
+	}
+	err := w.closeFile()
+	openFiles.Lock()
+	delete(openFiles.m, w)
+	openFiles.Unlock()
+	return err
+}
+
+// openFiles holds all FileWriters which are open, for ReopenFiles
+var openFiles = struct {
+	sync.Mutex
+	m map[*FileWriter]struct{}
+}{m: make(map[*FileWriter]struct{})}
+
+// ReopenFiles calls Reopen on all open FileWriters and returns the first error
+func ReopenFiles() error {
+	openFiles.Lock()
+	files := make([]*FileWriter, 0, len(openFiles.m))
+	for w := range openFiles.m {
+		files = append(files, w)
+	}
+	openFiles.Unlock()
+	var err error
+	for _, w := range files {
+		if e := w.Reopen(); e != nil && e != os.ErrClosed && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// HandleSignals reopens all FileWriters (see ReopenFiles) whenever the process receives
+// SIGHUP, which is how logrotate and similar tools ask a process to reopen its log files.
+// For example, with this logrotate configuration:
+//
+//   /var/log/app.log {
+//     daily
+//     postrotate
+//       kill -HUP $(cat /var/run/app.pid)
+//     endscript
+//   }
+//
+// Errors are logged to RootLogger. Call stop to stop handling SIGHUP.
+// On platforms without SIGHUP, like js/wasm and Plan 9, HandleSignals does nothing.
+func HandleSignals() (stop func()) {
+	return onHangup(func() {
+		if err := ReopenFiles(); err != nil {
+			RootLogger.Error("failed to reopen log files: %v", err)
+		}
+	})
+}
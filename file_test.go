@@ -0,0 +1,136 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestFileWriter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir, err := ioutil.TempDir("", "log-test")
+	assert.NoErr("TempDir", err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	f, err := OpenFile(path, &FileOptions{MaxSize: 10, MaxBackups: 2})
+	assert.NoErr("OpenFile", err)
+	for _, s := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffff\n", "gggg\n"} {
+		_, err := f.Write([]byte(s))
+		assert.NoErr("Write", err)
+	}
+	assert.NoErr("Close", f.Close())
+	_, err = f.Write([]byte("x"))
+	assert.Eq("write after close", err, os.ErrClosed)
+
+	read := func(name string) string {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+	assert.Eq("app.log", read("app.log"), "gggg\n")
+	assert.Eq("app.log.1", read("app.log.1"), "eeee\nffff\n")
+	assert.Eq("app.log.2", read("app.log.2"), "cccc\ndddd\n")
+	_, err = os.Stat(filepath.Join(dir, "app.log.3"))
+	assert.Ok("app.log.3 does not exist", os.IsNotExist(err))
+
+	// appends to existing file
+	f, err = OpenFile(path, nil)
+	assert.NoErr("OpenFile", err)
+	f.Write([]byte("hhhh\n"))
+	f.Close()
+	assert.Eq("app.log", read("app.log"), "gggg\nhhhh\n")
+}
+
+func TestFileWriterCompress(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir, err := ioutil.TempDir("", "log-test")
+	assert.NoErr("TempDir", err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log.gz")
+	gunzip := func(name string) (string, error) {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadAll(r)
+		return string(data), err
+	}
+
+	opts := &FileOptions{Compress: Gzip(gzip.BestSpeed), FlushInterval: 10 * time.Millisecond}
+	f, err := OpenFile(path, opts)
+	assert.NoErr("OpenFile", err)
+	f.Write([]byte("aaaa\n"))
+	assert.NoErr("Sync", f.Sync())
+	s, err := gunzip("app.log.gz")
+	assert.Eq("readable after Sync", s, "aaaa\n")
+	assert.Eq("stream not yet closed", err, io.ErrUnexpectedEOF)
+
+	f.Write([]byte("bbbb\n"))
+	time.Sleep(100 * time.Millisecond)
+	s, _ = gunzip("app.log.gz")
+	assert.Eq("flushed after FlushInterval", s, "aaaa\nbbbb\n")
+	assert.NoErr("Close", f.Close())
+
+	// appends a new stream to the existing file
+	f, err = OpenFile(path, opts)
+	assert.NoErr("OpenFile", err)
+	f.Write([]byte("cccc\n"))
+	assert.NoErr("Rotate", f.Rotate())
+	f.Write([]byte("dddd\n"))
+	assert.NoErr("Close", f.Close())
+	s, err = gunzip("app.log.gz.1")
+	assert.NoErr("gunzip", err)
+	assert.Eq("rotated", s, "aaaa\nbbbb\ncccc\n")
+	s, err = gunzip("app.log.gz")
+	assert.NoErr("gunzip", err)
+	assert.Eq("current", s, "dddd\n")
+}
+
+func TestFileWriterRetention(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir, err := ioutil.TempDir("", "log-test")
+	assert.NoErr("TempDir", err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	f, err := OpenFile(path, &FileOptions{MaxBackups: 5, MaxTotalSize: 12})
+	assert.NoErr("OpenFile", err)
+	for _, s := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n"} {
+		f.Write([]byte(s))
+		assert.NoErr("Rotate", f.Rotate())
+	}
+	assert.NoErr("Cleanup", f.Cleanup())
+	assert.Ok("app.log.1 kept", exists("app.log.1"))
+	assert.Ok("app.log.2 kept", exists("app.log.2"))
+	assert.Ok("app.log.3 removed", !exists("app.log.3"))
+	assert.Ok("app.log.4 removed", !exists("app.log.4"))
+	assert.NoErr("Close", f.Close())
+
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(dir, "app.log.2"), old, old)
+	f, err = OpenFile(path, &FileOptions{MaxBackups: 5, MaxAge: time.Hour})
+	assert.NoErr("OpenFile", err)
+	assert.NoErr("Rotate", f.Rotate()) // app.log.2 becomes app.log.3; janitor runs
+	deadline := time.Now().Add(5 * time.Second)
+	for exists("app.log.3") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Ok("old file removed by janitor", !exists("app.log.3"))
+	assert.Ok("app.log.2 kept", exists("app.log.2"))
+	assert.NoErr("Close", f.Close())
+}
@@ -0,0 +1,141 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestFileWriterReopen(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir, err := ioutil.TempDir("", "log-test")
+	assert.NoErr("TempDir", err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	f, err := OpenFile(path, nil)
+	assert.NoErr("OpenFile", err)
+	defer f.Close()
+	l := NewLogger(f, "", LevelInfo, 0)
+	defer l.Close()
+
+	stop := HandleSignals()
+	defer stop()
+
+	l.Info("a")
+	l.Sync()
+	assert.NoErr("Rename", os.Rename(path, path+".old")) // like logrotate
+	l.Info("b")
+	l.Sync()
+	assert.NoErr("Kill", syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	l.Info("c")
+	l.Sync()
+
+	read := func(name string) string {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+	assert.Eq("app.log.old", read("app.log.old"), "a\nb\n")
+	assert.Eq("app.log", read("app.log"), "c\n")
+}
+
+func TestFileWriterLock(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	read := func(name string) string {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+
+	// two writers of the same file, like two processes
+	opts := &FileOptions{MaxSize: 10, MaxBackups: 2, Lock: true}
+	w1, err := OpenFile(path, opts)
+	assert.NoErr("OpenFile", err)
+	defer w1.Close()
+	w2, err := OpenFile(path, opts)
+	assert.NoErr("OpenFile", err)
+	defer w2.Close()
+
+	w1.Write([]byte("aaaa\n"))
+	w2.Write([]byte("bbbb\n"))
+	w2.Write([]byte("cccc\n")) // rotates, since the file holds the line of w1 as well
+	w1.Write([]byte("dddd\n")) // continues with the file rotated by w2
+	assert.Eq("app.log.1", read("app.log.1"), "aaaa\nbbbb\n")
+	assert.Eq("app.log", read("app.log"), "cccc\ndddd\n")
+
+	_, err = OpenFile(path, &FileOptions{Lock: true, Compress: Gzip(1)})
+	assert.Err("Lock with Compress", "can't be combined", err)
+}
+
+func TestFileWriterLockConcurrentRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	const maxSize, writers, lines = 200, 4, 300
+	opts := &FileOptions{MaxSize: maxSize, MaxBackups: 1000, Lock: true}
+
+	// several writers of the same file, like processes, rotating it concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		w, err := OpenFile(path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int, w *FileWriter) {
+			defer wg.Done()
+			defer w.Close()
+			for j := 0; j < lines; j++ {
+				if _, err := fmt.Fprintf(w, "%d-%04d\n", i, j); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i, w)
+	}
+	wg.Wait()
+
+	// no line is lost and no file is rotated before it's full
+	seen := make(map[string]bool)
+	for n := 0; ; n++ {
+		name := path
+		if n > 0 {
+			name = fmt.Sprintf("%s.%d", path, n)
+		}
+		data, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if n > 0 && len(data) <= maxSize-len("0-0000\n") {
+			t.Errorf("%s rotated at %d bytes", name, len(data))
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if seen[line] {
+				t.Fatalf("line %q written twice", line)
+			}
+			seen[line] = true
+		}
+	}
+	if len(seen) != writers*lines {
+		t.Errorf("%d lines written; expected %d", len(seen), writers*lines)
+	}
+}
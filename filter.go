@@ -0,0 +1,25 @@
+package log
+
+// SetFilter sets a function which decides whether messages logged by l, its parent and
+// sub-loggers are kept. Messages for which f returns false are discarded before they are queued
+// and are not counted in Metrics. This is useful for centrally silencing known, noisy messages:
+//
+//   logger.SetFilter(func(level log.Level, msg string) bool {
+//     return !strings.HasPrefix(msg, "GET /healthz")
+//   })
+//
+// f is called on the goroutine which logged the message and must be safe for concurrent use.
+// msg is the message without header or prefix, before redaction (see AddRedactor.)
+// Since f is passed the formatted message, every message is formatted on the logging goroutine
+// while a filter is set, including those which f rejects and those which would otherwise be
+// formatted in the write loop (see Safe). Use SetLevel to discard messages unformatted.
+// Passing nil removes the filter.
+func (l *Logger) SetFilter(f func(level Level, msg string) bool) {
+	l.q.filter.Store(f)
+}
+
+// filtered returns true if m is rejected by the filter of q
+func (q *queue) filtered(m *logRecord) bool {
+	f, _ := q.filter.Load().(func(Level, string) bool)
+	return f != nil && !f(m.level, string(m.msg))
+}
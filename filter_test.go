@@ -0,0 +1,33 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestSetFilter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+
+	l.SetFilter(func(level Level, msg string) bool {
+		return level >= LevelWarn || !strings.HasPrefix(msg, "GET /healthz")
+	})
+	l.Info("GET /healthz 200")
+	l.SubLogger("[sub]").Info("GET /healthz 200")
+	l.Warn("GET /healthz 500")
+	l.Info("GET /api 200")
+	l.Sync()
+	assert.Eq("filtered output", w.String(), "GET /healthz 500\nGET /api 200\n")
+	assert.Eq("filtered records not counted", l.Metrics().Records[LevelInfo], uint64(1))
+
+	w.Reset()
+	l.SetFilter(nil)
+	l.Info("GET /healthz 200")
+	l.Sync()
+	assert.Eq("filter removed", w.String(), "GET /healthz 200\n")
+}
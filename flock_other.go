@@ -0,0 +1,10 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package log
+
+import "os"
+
+const fileLocking = false
+
+func lockFile(f *os.File) error   { return errFileLocking }
+func unlockFile(f *os.File) error { return errFileLocking }
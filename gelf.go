@@ -0,0 +1,221 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// GELFWriter writes records in the Graylog Extended Log Format (GELF 1.1) to a Graylog
+// server or other GELF-compatible collector. Levels are mapped to syslog severities and the
+// prefix and scope of records are sent as the additional fields "_prefix" and "_scope".
+// Fields of records are sent as additional fields too, with characters other than letters,
+// digits, '_', '.' and '-' in their keys replaced by '_'. Since GELF reserves "_id", and to not
+// clash with the fields above, the keys "id", "level_name", "prefix" and "scope" get a
+// trailing '_', e.g. "_id_".
+//
+//   logger.SetWriter(log.DialGELF("udp", "graylog.example.com:12201", nil))
+//
+type GELFWriter struct {
+	// Host is the "host" field of messages. Defaults to the hostname of the machine.
+	Host string
+
+	// ChunkSize is the maximum size of UDP datagrams. Larger messages are compressed and,
+	// if still too large, split into chunks. Defaults to 8192. Only used in UDP mode.
+	ChunkSize int
+
+	w   io.Writer
+	udp bool
+}
+
+// NewGELFWriter creates a GELFWriter which writes messages to w. If udp is true, each
+// message (or chunk) is written with a separate call to w.Write, suitable for a connected
+// UDP socket. Otherwise messages are null-byte delimited, as GELF TCP expects.
+func NewGELFWriter(w io.Writer, udp bool) *GELFWriter {
+	host, _ := os.Hostname()
+	return &GELFWriter{Host: host, ChunkSize: 8192, w: w, udp: udp}
+}
+
+// DialGELF creates a GELFWriter sending to addr using a NetWriter.
+// network should be "udp" or "tcp". opts may be nil.
+func DialGELF(network, addr string, opts *DialOptions) *GELFWriter {
+	return NewGELFWriter(DialWriter(network, addr, opts), strings.HasPrefix(network, "udp"))
+}
+
+// Close closes the underlying writer, if it implements io.Closer
+func (g *GELFWriter) Close() error {
+	if c, ok := g.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Write sends each line of p as a LevelInfo message
+func (g *GELFWriter) Write(p []byte) (int, error) {
+	now := time.Now()
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		if err := g.WriteRecord(&Record{Level: LevelInfo, Time: now, Msg: line}); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (g *GELFWriter) writeRecord(m *logRecord) error {
+	r := m.record()
+	return g.WriteRecord(&r)
+}
+
+// gelfSeverity maps levels to syslog severities
+var gelfSeverity = [...]int{
+	LevelDebug:   7, // debug
+	LevelInfo:    6, // informational
+	LevelWarn:    4, // warning
+	LevelError:   3, // error
+	LevelDisable: 6,
+	levelTime:    6,
+}
+
+type gelfMessage struct {
+	Version      string  `json:"version"`
+	Host         string  `json:"host"`
+	ShortMessage string  `json:"short_message"`
+	FullMessage  string  `json:"full_message,omitempty"`
+	Timestamp    float64 `json:"timestamp"`
+	Level        int     `json:"level"`
+	LevelName    string  `json:"_level_name"`
+	Prefix       string  `json:"_prefix,omitempty"`
+	Scope        string  `json:"_scope,omitempty"`
+}
+
+// WriteRecord sends r as a GELF message
+func (g *GELFWriter) WriteRecord(r *Record) error {
+	msg := strings.TrimRight(r.Msg, "\n")
+	gm := gelfMessage{
+		Version:      "1.1",
+		Host:         g.Host,
+		ShortMessage: msg,
+		Timestamp:    float64(r.Time.UnixNano()/1e6) / 1e3,
+		Level:        6,
+		LevelName:    r.Level.String(),
+		Prefix:       r.Prefix,
+		Scope:        strings.Join(r.Scope, ">"),
+	}
+	if r.Level >= 0 && int(r.Level) < len(gelfSeverity) {
+		gm.Level = gelfSeverity[r.Level]
+	}
+	if i := strings.IndexByte(msg, '\n'); i != -1 {
+		gm.ShortMessage = msg[:i]
+		gm.FullMessage = msg
+	}
+	if gm.Host == "" {
+		gm.Host = "localhost" // required by GELF
+	}
+	data, err := json.Marshal(&gm)
+	if err != nil {
+		return err
+	}
+	if len(r.Fields) > 0 {
+		data = appendGELFFields(data[:len(data)-1], r.Fields) // replace closing '}'
+	}
+	if !g.udp {
+		data = append(data, 0)
+		_, err = g.w.Write(data)
+		return err
+	}
+	return g.writeUDP(data)
+}
+
+// appendGELFFields appends fields as GELF additional fields ("_key":"value") to the JSON object
+// data, which is missing its closing brace
+func appendGELFFields(data []byte, fields map[string]string) []byte {
+	var seen map[string]bool // keys already appended, which differ only in invalid characters
+	for _, f := range sortedFields(fields) {
+		name := gelfFieldName(f.Key)
+		if name == "" || seen[name] {
+			continue
+		}
+		if seen == nil {
+			seen = make(map[string]bool, len(fields))
+		}
+		seen[name] = true
+		key, _ := json.Marshal(name)
+		value, _ := json.Marshal(f.Value)
+		data = append(data, ',')
+		data = append(data, key...)
+		data = append(data, ':')
+		data = append(data, value...)
+	}
+	return append(data, '}')
+}
+
+// gelfFieldName returns the name of the additional field for a field key, or "" if key is empty
+func gelfFieldName(key string) string {
+	if key == "" {
+		return ""
+	}
+	switch key {
+	case "id", "level_name", "prefix", "scope":
+		return "_" + key + "_"
+	}
+	name := []byte("_" + key)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '_' || c == '.' || c == '-') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+const (
+	gelfChunkHeaderSize = 12
+	gelfMaxChunks       = 128
+)
+
+func (g *GELFWriter) writeUDP(data []byte) error {
+	chunkSize := g.ChunkSize
+	if chunkSize <= gelfChunkHeaderSize {
+		chunkSize = 8192
+	}
+	if len(data) > chunkSize {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+	}
+	if len(data) <= chunkSize {
+		_, err := g.w.Write(data)
+		return err
+	}
+	// chunked message
+	payloadSize := chunkSize - gelfChunkHeaderSize
+	count := (len(data) + payloadSize - 1) / payloadSize
+	if count > gelfMaxChunks {
+		return errors.New("log: GELF message too large")
+	}
+	chunk := make([]byte, 0, chunkSize)
+	chunk = append(chunk, 0x1e, 0x0f)
+	var id [8]byte
+	rand.Read(id[:])
+	chunk = append(chunk, id[:]...)
+	for seq := 0; seq < count; seq++ {
+		payload := data[seq*payloadSize:]
+		if len(payload) > payloadSize {
+			payload = payload[:payloadSize]
+		}
+		chunk = append(chunk[:10], byte(seq), byte(count))
+		chunk = append(chunk, payload...)
+		if _, err := g.w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+// datagramWriter records each write as a separate datagram
+type datagramWriter struct {
+	datagrams [][]byte
+}
+
+func (w *datagramWriter) Write(p []byte) (int, error) {
+	w.datagrams = append(w.datagrams, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestGELF(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dw := &datagramWriter{}
+	g := NewGELFWriter(dw, true)
+	g.Host = "testhost"
+	l := NewLogger(g, "[srv]", LevelDebug, FDefault)
+	defer l.Close()
+
+	ctx := ContextWithFields(context.Background(), "disk", "sda1", "id", "1", "req id", "2")
+	l.WarnContext(ctx, "disk almost full")
+	l.Error("crashed\nstack trace")
+	l.Sync()
+
+	if !assert.Eq("datagrams", len(dw.datagrams), 2) {
+		return
+	}
+	var m map[string]interface{}
+	assert.NoErr("json", json.Unmarshal(dw.datagrams[0], &m))
+	assert.Eq("version", m["version"], "1.1")
+	assert.Eq("host", m["host"], "testhost")
+	assert.Eq("short_message", m["short_message"], "disk almost full")
+	assert.Eq("level", m["level"], float64(4))
+	assert.Eq("_prefix", m["_prefix"], "[srv]")
+	assert.Eq("field", m["_disk"], "sda1")
+	assert.Eq("reserved field name", m["_id_"], "1")
+	assert.Eq("invalid field name", m["_req_id"], "2")
+	assert.Ok("timestamp", m["timestamp"].(float64) > float64(time.Now().Unix()-60))
+	_, hasFull := m["full_message"]
+	assert.Ok("no full_message", !hasFull)
+
+	m = nil
+	assert.NoErr("json", json.Unmarshal(dw.datagrams[1], &m))
+	assert.Eq("level", m["level"], float64(3))
+	assert.Eq("short_message", m["short_message"], "crashed")
+	assert.Eq("full_message", m["full_message"], "crashed\nstack trace")
+}
+
+func TestGELFChunked(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dw := &datagramWriter{}
+	g := NewGELFWriter(dw, true)
+	g.ChunkSize = 100
+
+	// random-ish message which does not compress well
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		sb.WriteString(time.Duration(i * 7919).String())
+	}
+	msg := sb.String()
+	assert.NoErr("write", g.WriteRecord(&Record{Level: LevelInfo, Time: time.Now(), Msg: msg}))
+
+	assert.Ok("multiple chunks", len(dw.datagrams) > 1)
+	var data []byte
+	for i, chunk := range dw.datagrams {
+		assert.Ok("chunk size", len(chunk) <= 100)
+		assert.Eq("magic", chunk[:2], []byte{0x1e, 0x0f})
+		assert.Eq("message id", chunk[2:10], dw.datagrams[0][2:10])
+		assert.Eq("seq", int(chunk[10]), i)
+		assert.Eq("count", int(chunk[11]), len(dw.datagrams))
+		data = append(data, chunk[12:]...)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if !assert.NoErr("gzip", err) {
+		return
+	}
+	data, _ = ioutil.ReadAll(zr)
+	var m map[string]interface{}
+	assert.NoErr("json", json.Unmarshal(data, &m))
+	assert.Eq("short_message", m["short_message"], msg)
+}
+
+func TestGELFTCP(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf bytes.Buffer
+	g := NewGELFWriter(&buf, false)
+	l := NewLogger(g, "", LevelInfo, 0)
+	defer l.Close()
+	l.Info("one")
+	l.Info("two")
+	l.Sync()
+	msgs := bytes.Split(buf.Bytes(), []byte{0})
+	if assert.Eq("null-delimited messages", len(msgs), 3) {
+		assert.Ok("first", bytes.Contains(msgs[0], []byte(`"short_message":"one"`)))
+		assert.Ok("second", bytes.Contains(msgs[1], []byte(`"short_message":"two"`)))
+		assert.Eq("trailing", len(msgs[2]), 0)
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Group is a logger for a section of related output, like a step of a command-line tool.
+// Messages logged with a group are indented below the group's title, and End logs a summary
+// with the time taken and the number of errors and warnings logged in the group:
+//
+//   g := logger.Group("Loading plugins")
+//   for _, p := range plugins {
+//     if err := p.Load(); err != nil {
+//       g.Error("%s: %v", p.Name, err)
+//     } else {
+//       g.Info("%s", p.Name)
+//     }
+//   }
+//   g.End()
+//
+// Output:
+//
+//   [info] Loading plugins
+//   [info]   foo
+//   [error]   bar: file not found
+//   [info] Loading plugins done in 12ms (1 error)
+//
+// Groups can be nested. Messages of nested groups are counted by enclosing groups too.
+type Group struct {
+	*Logger
+	title    string
+	start    time.Time
+	depth    int    // nesting level; 1 for outermost groups
+	outer    *Group // enclosing group, if any
+	errors   int32  // read and written atomically
+	warnings int32  // read and written atomically
+	parent   *Logger
+}
+
+// Group logs title as an info message and returns a group for logging related messages.
+// See the Group type.
+func (l *Logger) Group(title string) *Group {
+	l.Info("%s", title)
+	g := &Group{title: title, start: l.now(), depth: 1, outer: l.group, parent: l}
+	if l.group != nil {
+		g.depth = l.group.depth + 1
+	}
+	g.Logger = l.SubLogger("")
+	g.Logger.group = g
+	return g
+}
+
+// Errors returns the number of errors logged in the group so far
+func (g *Group) Errors() int { return int(atomic.LoadInt32(&g.errors)) }
+
+// Warnings returns the number of warnings logged in the group so far
+func (g *Group) Warnings() int { return int(atomic.LoadInt32(&g.warnings)) }
+
+// End logs a summary of the group, like "Loading plugins done in 12ms (1 error, 2 warnings)",
+// as an info message with the logger the group was created from.
+func (g *Group) End() {
+	msg := g.title + " done in " + g.now().Sub(g.start).Round(time.Millisecond/10).String()
+	errors, warnings := g.Errors(), g.Warnings()
+	if errors > 0 || warnings > 0 {
+		msg += " ("
+		if errors > 0 {
+			msg += plural(errors, "error")
+		}
+		if warnings > 0 {
+			if errors > 0 {
+				msg += ", "
+			}
+			msg += plural(warnings, "warning")
+		}
+		msg += ")"
+	}
+	g.parent.Info("%s", msg)
+}
+
+// count counts a message of level logged in g or a nested group
+func (g *Group) count(level Level) {
+	for ; g != nil; g = g.outer {
+		switch level {
+		case LevelError:
+			atomic.AddInt32(&g.errors, 1)
+		case LevelWarn:
+			atomic.AddInt32(&g.warnings, 1)
+		}
+	}
+}
+
+func plural(n int, noun string) string {
+	s := strconv.Itoa(n) + " " + noun
+	if n != 1 {
+		s += "s"
+	}
+	return s
+}
@@ -0,0 +1,41 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestGroup(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	var feats Features = FPrefixInfo | FPrefixWarn | FPrefixError
+	l := NewLogger(w, "", LevelInfo, feats)
+	defer l.Close()
+
+	g := l.Group("Loading plugins")
+	g.Info("foo")
+	g2 := g.Group("bar")
+	g2.Warn("deprecated")
+	g2.Error("failed")
+	g2.End()
+	g.Error("baz failed")
+	g.End()
+	l.Info("after")
+	l.Sync()
+
+	assert.Eq("errors", g.Errors(), 2)
+	re := regexp.MustCompile(`in [0-9.]+[µm]?s`)
+	assert.Eq("output", re.ReplaceAllString(w.String(), "in T"), ""+
+		"[info] Loading plugins\n"+
+		"[info]   foo\n"+
+		"[info]   bar\n"+
+		"[warn]     deprecated\n"+
+		"[error]     failed\n"+
+		"[info]   bar done in T (1 error, 1 warning)\n"+
+		"[error]   baz failed\n"+
+		"[info] Loading plugins done in T (2 errors, 1 warning)\n"+
+		"[info] after\n")
+}
@@ -0,0 +1,110 @@
+// Package httplog logs HTTP requests handled by net/http servers and web frameworks to a
+// go-log Logger, one message per request:
+//
+//   GET /users/42 200 1.2KiB 3.1ms
+//
+// The values are also attached as fields (see Logger.InfoT), so that JSON output can be
+// searched by method, path, status, size and duration. Requests which failed with a 5xx status
+// are logged as errors and those with a 4xx status as warnings.
+//
+// Middleware works with net/http and any router which accepts net/http middleware, like chi:
+//
+//   r := chi.NewRouter()
+//   r.Use(httplog.Middleware(logger))
+//
+// and echo:
+//
+//   e.Use(echo.WrapMiddleware(httplog.Middleware(logger)))
+//
+// Frameworks with their own handler types call LogRequest, like this middleware for gin:
+//
+//   router.Use(func(c *gin.Context) {
+//     start := time.Now()
+//     c.Next()
+//     httplog.LogRequest(logger, c.Request, c.Writer.Status(), int64(c.Writer.Size()),
+//       time.Since(start))
+//   })
+//
+// This package does not depend on any web framework.
+package httplog
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rsms/go-log"
+)
+
+// template of request messages; see LogRequest
+const template = "{method} {path} {status} {size} {duration}"
+
+// LogRequest logs a request which was handled with the given response status and size in
+// bytes, taking d
+func LogRequest(l *log.Logger, r *http.Request, status int, size int64, d time.Duration) {
+	v := log.V{
+		"method":   r.Method,
+		"path":     r.URL.Path,
+		"status":   status,
+		"size":     log.Bytes(size),
+		"duration": log.Dur(d),
+	}
+	switch {
+	case status >= 500:
+		l.ErrorT(template, v)
+	case status >= 400:
+		l.WarnT(template, v)
+	default:
+		l.InfoT(template, v)
+	}
+}
+
+// Middleware returns net/http middleware which logs each request with LogRequest after it has
+// been handled
+func Middleware(l *log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			if sw.status == 0 {
+				sw.status = http.StatusOK
+			}
+			LogRequest(l, r, sw.status, sw.size, time.Since(start))
+		})
+	}
+}
+
+// statusWriter records the status and size of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher if the wrapped ResponseWriter does
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
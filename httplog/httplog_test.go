@@ -0,0 +1,39 @@
+package httplog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-log"
+	"github.com/rsms/go-testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf bytes.Buffer
+	l := log.NewLogger(&buf, "", log.LevelInfo, log.FPrefixWarn|log.FPrefixError)
+	h := Middleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte("hello"))
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	for _, path := range []string{"/ok", "/missing", "/fail"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	assert.NoErr("close", l.Close())
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Eq("lines", len(lines), 3)
+	if len(lines) == 3 {
+		assert.Ok("ok "+lines[0], strings.HasPrefix(lines[0], "GET /ok 200 5B "))
+		assert.Ok("not found "+lines[1], strings.HasPrefix(lines[1], "[warn] GET /missing 404 19B "))
+		assert.Ok("fail "+lines[2], strings.HasPrefix(lines[2], "[error] GET /fail 500 0B "))
+	}
+}
@@ -0,0 +1,63 @@
+package log
+
+// Interface is the subset of Logger's methods used by most code that logs. Packages which
+// accept an Interface rather than a *Logger can be given a *Logger, a *TestLogger, Discard or
+// a mock in tests:
+//
+//   type Server struct {
+//     log log.Interface
+//   }
+//
+//   srv := &Server{log: logger.Sub("[server]")}
+//
+// With and Sub return an Interface, which is what sets them apart from WithFields and
+// SubLogger; Go does not allow methods returning *Logger to satisfy an interface method
+// returning Interface.
+type Interface interface {
+	Error(format string, v ...interface{})
+	Warn(format string, v ...interface{})
+	Info(format string, v ...interface{})
+	Debug(format string, v ...interface{})
+	With(keyvals ...string) Interface // see Logger.WithFields
+	Sub(addPrefix string) Interface   // see Logger.SubLogger
+}
+
+var (
+	_ Interface = (*Logger)(nil)
+	_ Interface = (*TestLogger)(nil)
+)
+
+// WithFields returns a sub-logger which includes fields given as alternating keys and values
+// with every message it logs:
+//
+//   logger = logger.WithFields("conn", conn.ID(), "peer", conn.RemoteAddr().String())
+//   logger.Info("connected") // "[info] connected conn=4 peer=10.0.0.2:5123"
+//
+// Fields with keys already added by WithFields (or WithWorker) are replaced.
+func (l *Logger) WithFields(keyvals ...string) *Logger {
+	fields := make([]Field, 0, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields = append(fields, Field{keyvals[i], keyvals[i+1]})
+	}
+	return l.withFields(fields)
+}
+
+// With is like WithFields but returns an Interface
+func (l *Logger) With(keyvals ...string) Interface { return l.WithFields(keyvals...) }
+
+// Sub is like SubLogger but returns an Interface
+func (l *Logger) Sub(addPrefix string) Interface { return l.SubLogger(addPrefix) }
+
+// withFields returns a sub-logger with fields added to the fields of l, replacing fields of l
+// with the same keys
+func (l *Logger) withFields(fields []Field) *Logger {
+	l2 := l.SubLogger("")
+	l2.fields = make([]Field, 0, len(l.fields)+len(fields))
+	for _, f := range l.fields {
+		if !hasField(fields, f.Key) {
+			l2.fields = append(l2.fields, f)
+		}
+	}
+	l2.fields = append(l2.fields, fields...)
+	return l2
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestInterface(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+
+	var logger Interface = l
+	logger = logger.Sub("[a]").With("x", "1", "y", "2")
+	logger.Info("hello")
+	logger.With("x", "3").Warn("replaced")
+	logger.Debug("not logged")
+	l.Info("plain")
+	l.Sync()
+	assert.Eq("output", w.String(),
+		"[a] hello x=1 y=2\n[a] replaced y=2 x=3\nplain\n")
+
+	tl := NewTestLogger(t)
+	logger = tl
+	logger.With("k", "v").Info("from test logger")
+	assert.Ok("test logger", tl.Contains(LevelInfo, "from test logger"))
+
+	logger = Discard
+	logger.Sub("[b]").Info("discarded")
+}
@@ -0,0 +1,156 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KlogWriter returns a writer which logs the messages of klog (k8s.io/klog), as used by the
+// Kubernetes client libraries, to l, so that they are formatted and written like messages
+// logged with l. The klog header (severity, time, thread and source location) is removed and
+// the severity selects the level: info, warning, or error for error and fatal messages.
+//
+// klog writes each message to the output of its severity and of all lower severities; set its
+// one_output flag so that messages are written once:
+//
+//   fs := flag.NewFlagSet("klog", flag.ExitOnError)
+//   klog.InitFlags(fs)
+//   fs.Set("logtostderr", "false")
+//   fs.Set("one_output", "true")
+//   klog.SetOutput(log.KlogWriter(logger.SubLogger("[k8s]")))
+//
+// See LogrSink for the opposite direction, klog.SetLogger.
+func KlogWriter(l *Logger) io.Writer {
+	return klogWriter{l}
+}
+
+// klogWriter receives messages from klog. Each call to Write is one message.
+type klogWriter struct {
+	l *Logger
+}
+
+func (w klogWriter) Write(p []byte) (int, error) {
+	level, msg := parseKlogLine(strings.TrimSuffix(string(p), "\n"))
+	w.l.Log(level, "%s", msg)
+	return len(p), nil
+}
+
+// parseKlogLine returns the level and message of a line in the klog format, e.g.
+// "W0102 15:04:05.123456   12345 file.go:123] msg". A line in another format is returned as
+// an info message.
+func parseKlogLine(line string) (Level, string) {
+	if len(line) < 6 || strings.Trim(line[1:5], "0123456789") != "" || line[5] != ' ' {
+		return LevelInfo, line
+	}
+	end := strings.Index(line, "] ")
+	if end == -1 {
+		return LevelInfo, line
+	}
+	switch line[0] {
+	case 'I':
+		return LevelInfo, line[end+2:]
+	case 'W':
+		return LevelWarn, line[end+2:]
+	case 'E', 'F':
+		return LevelError, line[end+2:]
+	}
+	return LevelInfo, line
+}
+
+// LogrSink logs to a Logger through the methods of logr.LogSink (github.com/go-logr/logr),
+// the interface which klog.SetLogger and controller-runtime log to. Verbosity 0 is logged as
+// info and higher verbosity as debug. Key/value pairs become fields, formatted with fmt.Sprint,
+// and names given to WithName are joined with "/" in the field "logger".
+//
+// Since this package does not depend on logr, LogrSink lacks Init and returns *LogrSink rather
+// than logr.LogSink from WithValues and WithName. Wrap it to implement logr.LogSink:
+//
+//   type logrSink struct{ *log.LogrSink }
+//
+//   func (s logrSink) Init(logr.RuntimeInfo) {}
+//   func (s logrSink) WithValues(kv ...interface{}) logr.LogSink {
+//     return logrSink{s.LogrSink.WithValues(kv...)}
+//   }
+//   func (s logrSink) WithName(name string) logr.LogSink {
+//     return logrSink{s.LogrSink.WithName(name)}
+//   }
+//
+//   klog.SetLogger(logr.New(logrSink{log.NewLogrSink(logger)}))
+//
+type LogrSink struct {
+	l    *Logger
+	name string
+}
+
+// NewLogrSink creates a LogrSink logging to l
+func NewLogrSink(l *Logger) *LogrSink {
+	return &LogrSink{l: l}
+}
+
+// logrLevel returns the level of messages of logr verbosity v
+func logrLevel(v int) Level {
+	if v > 0 {
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+// Enabled returns true if messages of verbosity v are logged
+func (s *LogrSink) Enabled(v int) bool {
+	return s.l.GetLevel() <= logrLevel(v)
+}
+
+// Info logs msg with verbosity v
+func (s *LogrSink) Info(v int, msg string, keysAndValues ...interface{}) {
+	level := logrLevel(v)
+	if s.l.GetLevel() <= level {
+		s.with(logrFields(keysAndValues)).Log(level, "%s", msg)
+	}
+}
+
+// Error logs msg as an error, with err in the field "error"
+func (s *LogrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if s.l.GetLevel() <= LevelError {
+		fields := logrFields(keysAndValues)
+		if err != nil {
+			fields = append(fields, Field{"error", err.Error()})
+		}
+		s.with(fields).Log(LevelError, "%s", msg)
+	}
+}
+
+// WithValues returns a LogrSink which adds keysAndValues to all messages
+func (s *LogrSink) WithValues(keysAndValues ...interface{}) *LogrSink {
+	return &LogrSink{l: s.l.withFields(logrFields(keysAndValues)), name: s.name}
+}
+
+// WithName returns a LogrSink which adds name to the name of s
+func (s *LogrSink) WithName(name string) *LogrSink {
+	if s.name != "" {
+		name = s.name + "/" + name
+	}
+	return &LogrSink{l: s.l.withFields([]Field{{"logger", name}}), name: name}
+}
+
+// with returns s.l with fields added
+func (s *LogrSink) with(fields []Field) *Logger {
+	if len(fields) == 0 {
+		return s.l
+	}
+	return s.l.withFields(fields)
+}
+
+// logrFields converts logr key/value pairs to fields. A key without a value gets the value
+// "(MISSING)", like arguments missing in fmt.
+func logrFields(keysAndValues []interface{}) []Field {
+	fields := make([]Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		f := Field{Key: fmt.Sprint(keysAndValues[i]), Value: "(MISSING)"}
+		if i+1 < len(keysAndValues) {
+			f.Value = fmt.Sprint(keysAndValues[i+1])
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestKlogWriter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "[k8s]", LevelInfo, FPrefixWarn|FPrefixError)
+	w := KlogWriter(l)
+	fmt.Fprint(w, "I0102 15:04:05.123456   12345 reflector.go:221] Starting reflector\n")
+	fmt.Fprint(w, "W0102 15:04:05.123456   12345 warnings.go:70] deprecated API\n")
+	fmt.Fprint(w, "E0102 15:04:05.123456   12345 leaderelection.go:330] lost lease\n")
+	fmt.Fprint(w, "not klog\n")
+	assert.NoErr("close", l.Close())
+	assert.Eq("output", buf.String(), "[k8s] Starting reflector\n"+
+		"[warn] [k8s] deprecated API\n"+
+		"[error] [k8s] lost lease\n"+
+		"[k8s] not klog\n")
+}
+
+func TestLogrSink(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "", LevelInfo, FPrefixDebug|FPrefixError)
+	s := NewLogrSink(l)
+	assert.Ok("info enabled", s.Enabled(0))
+	assert.Ok("debug disabled", !s.Enabled(1))
+	s.Info(1, "hidden")
+	s.WithName("ctrl").WithName("pods").Info(0, "reconciled", "pod", "web-1", "n", 3)
+	s.WithValues("ns", "default").Error(errors.New("EOF"), "watch failed", "odd")
+	assert.NoErr("close", l.Close())
+	assert.Eq("output", buf.String(),
+		"reconciled logger=ctrl/pods pod=web-1 n=3\n"+
+			"[error] watch failed ns=default odd=(MISSING) error=EOF\n")
+}
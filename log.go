@@ -1,19 +1,26 @@
 package log
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 // Level defines the log level
-type Level int
+type Level int32
 
 const (
 	LevelDebug Level = iota
@@ -26,11 +33,68 @@ const (
 	levelTime
 
 	// internal control messages between the logger and its writeLoop
-	ctlSync // synchronize
+	ctlSync       // synchronize (ctlarg is nil or a chan error; see queue.sync)
+	ctlBuffer     // configure buffering (ctlarg is a bufferConfig)
+	ctlDuplicates // configure duplicate suppression (ctlarg is a time.Duration)
+	ctlProgress   // update the status line (ctlarg is a progressUpdate)
+	ctlWriter     // change the writer of the record's logger (ctlarg is a writerSwap)
+	ctlBatch      // write records contiguously (ctlarg is a []*logRecord; see Logger.Batch)
+	ctlSkip       // placeholder for a record which was not sent (see queue.skip)
 )
 
+// LevelInherit makes a sub-logger use the current level of the logger it was created from,
+// rather than the level that logger had when the sub-logger was created. It is the initial
+// level of named loggers (see GetLogger). See SetLevel.
+const LevelInherit Level = -1
+
+func (level Level) String() string {
+	if level == LevelInherit {
+		return "inherit"
+	}
+	if level >= 0 && int(level) < len(levelNames) {
+		return levelNames[level]
+	}
+	return fmt.Sprintf("Level(%d)", int(level))
+}
+
+// ParseLevel returns the level with the given name, e.g. "warn". Names are case-insensitive.
+// "inherit" is LevelInherit, which allows configuration to reset named loggers (see GetLogger)
+// to following the level of their parent.
+func ParseLevel(name string) (Level, error) {
+	if strings.EqualFold(name, "inherit") {
+		return LevelInherit, nil
+	}
+	for i, s := range levelNames[:LevelDisable+1] {
+		if strings.EqualFold(name, s) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+func (level Level) MarshalText() ([]byte, error) {
+	return []byte(level.String()), nil
+}
+
+func (level *Level) UnmarshalText(text []byte) error {
+	l, err := ParseLevel(string(text))
+	if err == nil {
+		*level = l
+	}
+	return err
+}
+
+// featureLevel returns the level whose prefix and sync feature bits apply to records of level.
+// Time records are reported at LevelInfo and thus follow its features.
+func (level Level) featureLevel() Level {
+	if level == levelTime {
+		return LevelInfo
+	}
+	return level
+}
+
 // behavior
-type Features int
+type Features int32
 
 const (
 	FDate         Features = 1 << iota // include date (format YYYY-MM-DD)
@@ -40,7 +104,35 @@ const (
 	FUTC                               // date & time in UTC rather than local time zone
 	FDebugOrigin                       // include source file at end of debug messages
 	FColor                             // enable ANSI terminal colors
-	FColorAuto                         // enable FColor if w is TTY & env TERM supports colors
+	FColorAuto                         // enable FColor if w is a TTY which supports colors
+
+	FIndent       Features = 1 << 14 // indent continuation lines of multi-line messages
+	FIndentMarker Features = 1 << 15 // with FIndent, mark continuation lines with "| "
+
+	// FTruncate and FWrap make lines fit the width of the terminal, when w is a terminal.
+	// Output to files and other writers is never truncated or wrapped.
+	FTruncate Features = 1 << 20 // truncate lines wider than the terminal with "…"
+	FWrap     Features = 1 << 21 // wrap lines wider than the terminal, with hanging indentation
+
+	// FRFC3339 formats timestamps as RFC3339 (ISO 8601) with timezone offset, e.g.
+	// "2006-01-02T15:04:05-07:00", including fractional seconds with FMilliseconds or
+	// FMicroseconds. FDate and FTime are implied. See also Logger.SetTimeFormat
+	FRFC3339 Features = 1 << 22
+
+	// FElapsed replaces the date and time with the time elapsed since the process started,
+	// e.g. "+12.345s" (or "+12.345678s" with FMicroseconds.) Times before the process started,
+	// e.g. from a clock set with SetClock, are negative, like "-1.500s".
+	FElapsed Features = 1 << 23
+
+	// FUnixTime replaces the date and time with the number of seconds since the Unix epoch,
+	// e.g. "1136214245", for logs consumed by programs. FUnixMillis is FUnixTime with
+	// FMilliseconds, which yields milliseconds since the epoch, e.g. "1136214245000".
+	FUnixTime   Features = 1 << 28
+	FUnixMillis          = FUnixTime | FMilliseconds
+
+	// FSymbols replaces level prefixes like "[warn]" with compact symbols like "⚠" when w is a
+	// terminal. Output to files and other writers uses the text prefixes.
+	FSymbols Features = 1 << 29
 
 	fPrefixStart   = 0xff
 	fPrefixBitOffs = 8
@@ -61,21 +153,74 @@ const (
 	FSync    = FSyncDebug | FSyncInfo | FSyncWarn | FSyncError
 	FDefault = FTime | FDebugOrigin | FColorAuto |
 		FPrefixDebug | FPrefixInfo | FPrefixWarn | FPrefixError
+
+	fFsyncStart   = 0xffffff
+	fFsyncBitOffs = 24
+
+	// FFsync* flush the writer to stable storage (i.e. call File.Sync) after writing messages of
+	// the corresponding level, so that they survive a power loss. Combine with FSync* to also
+	// make the logging call wait for this to happen. Ignored for writers without a Sync method.
+	FFsyncDebug = 1 << (fFsyncBitOffs + LevelDebug) // sync to disk after writing debug messages
+	FFsyncInfo  = 1 << (fFsyncBitOffs + LevelInfo)  // sync to disk after writing info messages
+	FFsyncWarn  = 1 << (fFsyncBitOffs + LevelWarn)  // sync to disk after writing warning messages
+	FFsyncError = 1 << (fFsyncBitOffs + LevelError) // sync to disk after writing error messages
+
+	FFsync = FFsyncDebug | FFsyncInfo | FFsyncWarn | FFsyncError
 )
 
+// ParseFeatures returns the features named in s, separated by commas, spaces or "|".
+// Names are those of the F* constants without the "F" prefix and are case-insensitive,
+// e.g. "Time,PrefixWarn,PrefixError". In addition, "Prefix" enables all level prefixes.
+func ParseFeatures(s string) (Features, error) {
+	var feats Features
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '|' || r == ' '
+	}) {
+		f, ok := featureNames[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown log feature %q", name)
+		}
+		feats |= f
+	}
+	return feats, nil
+}
+
+func (feats *Features) UnmarshalText(text []byte) error {
+	f, err := ParseFeatures(string(text))
+	if err == nil {
+		*feats = f
+	}
+	return err
+}
+
 type Logger struct {
-	Level
-	Features
-	Prefix string
+	Level    // use GetLevel and SetLevel when other goroutines may be logging
+	Features // use GetFeatures, SetFeatures etc. when other goroutines may be logging
+	Prefix   string
 
-	parent *Logger // non-nil for sub-loggers
-	w      io.Writer
-	qch    chan *logRecord // may be shared by multiple loggers
-	syncch chan error
+	parent  *Logger      // non-nil for sub-loggers
+	name    string       // non-empty for named loggers (see GetLogger)
+	w       atomic.Value // writerRef; see Writer and SetWriter
+	q       *queue       // shared with sub-loggers
+	metrics *metrics     // shared with sub-loggers
+	stats   *metrics     // record counts of a named logger and its sub-loggers; see Stats
+	scope   []string     // immutable; replaced (never modified) by WithScope
+	group   *Group       // innermost group; see Logger.Group
+	fields  []Field      // immutable; included with all records. See WithWorker
+	static  []Field      // immutable; included with structured records. See SetStaticFields
+
+	timeFormat   string           // custom timestamp layout; see SetTimeFormat
+	timeRender   TimeRenderer     // custom timestamp renderer; see SetTimeRenderer
+	clock        func() time.Time // time source; nil for time.Now. See SetClock
+	formatter    Formatter        // replaces the text format when non-nil; see Options
+	originFormat OriginFormat     // see SetOriginFormat
 }
 
 var RootLogger = NewLogger(os.Stdout, "", LevelInfo, FDefault)
 
+// ErrClosed is the error reported for messages logged after a logger has been closed
+var ErrClosed = errors.New("log: logger closed")
+
 func Error(format string, v ...interface{})       { RootLogger.Error(format, v...) }
 func Warn(format string, v ...interface{})        { RootLogger.Warn(format, v...) }
 func Info(format string, v ...interface{})        { RootLogger.Info(format, v...) }
@@ -85,90 +230,352 @@ func Printf(format string, v ...interface{})      { RootLogger.Info(format, v...
 func SubLogger(extraPrefix string) *Logger        { return RootLogger.SubLogger(extraPrefix) }
 func Sync()                                       { RootLogger.Sync() }
 
-// NewLogger makes a new logger that is writing to w
+// NewLogger makes a new logger that is writing to w. See New for more options.
 func NewLogger(w io.Writer, prefix string, level Level, feats Features) *Logger {
-	if feats&FColorAuto != 0 {
-		feats = featuresWithAutoColor(w, feats)
-	}
-	// feats = feats &^ FColor // XXX
-	l := &Logger{
-		Level:    level,
-		Features: feats,
-		Prefix:   prefix,
-		w:        w,
-		qch:      make(chan *logRecord, 100),
-		syncch:   make(chan error),
-	}
-	go l.writeLoop()
-	return l
+	return New(Options{Writer: w, Prefix: prefix, Level: level, Features: feats})
 }
 
 func (l *Logger) SubLogger(addPrefix string) *Logger {
-	l2 := *l // shallow copy
+	l2 := l.clone()
 	l2.Prefix = l2.Prefix + addPrefix
 	l2.parent = l
-	return &l2
+	l2.name = ""
+	return l2
 }
 
-func (l *Logger) Close() {
-	if l.parent == nil {
-		l.Sync()
-		close(l.qch)
-	} else {
-		// sub logger
-		l.Level = LevelDisable
-		l.qch = nil
+// clone returns a shallow copy of l. Fields which may be changed concurrently (level,
+// features and writer) are read atomically, which a plain copy (*l) would not do.
+func (l *Logger) clone() *Logger {
+	l2 := &Logger{
+		Level:        Level(atomic.LoadInt32((*int32)(&l.Level))),       // may be LevelInherit
+		Features:     Features(atomic.LoadInt32((*int32)(&l.Features))), // may be featuresInherit
+		Prefix:       l.Prefix,
+		parent:       l.parent,
+		name:         l.name,
+		q:            l.q,
+		metrics:      l.metrics,
+		stats:        l.stats,
+		scope:        l.scope,
+		group:        l.group,
+		fields:       l.fields,
+		static:       l.static,
+		timeFormat:   l.timeFormat,
+		timeRender:   l.timeRender,
+		clock:        l.clock,
+		formatter:    l.formatter,
+		originFormat: l.originFormat,
+	}
+	l2.w.Store(l.w.Load())
+	return l2
+}
+
+// WithScope pushes a scope segment which is included with all messages logged by l until the
+// returned function is called. Nested scopes are rendered as "cmd>subcmd>step".
+// Sub-loggers inherit the scope of their parent at the time they are created.
+//
+// WithScope modifies l and is meant for sequential code like CLI commands. Use a SubLogger
+// to scope messages logged from other goroutines.
+//
+// Example:
+//
+//   func build() {
+//     defer log.RootLogger.WithScope("build")()
+//     log.Info("compiling") // "[info] build compiling"
+//     compile()
+//   }
+//   func compile() {
+//     defer log.RootLogger.WithScope("compile")()
+//     log.Info("main.go") // "[info] build>compile main.go"
+//   }
+//
+func (l *Logger) WithScope(name string) func() {
+	prev := l.scope
+	// copy rather than append since records in the queue may reference prev
+	scope := make([]string, len(prev)+1)
+	copy(scope, prev)
+	scope[len(prev)] = name
+	l.scope = scope
+	return func() { l.scope = prev }
+}
+
+// Scope returns the current scope segments of l, outermost first.
+// The returned slice must not be modified.
+func (l *Logger) Scope() []string {
+	return l.scope
+}
+
+// Close writes all queued messages, stops the logger and closes its writer if it implements
+// io.Closer (except for os.Stdout and os.Stderr.) Messages logged after Close are discarded.
+// Returns the last write error, or the error from closing the writer.
+//
+// Closing a sub-logger disables it and waits for its messages to be written. The parent logger
+// and other sub-loggers are not affected. A child logger with its own writer (see Child) is
+// closed like a logger created with NewLogger.
+//
+// When called by the logger's writer or OnError function, Close returns nil right away and
+// the logger is closed once the write in progress has returned.
+func (l *Logger) Close() error {
+	if l.parent != nil && l.q == l.parent.q {
+		l.SetLevel(LevelDisable)
+		return l.Sync()
+	}
+	if l.q.writing.containsCurrent() {
+		// called while writing, e.g. by OnError; the writeLoop can't exit until we return
+		go l.Close()
+		return nil
+	}
+	if !l.q.close() {
+		return nil // already closed
 	}
+	openQueues.remove(l.q)
+	err := l.q.err
+	if l.q.wal != nil {
+		if e := l.q.wal.close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	w := l.Writer()
+	if c, ok := w.(io.Closer); ok && w != os.Stdout && w != os.Stderr {
+		if e := c.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
 }
 
 // Sync returns when all messages have been written.
 // If the process exits after a Sync call all messages up to that point are guaranteed to be
 // written, assuming the OS kernel doesn't terminate (i.e. from power failure.)
+// Sync is safe to call from several goroutines at once. See also SyncContext
+//
+// When called by the logger's writer or OnError function, Sync returns nil right away since
+// waiting for the write in progress to finish would never return.
 func (l *Logger) Sync() error {
-	m := logRecordFree.Get().(*logRecord)
-	m.level = ctlSync
-	l.qch <- m
-	return <-l.syncch
+	return l.q.sync(nil)
+}
+
+// GetLevel returns the level of l. Unlike reading the Level field directly, this is safe to
+// call while another goroutine calls SetLevel.
+func (l *Logger) GetLevel() Level {
+	level := Level(atomic.LoadInt32((*int32)(&l.Level)))
+	if level == LevelInherit {
+		if l.parent == nil {
+			return LevelInfo
+		}
+		return l.parent.GetLevel()
+	}
+	return level
+}
+
+// SetLevel changes the level of l. Unlike assigning the Level field directly, this is safe to
+// call while other goroutines are logging.
+//
+// Sub-loggers start out with the level of their parent at the time they are created. With
+// LevelInherit, l instead follows the level of its parent as it changes, until SetLevel is called
+// with another level. Sub-loggers of l created while it inherits its level inherit as well.
+// A logger without parent, like one created with New, has LevelInfo when set to inherit.
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32((*int32)(&l.Level), int32(level))
+}
+
+// featuresInherit is the Features of a logger which uses the current features of its parent,
+// like LevelInherit; see GetLogger
+const featuresInherit Features = -1
+
+// GetFeatures returns the features of l. Unlike reading the Features field directly, this is
+// safe to call while another goroutine changes the features.
+func (l *Logger) GetFeatures() Features {
+	feats := Features(atomic.LoadInt32((*int32)(&l.Features)))
+	if feats == featuresInherit && l.parent != nil {
+		return l.parent.GetFeatures()
+	}
+	return feats
+}
+
+// SetFeatures replaces the features of l. If FColorAuto is enabled, FColor is evaluated for
+// the writer. Unlike assigning the Features field directly, this is safe to call while other
+// goroutines are logging. Messages already logged may be formatted with either set of features.
+func (l *Logger) SetFeatures(feats Features) {
+	if feats&FColorAuto != 0 {
+		feats = featuresWithAutoColor(l.Writer(), feats&^FColor)
+	}
+	atomic.StoreInt32((*int32)(&l.Features), int32(feats))
+}
+
+// updateFeatures atomically replaces the features of l with f(features)
+func (l *Logger) updateFeatures(f func(Features) Features) {
+	for {
+		prev := Features(atomic.LoadInt32((*int32)(&l.Features)))
+		feats := prev
+		if feats == featuresInherit && l.parent != nil {
+			feats = l.parent.GetFeatures()
+		}
+		if atomic.CompareAndSwapInt32((*int32)(&l.Features), int32(prev), int32(f(feats))) {
+			return
+		}
+	}
 }
 
 func (l *Logger) EnableFeatures(enableFeats Features) {
-	if enableFeats&FColorAuto != 0 && l.Features&FColor == 0 {
+	l.updateFeatures(func(feats Features) Features { return feats | enableFeats })
+	if enableFeats&FColorAuto != 0 {
 		// maybe turn on FColor
-		enableFeats = featuresWithAutoColor(l.w, enableFeats)
+		l.RefreshAutoFeatures()
 	}
-	l.Features |= enableFeats
 }
 
 func (l *Logger) DisableFeatures(disableFeats Features) {
-	if disableFeats&FColorAuto != 0 && l.Features&FColorAuto == 0 {
-		// turn off FColor if FColorAuto is enabled
-		disableFeats |= FColor
+	l.updateFeatures(func(feats Features) Features {
+		if disableFeats&FColorAuto != 0 && feats&FColorAuto != 0 {
+			// turn off FColor if FColorAuto is enabled
+			return feats &^ (disableFeats | FColor)
+		}
+		return feats &^ disableFeats
+	})
+}
+
+// SetTimeFormat sets a custom layout for timestamps, as accepted by time.Time.Format.
+// When set, it replaces the timestamp otherwise produced by FDate, FTime, FMilliseconds,
+// FMicroseconds and FRFC3339, and timestamps are included even when none of these features are
+// enabled. FUTC still applies. An empty layout restores the default behavior.
+//
+// For example, to log timestamps like "Jan  2 15:04:05.000":
+//
+//   logger.SetTimeFormat(time.StampMilli)
+//
+// Sub-loggers created after the call inherit the layout.
+func (l *Logger) SetTimeFormat(layout string) {
+	l.timeFormat = layout
+}
+
+// TimeRenderer appends a rendering of t to buf and returns the extended buffer, like
+// time.Time.AppendFormat. See Logger.SetTimeRenderer
+type TimeRenderer func(buf []byte, t time.Time) []byte
+
+// SetTimeRenderer sets a function which renders timestamps. Like SetTimeFormat, it replaces
+// the timestamp otherwise produced by FDate, FTime, FMilliseconds, FMicroseconds and FRFC3339,
+// and takes precedence over SetTimeFormat. This allows timestamps which time.Time.Format can
+// not produce, like localized month names:
+//
+//   months := [...]string{"janv.", "févr.", "mars", "avr.", "mai", "juin",
+//     "juil.", "août", "sept.", "oct.", "nov.", "déc."}
+//   logger.SetTimeRenderer(func(buf []byte, t time.Time) []byte {
+//     buf = strconv.AppendInt(buf, int64(t.Day()), 10)
+//     buf = append(buf, ' ')
+//     buf = append(buf, months[t.Month()-1]...)
+//     return t.AppendFormat(buf, " 15:04:05")
+//   })
+//
+// FUTC still applies. Sub-loggers created after the call inherit the renderer.
+// Passing nil restores the default behavior.
+func (l *Logger) SetTimeRenderer(render TimeRenderer) {
+	l.timeRender = render
+}
+
+// SetClock sets the function used to read the current time, which is time.Now by default.
+// This is mainly useful in tests, to produce deterministic timestamps:
+//
+//   t := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+//   logger.SetClock(func() time.Time { return t })
+//
+// Durations measured by Time and Group are also based on the clock.
+// Sub-loggers created after the call inherit the clock. Passing nil restores time.Now.
+func (l *Logger) SetClock(now func() time.Time) {
+	l.clock = now
+}
+
+// now returns the current time according to l's clock
+func (l *Logger) now() time.Time {
+	if l.clock != nil {
+		return l.clock()
+	}
+	return time.Now()
+}
+
+// writerRef wraps the writer of a logger, since atomic.Value requires values of the same type
+type writerRef struct {
+	io.Writer
+	tty     bool // Writer is a terminal; see FSymbols
+	inherit bool // use the current writer of the logger's parent; see GetLogger
+}
+
+func newWriterRef(w io.Writer) writerRef {
+	return writerRef{Writer: w, tty: isTerminal(w)}
+}
+
+// writer returns the writerRef of l, or of its parent if l inherits its writer
+func (l *Logger) writer() writerRef {
+	r, _ := l.w.Load().(writerRef) // zero for loggers not created with NewLogger
+	if r.inherit && l.parent != nil {
+		return l.parent.writer()
 	}
-	l.Features = l.Features &^ disableFeats
+	return r
 }
 
 func (l *Logger) Writer() io.Writer {
-	return l.w
+	return l.writer().Writer
 }
 
+// SetWriter changes the writer of l. If FColorAuto is enabled, FColor is re-evaluated for w.
+//
+// Messages logged before the call are written to the previous writer, and messages logged
+// after the call are written to w. SetWriter waits for messages in the queue to be written,
+// so when it returns the previous writer is no longer used by l and can be closed, e.g. when
+// rotating log files. It is safe to call while other goroutines are logging, but must not be
+// called from a LogCB callback.
 func (l *Logger) SetWriter(w io.Writer) {
-	l.w = w
+	swap := writerSwap{w, make(chan struct{})}
+	m := logRecordFree.Get().(*logRecord)
+	m.logger = l
+	m.level = ctlWriter
+	m.ctlarg = swap
+	if l.q.send(m) {
+		<-swap.done
+	} else {
+		m.free()
+		l.w.Store(newWriterRef(w)) // closed
+	}
+	l.RefreshAutoFeatures()
+}
+
+// writerSwap is a request to the writeLoop to change the writer of a logger
+type writerSwap struct {
+	w    io.Writer
+	done chan struct{} // closed when the writer has been changed
+}
+
+// RefreshAutoFeatures re-evaluates features that depend on the writer and environment.
+// Currently this means FColor, which is enabled or disabled according to the writer when
+// FColorAuto is enabled. This is done automatically by NewLogger and SetWriter; call
+// RefreshAutoFeatures when something else changed, like the TERM environment variable.
+func (l *Logger) RefreshAutoFeatures() {
+	r, _ := l.w.Load().(writerRef)
+	if r.inherit && Features(atomic.LoadInt32((*int32)(&l.Features))) == featuresInherit {
+		return // l uses the features of its parent, which were evaluated for the same writer
+	}
+	w := l.Writer()
+	l.updateFeatures(func(feats Features) Features {
+		if feats&FColorAuto != 0 {
+			feats = featuresWithAutoColor(w, feats&^FColor)
+		}
+		return feats
+	})
 }
 
 func (l *Logger) Error(format string, v ...interface{}) {
-	if l.Level <= LevelError {
+	if l.GetLevel() <= LevelError {
 		l.log(LevelError, format, v...)
 	}
 }
 
 func (l *Logger) Warn(format string, v ...interface{}) {
-	if l.Level <= LevelWarn {
+	if l.GetLevel() <= LevelWarn {
 		l.log(LevelWarn, format, v...)
 	}
 }
 
 func (l *Logger) Info(format string, v ...interface{}) {
-	if l.Level <= LevelInfo {
+	if l.GetLevel() <= LevelInfo {
 		l.log(LevelInfo, format, v...)
 	}
 }
@@ -178,31 +585,19 @@ func (l *Logger) Debug(format string, v ...interface{}) {
 }
 
 func (l *Logger) LogDebug(calldepth int, format string, v ...interface{}) {
-	if l.Level <= LevelDebug {
-		if l.Features&FDebugOrigin != 0 {
-			var file string
-			var line int
-			var ok bool
-			_, file, line, ok = runtime.Caller(calldepth + 1)
-			if !ok {
-				file = "???"
-				line = 0
-			} else {
-				// simplify /path/to/dir/file.go -> dir/file.go
-				file = simplifySrcFilename(file)
-			}
-			if l.Features&FColor != 0 {
-				format = format + " \x1b[90m(%s:%d)\x1b[39m"
-			} else {
-				format = format + " (%s:%d)"
-			}
-			v = append(v, file, line)
+	if l.GetLevel() <= LevelDebug {
+		m := l.newRecord(LevelDebug)
+		if !m.deferf(format, v) {
+			m.appendf(format, v...)
+		}
+		if l.GetFeatures()&FDebugOrigin != 0 {
+			m.appendOrigin(calldepth + 1)
 		}
-		l.log(LevelDebug, format, v...)
+		l.submit(m)
 	}
 }
 
-var initTime = time.Now()
+var initTime = time.Now() // roughly the time the process started; see Time and FElapsed
 
 // Time starts a time measurement, logged when the returned function is invoked. Uses LevelInfo.
 // Call the returned function to measure time taken since the call to l.Time and log a message.
@@ -218,28 +613,60 @@ var initTime = time.Now()
 //   "[time] foo with thing 123: 6.597116ms"
 //
 func (l *Logger) Time(format string, v ...interface{}) func() {
-	if l.Level > LevelInfo {
+	if l.GetLevel() > LevelInfo {
 		return func() {}
 	}
 	// Note: Windows uses a low-res timer for time.Now (Oct 2020)
 	// See https://go-review.googlesource.com/c/go/+/227499/
-	start := time.Since(initTime)
+	start := l.now()
 	msg := fmt.Sprintf(format, v...) // must evaluate asap in case v contains pointers
 	return func() {
 		format := "%s: %s"
 		if len(msg) == 0 {
 			format = "%s%s"
 		}
-		l.log(levelTime, format, msg, time.Since(initTime)-start)
+		l.log(levelTime, format, msg, l.now().Sub(start))
 	}
 }
 
+// Enabled returns true if records of level are logged by l.
+// Useful for avoiding expensive work that is only needed for a log message.
+func (l *Logger) Enabled(level Level) bool {
+	return l.GetLevel() <= level
+}
+
 func (l *Logger) Log(level Level, format string, v ...interface{}) {
-	if l.Level <= level {
+	if l.GetLevel() <= level {
 		l.log(level, format, v...)
 	}
 }
 
+// LogCB is like Log but calls cb when the message has been written, with the error from
+// writing it, if any. This allows waiting for a critical message to be written without the
+// cost of a Sync, which waits for all queued messages:
+//
+//   done := make(chan error, 1)
+//   logger.LogCB(log.LevelError, func(err error) { done <- err }, "payment %s failed", id)
+//   ...
+//   if err := <-done; err != nil {
+//     // record was not written
+//   }
+//
+// cb is called on the writeLoop goroutine (or the logging goroutine for messages logged
+// synchronously; see FSync) and must not block or log with the same logger. If the message
+// is not logged because level is disabled or because of a filter (see SetFilter), cb is called
+// immediately with a nil error. If the logger is closed, cb is called with ErrClosed.
+func (l *Logger) LogCB(level Level, cb func(error), format string, v ...interface{}) {
+	if l.GetLevel() > level {
+		cb(nil)
+		return
+	}
+	m := l.newRecord(level)
+	m.done = cb
+	m.appendf(format, v...)
+	l.submit(m)
+}
+
 // alternate method spelling for compatibility with e.g. badger
 func (l *Logger) Errorf(format string, v ...interface{})   { l.Error(format, v...) }
 func (l *Logger) Warningf(format string, v ...interface{}) { l.Warn(format, v...) }
@@ -255,7 +682,7 @@ func (l *Logger) Debugf(format string, v ...interface{})   { l.Debug(format, v..
 // the receiver has an effect on the Go logger.
 //
 // Example:
-//   logger.Level = log.LevelWarn
+//   logger.SetLevel(log.LevelWarn)
 //   goLoggerInfo := logger.GoLogger(log.LevelInfo)
 //   goLoggerWarn := logger.GoLogger(log.LevelWarn)
 //   goLoggerInfo.Printf("Hello")  // (nothing is printed)
@@ -263,36 +690,161 @@ func (l *Logger) Debugf(format string, v ...interface{})   { l.Debug(format, v..
 //
 func (l *Logger) GoLogger(forLevel Level) *log.Logger {
 	var flag int
-	if l.Features&FDate != 0 {
+	feats := l.GetFeatures()
+	if feats&FDate != 0 {
 		flag |= log.Ldate
 	}
-	if l.Features&FTime != 0 {
+	if feats&FTime != 0 {
 		flag |= log.Ltime
 	}
-	if l.Features&(FMilliseconds|FMicroseconds) != 0 {
+	if feats&(FMilliseconds|FMicroseconds) != 0 {
 		flag |= log.Lmicroseconds
 	}
-	if l.Features&FUTC != 0 {
+	if feats&FUTC != 0 {
 		flag |= log.LUTC
 	}
-	if l.Features&FDebugOrigin != 0 {
+	if feats&FDebugOrigin != 0 {
 		flag |= log.Lshortfile
 	}
-	w := l.w
-	if forLevel < l.Level {
+	w := l.Writer()
+	if forLevel < l.GetLevel() {
 		w = ioutil.Discard
 	}
 	return log.New(w, l.Prefix, flag)
 }
 
+// Record describes a logged message
+type Record struct {
+	Level  Level     `json:"level"`
+	Time   time.Time `json:"time"`
+	Prefix string    `json:"prefix,omitempty"` // prefix of the logger
+	Scope  []string  `json:"scope,omitempty"`  // see Logger.WithScope
+	Msg    string    `json:"msg"`              // message (without header)
+
+	Fields map[string]string `json:"fields,omitempty"` // see PushScope and ContextWithFields
+
+	// trace context (hex-encoded) of messages logged with a context; see LogContext
+	TraceID string `json:"trace_id,omitempty"`
+	SpanID  string `json:"span_id,omitempty"`
+
+	// source location of the logging call, like "dir/file.go:123"; set for debug messages when
+	// FDebugOrigin is enabled
+	Caller string `json:"caller,omitempty"`
+
+	// message template of messages logged with InfoT etc, for grouping messages by template
+	Template string `json:"template,omitempty"`
+}
+
 // ——————————————————————————————————————————————————————————————————————————————————————————————
 // package internal
 
+// queue connects loggers with the writeLoop. It is shared by a logger and its sub-loggers.
+type queue struct {
+	ch     chan *logRecord
+	shards *shardSet     // nil unless sharded; see Options.Shards
+	done   chan struct{} // closed when writeLoop has exited
+	err    error         // last write error; valid once done is closed
+
+	mu     sync.RWMutex // held for reading while sending on ch and for writing when closing ch
+	closed bool
+
+	maxAge int64 // time.Duration; see Logger.SetMaxRecordAge. Accessed atomically
+
+	expect    expectations // see Logger.Expect
+	reporter  atomic.Value // *errorReporter; see Logger.ReportErrors
+	redactors atomic.Value // []Redactor; see Logger.AddRedactor
+	filter    atomic.Value // func(Level, string) bool; see Logger.SetFilter
+	rate      rateLimits   // see Logger.LogOnce and LogEvery
+	onError   func(error)  // see Options.OnError; immutable
+	ordered   int32        // 1 if sync writes go through writeLoop; see Logger.SetOrdered
+	writing   goroutineSet // writeLoop and goroutines writing synchronously; see enqueue
+	wal       *wal         // see Options.WAL; immutable
+	priority  int          // see Options.PriorityWindow; immutable
+	held      int32        // number of records held by writeLoop; see priorityQueue
+	ids       int32        // 1 if records get unique IDs; see Logger.SetRecordIDs
+}
+
+// newQueue creates a queue of size records. If shards > 1, the queue is sharded.
+func newQueue(size, shards int) *queue {
+	q := &queue{
+		ch:   make(chan *logRecord, size),
+		done: make(chan struct{}),
+	}
+	if shards > 1 {
+		q.shards = newShardSet(q.ch, shards)
+	}
+	return q
+}
+
+// len returns the number of queued records
+func (q *queue) len() int {
+	n := len(q.ch)
+	if q.shards != nil {
+		for _, ch := range q.shards.chs[1:] {
+			n += len(ch)
+		}
+	}
+	return n + int(atomic.LoadInt32(&q.held))
+}
+
+// send queues m for writing. Returns false if the queue is closed.
+func (q *queue) send(m *logRecord) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	q.input(m) <- m
+	return true
+}
+
+// close closes the queue and waits for the writeLoop to write all queued records and exit.
+// Returns false if the queue was already closed.
+func (q *queue) close() bool {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		<-q.done
+		return false
+	}
+	q.closed = true
+	if q.shards != nil {
+		for _, ch := range q.shards.chs {
+			close(ch)
+		}
+	} else {
+		close(q.ch)
+	}
+	q.mu.Unlock()
+	<-q.done
+	return true
+}
+
 type logRecord struct {
 	logger *Logger
 	level  Level
 	time   time.Time
+	scope  []string // immutable; see Logger.WithScope
+	trace  TraceContext
+	fields []Field     // immutable; see PushScope and ContextWithFields
+	static []Field     // immutable; see Logger.SetStaticFields
+	done   func(error) // called when the record has been written; see LogCB
 	msg    []byte
+	origin []byte      // source location; see appendOrigin
+	ctlarg interface{} // argument of control messages
+	raw    bool        // msg is written without header and fields; see Logger.Raw
+	walSeq uint64      // sequence number in the queue's WAL, or 0; see Options.WAL
+	seq    uint64      // sequence number in a sharded queue; see queue.input
+	code   string      // error code; see Logger.ErrorC
+	id     string      // unique ID; see Logger.ErrorID
+
+	template string // see Logger.InfoT
+	props    int    // number of fields at the end of fields with values from msg; see InfoT
+
+	argfmt string        // format of deferred args; see deferf
+	args   []interface{} // arguments to be formatted in writeLoop; see deferf
+
+	ctx context.Context // queueing is abandoned when ctx is done; see LogContext
 }
 
 // free list (note: go's fmt package uses this so it is definitely "fast enough")
@@ -308,75 +860,558 @@ func (m *logRecord) free() {
 	//   contains a variably-sized buffer, we add a hard limit on the maximum buffer
 	//   to place back in the pool.
 	//   See https://golang.org/issue/23199
-	if cap(m.msg) > 4<<10 {
+	if cap(m.msg) > 4<<10 || cap(m.origin) > 4<<10 {
 		return
 	}
 	m.logger = nil
+	m.scope = nil
+	m.trace = TraceContext{}
+	m.fields = nil
+	m.static = nil
+	m.done = nil
+	m.ctlarg = nil
+	m.raw = false
+	m.walSeq = 0
+	m.seq = 0
+	m.code = ""
+	m.id = ""
+	m.ctx = nil
+	m.template = ""
+	m.props = 0
+	m.clearArgs()
 	m.msg = m.msg[:0]
+	m.origin = m.origin[:0]
 	logRecordFree.Put(m)
 }
 
-func (m *logRecord) write(buf *[]byte) error {
-	m.logger.formatHeader(buf, m.time, m.level)
-	*buf = append(*buf, m.msg...)
-	if len(m.msg) == 0 || m.msg[len(m.msg)-1] != '\n' {
-		*buf = append(*buf, '\n')
+// appendOrigin records the source location of the caller in m.origin. It is written after
+// the message, like "(dir/file.go:123)".
+// calldepth is the number of stack frames to skip, relative to the caller of appendOrigin.
+func (m *logRecord) appendOrigin(calldepth int) {
+	if m.logger.originFormat == OriginFunc {
+		m.appendFuncOrigin(calldepth + 1)
+		return
+	}
+	_, file, line, ok := runtime.Caller(calldepth + 1)
+	if !ok {
+		file = "???"
+		line = 0
+	} else if m.logger.originFormat == OriginModule {
+		file = moduleSrcFilename(file)
+	} else {
+		// simplify /path/to/dir/file.go -> dir/file.go
+		file = simplifySrcFilename(file)
+	}
+	m.origin = append(m.origin[:0], file...)
+	m.origin = append(m.origin, ':')
+	itoa(&m.origin, line, -1)
+}
+
+// appendf appends format formatted with v to m.msg.
+// Must format now rather than in m.write since v may contain pointers (see deferf.)
+//
+// An error wrapped with %w is formatted like %v and added as the field "error", so that
+// structured output can be searched by cause. appendf only forwards v to fmt.Errorf (fprintf
+// takes a slice) which makes go vet check calls to the printf-like functions of this package
+// like calls to fmt.Errorf, allowing %w.
+func (m *logRecord) appendf(format string, v ...interface{}) {
+	if len(v) == 0 {
+		m.msg = append(m.msg, format...)
+	} else if hasWrapVerb(format) {
+		err := fmt.Errorf(format, v...)
+		m.msg = append(m.msg, err.Error()...)
+		if cause := errors.Unwrap(err); cause != nil {
+			m.fields = append(m.fields[:len(m.fields):len(m.fields)], Field{"error", cause.Error()})
+			m.props = 1
+		}
+	} else {
+		m.fprintf(format, v)
+	}
+}
+
+func (m *logRecord) fprintf(format string, v []interface{}) {
+	fmt.Fprintf(m, format, v...)
+}
+
+// hasWrapVerb returns true if format contains the verb %w
+func hasWrapVerb(format string) bool {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		for i++; i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) != -1; i++ {
+		}
+		if i < len(format) && format[i] == 'w' {
+			return true
+		}
+	}
+	return false
+}
+
+// Write appends p to m.msg, allowing fmt to format directly into the record
+func (m *logRecord) Write(p []byte) (int, error) {
+	m.msg = append(m.msg, p...)
+	return len(p), nil
+}
+
+// format appends the complete, newline-terminated log line of m to buf
+func (m *logRecord) format(buf *[]byte) {
+	if f := m.logger.formatter; f != nil {
+		r := m.record()
+		f(buf, &r)
+		return
+	}
+	m.formatWith(buf, m.logger)
+}
+
+// formatFeatures is like format but uses feats instead of the features of m's logger
+func (m *logRecord) formatFeatures(buf *[]byte, feats Features) {
+	l := m.logger.clone()
+	l.Features = feats
+	m.formatWith(buf, l)
+}
+
+// formatWith is like format but formats according to l rather than m's logger
+func (m *logRecord) formatWith(buf *[]byte, l *Logger) {
+	if m.raw {
+		*buf = append(*buf, m.msg...)
+		if len(m.msg) == 0 || m.msg[len(m.msg)-1] != '\n' {
+			*buf = append(*buf, '\n')
+		}
+		return
+	}
+	feats := l.GetFeatures()
+	start := len(*buf)
+	l.formatHeader(buf, m)
+	hdrw := visibleWidth((*buf)[start:])
+	msg := m.msg
+	if len(msg) > 0 && msg[len(msg)-1] == '\n' {
+		msg = msg[:len(msg)-1]
+	}
+	if feats&FIndent != 0 && bytes.IndexByte(msg, '\n') != -1 {
+		appendIndented(buf, msg, hdrw, feats&FIndentMarker != 0)
+	} else {
+		*buf = append(*buf, msg...)
+	}
+	if len(m.origin) > 0 {
+		if feats&FColor != 0 {
+			*buf = append(*buf, ' ')
+			*buf = append(*buf, currentTheme().origin...)
+			*buf = append(*buf, '(')
+		} else {
+			*buf = append(*buf, " ("...)
+		}
+		*buf = append(*buf, m.origin...)
+		*buf = append(*buf, ')')
+		if feats&FColor != 0 {
+			*buf = append(*buf, colorFgReset...)
+		}
+	}
+	if fields := m.fields[:len(m.fields)-m.props]; len(fields) > 0 {
+		// template values are part of the message and thus not repeated as fields
+		appendFields(buf, fields, feats&FColor != 0)
+	}
+	if len(m.id) > 0 {
+		appendFields(buf, []Field{{"id", m.id}}, feats&FColor != 0)
+	}
+	if feats&(FTruncate|FWrap) != 0 {
+		if width := termWidth(l.Writer()); width > 0 {
+			fitWidth(buf, start, width, hdrw, feats&FWrap != 0)
+		}
+	}
+	*buf = append(*buf, '\n')
+}
+
+func (m *logRecord) write() error {
+	l := m.logger
+	fsync := l.fsyncs(m.level)
+	var n int
+	var err error
+	if rw, ok := l.Writer().(recordWriter); ok {
+		err = rw.writeRecord(m)
+	} else {
+		b := getBuffer()
+		m.format(&b.B)
+		n, err = writeBuffer(l.Writer(), b)
+	}
+	if fsync && err == nil {
+		err = syncWriter(l.Writer())
+	}
+	l.wrote(n, err)
+	if m.done != nil {
+		m.done(err)
 	}
-	_, err := m.logger.w.Write(*buf)
 	m.free()
 	return err
 }
 
+// record returns a Record describing m
+func (m *logRecord) record() Record {
+	r := Record{
+		Level:  m.level,
+		Time:   m.time,
+		Prefix: m.logger.Prefix,
+		Scope:  m.scope,
+		Msg:    string(m.msg),
+		Fields: fieldMapOf(m.structuredFields()),
+		Caller: string(m.origin),
+
+		Template: m.template,
+	}
+	if m.trace.IsValid() {
+		r.TraceID = hex.EncodeToString(m.trace.TraceID[:])
+		r.SpanID = hex.EncodeToString(m.trace.SpanID[:])
+	}
+	return r
+}
+
+// recordWriter is implemented by writers which receive records rather than formatted output
+type recordWriter interface {
+	writeRecord(m *logRecord) error
+}
+
 func (l *Logger) log(level Level, format string, v ...interface{}) {
+	m := l.newRecord(level)
+	if !m.deferf(format, v) {
+		m.appendf(format, v...)
+	}
+	l.submit(m)
+}
+
+// newRecord returns a record from the free list, initialized for logging by l
+func (l *Logger) newRecord(level Level) *logRecord {
 	m := logRecordFree.Get().(*logRecord)
 	m.logger = l
 	m.level = level
-	m.time = time.Now()
-	// must format now rather than in m.write since v may contain pointers
-	if len(v) == 0 {
-		m.msg = append(m.msg, format...)
-	} else {
-		s := fmt.Sprintf(format, v...)
-		m.msg = append(m.msg, s...)
+	m.time = l.now()
+	m.scope = l.scope
+	m.static = l.static
+	m.fields = goroutineFields.current()
+	if len(l.fields) > 0 {
+		if len(m.fields) == 0 {
+			m.fields = l.fields
+		} else {
+			m.fields = append(append(make([]Field, 0, len(l.fields)+len(m.fields)), l.fields...),
+				m.fields...)
+		}
 	}
-	if Features(1<<(fSyncBitOffs+level))&l.Features != 0 {
-		var bufa [256]byte
-		buf := bufa[:]
-		m.write(&buf)
-	} else {
-		l.qch <- m
+	if atomic.LoadInt32(&l.q.ids) != 0 {
+		m.id = newRecordID()
 	}
+	return m
 }
 
-func featuresWithAutoColor(w io.Writer, feats Features) Features {
-	// enable FColor if w is a TTY and env $TERM seems to support color
-	if f, ok := w.(*os.File); ok {
-		if st, _ := f.Stat(); (st.Mode() & os.ModeCharDevice) != 0 {
-			TERM := os.Getenv("TERM")
-			if strings.Contains(TERM, "xterm") ||
-				strings.Contains(TERM, "vt100") ||
-				strings.Contains(TERM, "color") {
-				feats |= FColor
+// submit either writes m immediately (if sync is enabled for its level) or queues it for writing.
+// m is discarded if the logger is closed or if m is rejected by the filter (see SetFilter).
+func (l *Logger) submit(m *logRecord) {
+	q := l.q
+	if m.deferred() && !l.defers(m) {
+		m.render()
+	}
+	if q.filtered(m) {
+		if m.done != nil {
+			m.done(nil)
+		}
+		m.free()
+		return
+	}
+	if !m.deferred() {
+		q.redact(m) // else in writeLoop, after formatting
+	}
+	if m.level == LevelError {
+		q.report(m) // before locking q.mu since the reporter might log
+	}
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		l.metrics.drop()
+		if m.done != nil {
+			m.done(ErrClosed)
+		}
+		m.free()
+		return
+	}
+	l.count(m)
+	if !l.writesSync(m.level) {
+		var walErr error
+		if q.wal != nil {
+			walErr = q.wal.append(m)
+		}
+		q.enqueue(m)
+		q.mu.RUnlock()
+		q.writeFailed(walErr)
+		return
+	}
+	if q.writing.containsCurrent() {
+		// logged while writing, e.g. by the writer; waiting or writing would deadlock
+		q.mu.RUnlock()
+		q.writeReentrant(m)
+		return
+	}
+	if atomic.LoadInt32(&q.ordered) != 0 {
+		// write in writeLoop, after any records queued before m, and wait for it
+		written := make(chan struct{})
+		done := m.done
+		m.done = func(err error) {
+			if done != nil {
+				done(err)
 			}
+			close(written)
 		}
+		q.input(m) <- m
+		q.mu.RUnlock()
+		<-written
+		return
+	}
+	id := goroutineID()
+	q.writing.add(id)
+	err := m.write()
+	q.mu.RUnlock()
+	q.writeFailed(err) // after unlocking, since onError may log or close the logger
+	q.writing.remove(id)
+}
+
+// count updates counters and expectations for m, which is about to be queued
+func (l *Logger) count(m *logRecord) {
+	l.metrics.logged(m.level)
+	if l.stats != nil {
+		l.stats.logged(m.level)
+	}
+	if l.group != nil {
+		l.group.count(m.level)
+	}
+	if l.q.expect.active() {
+		l.q.expect.observe(m)
+	}
+}
+
+// writesSync returns true if messages of level are written synchronously; see FSync
+func (l *Logger) writesSync(level Level) bool {
+	return Features(1<<(fSyncBitOffs+level.featureLevel()))&l.GetFeatures() != 0
+}
+
+// SetOrdered controls whether messages which are written synchronously (see FSync) wait for
+// messages queued before them. By default a synchronous message is written right away by the
+// goroutine that logs it, so with e.g. FSyncError an error can appear in the output before info
+// messages which were logged earlier but are still queued. When ordered, synchronous messages are
+// instead written by the logger's write goroutine and the logging call returns once the message
+// has been written, so the output is always in the order messages were logged. This costs two
+// goroutine switches per synchronous message.
+//
+// The setting applies to l, its parent and sub-loggers, which share a queue.
+func (l *Logger) SetOrdered(ordered bool) {
+	var v int32
+	if ordered {
+		v = 1
+	}
+	atomic.StoreInt32(&l.q.ordered, v)
+}
+
+// fsyncs returns true if w should be synced to disk after writing a message of level
+func (l *Logger) fsyncs(level Level) bool {
+	return Features(1<<(fFsyncBitOffs+level.featureLevel()))&l.GetFeatures() != 0
+}
+
+// wrote records the outcome of writing n bytes of output
+func (l *Logger) wrote(n int, err error) {
+	l.metrics.wrote(n, err)
+}
+
+// writeFailed calls the OnError function of q, if any, if err is not nil. It must not be called
+// with q.mu held, since the function may log or close the logger.
+func (q *queue) writeFailed(err error) {
+	if err != nil && q.onError != nil {
+		q.onError(err)
+	}
+}
+
+// syncWriter flushes w to stable storage if w has a Sync method, like *os.File.
+// Files which do not support syncing, like terminals and pipes, are ignored.
+func syncWriter(w io.Writer) error {
+	s, ok := w.(interface{ Sync() error })
+	if !ok {
+		return nil
+	}
+	if err := s.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
+}
+
+func featuresWithAutoColor(w io.Writer, feats Features) Features {
+	// enable FColor if w is a TTY which seems to support color
+	if _, ok := w.(*os.File); ok && TerminalInfo(w).Colors != ColorDepthNone {
+		feats |= FColor
+	} else if r, ok := w.(*LevelRouter); ok && r.supportsColor() {
+		feats |= FColor
 	}
 	return feats
 }
 
-// writeLoop
+// writeLoop writes queued records until the queue is closed
 func (l *Logger) writeLoop() {
-	var buf []byte
 	var err error
+	var b recordBuffer       // used when buffering is enabled (see WithBuffer)
+	var d duplicateFilter    // used when duplicate suppression is enabled (see SuppressDuplicates)
+	var st statusLine        // used when progress is shown (see Progress)
+	var stale staleFilter    // used when stale records are dropped (see SetMaxRecordAge)
+	var walBuffered []uint64 // WAL sequence numbers of records in b (see Options.WAL)
+	var pq priorityQueue     // used when reordering (see Options.PriorityWindow)
+	pq.window = l.q.priority
+	defer b.stop()
+	defer d.stop()
+	id := goroutineID()
+	l.q.writing.add(id) // messages logged by the writer must not be queued; see enqueue
+	defer l.q.writing.remove(id)
+	flush := func() {
+		if e := b.flush(l); e != nil {
+			err = e
+			l.q.writeFailed(e)
+		}
+		if len(walBuffered) > 0 {
+			l.q.writeFailed(l.q.wal.written(walBuffered...))
+			walBuffered = walBuffered[:0]
+		}
+	}
+	write := func(m *logRecord) {
+		if m == nil {
+			return
+		}
+		overStatus := st.covers(m.logger.Writer())
+		if overStatus {
+			st.clear() // write message above the status line
+		}
+		walSeq := m.walSeq
+		if b.size > 0 {
+			if walSeq != 0 {
+				walBuffered = append(walBuffered, walSeq)
+			}
+			if e := b.add(l, m); e != nil {
+				err = e
+				l.q.writeFailed(e)
+			}
+			if overStatus {
+				flush()
+			}
+		} else {
+			err = m.write()
+			l.q.writeFailed(err)
+			if walSeq != 0 {
+				l.q.writeFailed(l.q.wal.written(walSeq))
+			}
+		}
+		if overStatus {
+			st.draw()
+		}
+	}
+	record := func(m *logRecord) {
+		if stale.drop(l, m) {
+			write(stale.report(l, l.q.len() == 0))
+			return
+		}
+		write(stale.report(l, false))
+		if m.deferred() {
+			m.render()
+			l.q.redact(m)
+		}
+		if walSeq := m.walSeq; d.suppress(m) {
+			if walSeq != 0 {
+				l.q.writeFailed(l.q.wal.written(walSeq)) // counted by the summary of repetitions
+			}
+		} else {
+			write(d.flush(false))
+			d.remember(m)
+			write(m)
+		}
+	}
 	for {
-		m, more := <-l.qch
-		if m.level == ctlSync {
-			l.syncch <- err // return last write error
+		var m *logRecord
+		var ok bool
+		if pq.held() {
+			m, ok = pq.next(l.q, nil)
 		} else {
-			buf = buf[:0] // reset buffer
-			err = m.write(&buf)
+			var ch <-chan *logRecord
+			if m, ch = l.q.next(); m != nil {
+				ok = true
+			} else {
+				var tick, timer bool
+				select {
+				case m, ok = <-ch:
+				case <-b.tickch:
+					tick = true
+				case <-d.timerch:
+					timer = true
+				default:
+					// idle; leave q.writing while waiting (see goroutineSet.containsCurrent)
+					l.q.writing.remove(id)
+					select {
+					case m, ok = <-ch:
+					case <-b.tickch:
+						tick = true
+					case <-d.timerch:
+						timer = true
+					}
+					l.q.writing.add(id)
+				}
+				if tick {
+					flush()
+					continue
+				}
+				if timer {
+					write(d.flush(true))
+					continue
+				}
+				if m, ok = l.q.accept(m, ok); m == nil && ok {
+					continue // received before its turn
+				}
+			}
+			if ok && pq.window > 0 {
+				m, ok = pq.next(l.q, m)
+			}
 		}
-		if !more {
-			break
+		if !ok {
+			write(d.flush(false))
+			write(stale.report(l, true))
+			st.clear()
+			flush()
+			l.q.err = err
+			close(l.q.done)
+			return
+		}
+		switch m.level {
+		case ctlSync:
+			write(d.flush(false))
+			write(stale.report(l, true))
+			flush()
+			m.ctlarg.(chan error) <- err // return last write error; see queue.sync
+			m.free()
+		case ctlBuffer:
+			flush()
+			b.configure(m.ctlarg.(bufferConfig))
+			m.free()
+		case ctlProgress:
+			flush()
+			st.update(m.ctlarg.(progressUpdate))
+			m.free()
+		case ctlWriter:
+			write(d.flush(false))
+			flush()
+			swap := m.ctlarg.(writerSwap)
+			st.swapWriter(m.logger.Writer(), swap.w)
+			m.logger.w.Store(newWriterRef(swap.w))
+			close(swap.done)
+			m.free()
+		case ctlDuplicates:
+			write(d.flush(true))
+			d.configure(m.ctlarg.(time.Duration))
+			m.free()
+		case ctlBatch:
+			for _, m := range m.ctlarg.([]*logRecord) {
+				record(m)
+			}
+			m.free()
+		default:
+			record(m)
 		}
 	}
 }
@@ -385,57 +1420,225 @@ func (l *Logger) writeLoop() {
 //   - date and/or time (if corresponding flags are provided)
 //   - levelPrefix[level]
 //   - prefix
+//   - scope
 // Adapted from go/src/log/log.go
-func (l *Logger) formatHeader(buf *[]byte, t time.Time, level Level) {
-	if l.Features&(FDate|FTime|FMilliseconds|FMicroseconds) != 0 {
-		if l.Features&FColor != 0 {
-			*buf = append(*buf, colorFgGrey...)
+func (l *Logger) formatHeader(buf *[]byte, m *logRecord) {
+	feats := l.GetFeatures()
+	t, level := m.time, m.level
+	if feats&(FDate|FTime|FMilliseconds|FMicroseconds|FRFC3339|FElapsed|FUnixTime) != 0 ||
+		l.timeFormat != "" || l.timeRender != nil {
+		if feats&FColor != 0 {
+			*buf = append(*buf, currentTheme().timestamp...)
 		}
-		if l.Features&FUTC != 0 {
+		if feats&FUTC != 0 {
 			t = t.UTC()
 		}
-		if l.Features&FDate != 0 {
-			year, month, day := t.Date()
-			itoa(buf, year, 4)
-			*buf = append(*buf, '-')
-			itoa(buf, int(month), 2)
-			*buf = append(*buf, '-')
-			itoa(buf, day, 2)
-			*buf = append(*buf, ' ')
-		}
-		if l.Features&(FTime|FMilliseconds|FMicroseconds) != 0 {
-			hour, min, sec := t.Clock()
-			itoa(buf, hour, 2)
-			*buf = append(*buf, ':')
-			itoa(buf, min, 2)
-			*buf = append(*buf, ':')
-			itoa(buf, sec, 2)
-			if l.Features&(FMilliseconds|FMicroseconds) != 0 {
-				*buf = append(*buf, '.')
-				ns := t.Nanosecond()
-				if l.Features&FMicroseconds != 0 {
-					itoa(buf, ns/1e3, 6)
-				} else {
-					itoa(buf, ns/1e6, 3)
-				}
+		if feats&FElapsed != 0 {
+			l.appendElapsed(buf, t)
+		} else if feats&FUnixTime != 0 {
+			if feats&FMilliseconds != 0 {
+				*buf = strconv.AppendInt(*buf, t.UnixNano()/int64(time.Millisecond), 10)
+			} else {
+				*buf = strconv.AppendInt(*buf, t.Unix(), 10)
 			}
 			*buf = append(*buf, ' ')
+		} else if l.timeRender != nil {
+			*buf = l.timeRender(*buf, t)
+			*buf = append(*buf, ' ')
+		} else if layout := l.timeLayout(); layout != "" {
+			*buf = t.AppendFormat(*buf, layout)
+			*buf = append(*buf, ' ')
+		} else {
+			l.appendDateTime(buf, t)
 		}
-		if l.Features&FColor != 0 {
+		if feats&FColor != 0 {
 			*buf = append(*buf, colorFgReset...)
 		}
 	}
-	if Features(1<<(fPrefixBitOffs+level))&l.Features != 0 {
-		if l.Features&FColor != 0 {
-			*buf = append(*buf, levelPrefixColor[level]...)
-		} else {
-			*buf = append(*buf, levelPrefixPlain[level]...)
+	if Features(1<<(fPrefixBitOffs+level.featureLevel()))&feats != 0 {
+		ct := currentTheme()
+		symbols := false
+		if feats&FSymbols != 0 {
+			symbols = l.writer().tty
+		}
+		switch {
+		case symbols && feats&FColor != 0:
+			*buf = append(*buf, ct.levelSymbol[level]...)
+		case symbols:
+			*buf = append(*buf, levelSymbols[level]...)
+			*buf = append(*buf, ' ')
+		case feats&FColor != 0:
+			*buf = append(*buf, ct.levelPrefix[level]...)
+		default:
+			*buf = append(*buf, ct.levelPlain[level]...)
 		}
 	}
 	if len(l.Prefix) > 0 {
 		*buf = append(*buf, l.Prefix...)
 		*buf = append(*buf, ' ')
 	}
+	if l.group != nil {
+		for i := 0; i < l.group.depth; i++ {
+			*buf = append(*buf, "  "...)
+		}
+	}
+	if len(m.scope) > 0 {
+		for i, name := range m.scope {
+			if i > 0 {
+				*buf = append(*buf, '>')
+			}
+			*buf = append(*buf, name...)
+		}
+		*buf = append(*buf, ' ')
+	}
+	if len(m.code) > 0 {
+		*buf = append(*buf, m.code...)
+		*buf = append(*buf, ' ')
+	}
+}
+
+// timeLayout returns the layout used for timestamps, or "" for the FDate/FTime format
+func (l *Logger) timeLayout() string {
+	feats := l.GetFeatures()
+	if l.timeFormat != "" {
+		return l.timeFormat
+	}
+	if feats&FRFC3339 != 0 {
+		switch {
+		case feats&FMicroseconds != 0:
+			return "2006-01-02T15:04:05.000000Z07:00"
+		case feats&FMilliseconds != 0:
+			return "2006-01-02T15:04:05.000Z07:00"
+		}
+		return time.RFC3339
+	}
+	return ""
+}
+
+// appendElapsed appends the time elapsed from initTime to t to buf, e.g. "+12.345s "
+func (l *Logger) appendElapsed(buf *[]byte, t time.Time) {
+	feats := l.GetFeatures()
+	d := t.Sub(initTime)
+	if d < 0 {
+		*buf = append(*buf, '-')
+		d = -d
+	} else {
+		*buf = append(*buf, '+')
+	}
+	itoa(buf, int(d/time.Second), -1)
+	*buf = append(*buf, '.')
+	ns := int(d % time.Second)
+	if feats&FMicroseconds != 0 {
+		itoa(buf, ns/1e3, 6)
+	} else {
+		itoa(buf, ns/1e6, 3)
+	}
+	*buf = append(*buf, "s "...)
+}
+
+// appendDateTime appends the date and/or time of t to buf, according to FDate, FTime,
+// FMilliseconds and FMicroseconds
+func (l *Logger) appendDateTime(buf *[]byte, t time.Time) {
+	feats := l.GetFeatures()
+	date := feats&FDate != 0
+	clock := feats&(FTime|FMilliseconds|FMicroseconds) != 0
+	if date || clock {
+		*buf = append(*buf, dateTimeFragment(t, date, clock)...)
+	}
+	if clock {
+		if feats&(FMilliseconds|FMicroseconds) != 0 {
+			*buf = append(*buf, '.')
+			ns := t.Nanosecond()
+			if feats&FMicroseconds != 0 {
+				itoa(buf, ns/1e3, 6)
+			} else {
+				itoa(buf, ns/1e6, 3)
+			}
+		}
+		*buf = append(*buf, ' ')
+	}
+}
+
+// dateTimeCache holds the most recently formatted second (a *dateTime).
+// Records are mostly logged in bursts within the same second, so this saves calculating the
+// calendar date and clock of t for most records.
+var dateTimeCache atomic.Value
+
+type dateTime struct {
+	sec int64
+	loc *time.Location
+	b   []byte // "2006-01-02 15:04:05"
+}
+
+// dateTimeFragment returns "2006-01-02 ", "15:04:05" or "2006-01-02 15:04:05" for t
+func dateTimeFragment(t time.Time, date, clock bool) []byte {
+	sec, loc := t.Unix(), t.Location()
+	dt, _ := dateTimeCache.Load().(*dateTime)
+	if dt == nil || dt.sec != sec || dt.loc != loc {
+		dt = &dateTime{sec: sec, loc: loc, b: make([]byte, 0, 19)}
+		year, month, day := t.Date()
+		itoa(&dt.b, year, 4)
+		dt.b = append(dt.b, '-')
+		itoa(&dt.b, int(month), 2)
+		dt.b = append(dt.b, '-')
+		itoa(&dt.b, day, 2)
+		dt.b = append(dt.b, ' ')
+		hour, min, sec := t.Clock()
+		itoa(&dt.b, hour, 2)
+		dt.b = append(dt.b, ':')
+		itoa(&dt.b, min, 2)
+		dt.b = append(dt.b, ':')
+		itoa(&dt.b, sec, 2)
+		dateTimeCache.Store(dt)
+	}
+	switch {
+	case !clock:
+		return dt.b[:11]
+	case !date:
+		return dt.b[11:]
+	}
+	return dt.b
+}
+
+// appendIndented appends msg to buf with continuation lines indented by width columns.
+// If marker is true, "| " is placed at the end of the indentation.
+func appendIndented(buf *[]byte, msg []byte, width int, marker bool) {
+	for {
+		i := bytes.IndexByte(msg, '\n')
+		if i == -1 {
+			*buf = append(*buf, msg...)
+			return
+		}
+		*buf = append(*buf, msg[:i+1]...)
+		msg = msg[i+1:]
+		pad := width
+		if marker {
+			pad -= 2
+		}
+		for ; pad > 0; pad-- {
+			*buf = append(*buf, ' ')
+		}
+		if marker {
+			*buf = append(*buf, "| "...)
+		}
+	}
+}
+
+// visibleWidth returns the number of terminal columns of b, excluding ANSI escape sequences
+func visibleWidth(b []byte) int {
+	n := 0
+	for i := 0; i < len(b); i++ {
+		if b[i] == 0x1b && i+1 < len(b) && b[i+1] == '[' {
+			// skip CSI sequence, terminated by a byte in the range 0x40–0x7e
+			for i += 2; i < len(b) && (b[i] < 0x40 || b[i] > 0x7e); i++ {
+			}
+			continue
+		}
+		if b[i]&0xc0 != 0x80 { // not a UTF-8 continuation byte
+			n++
+		}
+	}
+	return n
 }
 
 // Cheap integer to fixed-width decimal ASCII. Give a negative width to avoid zero-padding.
@@ -456,15 +1659,26 @@ func itoa(buf *[]byte, i int, wid int) {
 	*buf = append(*buf, b[bp:]...)
 }
 
+// workingDir is the working directory of the process when the first origin was recorded
+var workingDir struct {
+	sync.Once
+	dir string
+}
+
 func simplifySrcFilename(file string) string {
-	if wd, err := os.Getwd(); err == nil {
+	workingDir.Do(func() { workingDir.dir, _ = os.Getwd() })
+	if wd := workingDir.dir; wd != "" {
 		if len(file) > len(wd) && file[len(wd)] == os.PathSeparator && strings.HasPrefix(file, wd) {
 			// rooted in working directory
 			return file[len(wd)+1:]
 		}
 	}
-	// return a short version that contains the first parent dir + basename.
-	// i.e. /foo/bar/baz.go -> bar/baz.go, /baz.go -> baz.go
+	return shortSrcFilename(file)
+}
+
+// shortSrcFilename returns a short version of file that contains the first parent dir +
+// basename, i.e. /foo/bar/baz.go -> bar/baz.go, /baz.go -> baz.go
+func shortSrcFilename(file string) string {
 	short := file
 	for i := len(file) - 1; i > 0; i-- {
 		if file[i] == '/' {
@@ -486,12 +1700,64 @@ func simplifySrcFilename(file string) string {
 // ——————————————————————————————————————————————————————————————————————————————————————————————
 // data
 
-const (
-	colorFgGrey  = "\x1b[90m"
-	colorFgReset = "\x1b[39m"
-)
+const colorFgReset = "\x1b[39m"
 
 var (
+	levelNames = [6]string{
+		"debug",
+		"info",
+		"warn",
+		"error",
+		"disable",
+		"time",
+	}
+
+	featureNames = map[string]Features{
+		"date":         FDate,
+		"time":         FTime,
+		"milliseconds": FMilliseconds,
+		"microseconds": FMicroseconds,
+		"utc":          FUTC,
+		"debugorigin":  FDebugOrigin,
+		"color":        FColor,
+		"colorauto":    FColorAuto,
+		"indent":       FIndent,
+		"indentmarker": FIndentMarker,
+		"truncate":     FTruncate,
+		"wrap":         FWrap,
+		"rfc3339":      FRFC3339,
+		"elapsed":      FElapsed,
+		"unixtime":     FUnixTime,
+		"unixmillis":   FUnixMillis,
+		"symbols":      FSymbols,
+		"prefix":       FPrefixDebug | FPrefixInfo | FPrefixWarn | FPrefixError,
+		"prefixdebug":  FPrefixDebug,
+		"prefixinfo":   FPrefixInfo,
+		"prefixwarn":   FPrefixWarn,
+		"prefixerror":  FPrefixError,
+		"sync":         FSync,
+		"syncdebug":    FSyncDebug,
+		"syncinfo":     FSyncInfo,
+		"syncwarn":     FSyncWarn,
+		"syncerror":    FSyncError,
+		"fsync":        FFsync,
+		"fsyncdebug":   FFsyncDebug,
+		"fsyncinfo":    FFsyncInfo,
+		"fsyncwarn":    FFsyncWarn,
+		"fsyncerror":   FFsyncError,
+		"default":      FDefault,
+	}
+
+	// used instead of level prefixes with FSymbols
+	levelSymbols = [6]string{
+		"·",
+		"•",
+		"⚠",
+		"✗",
+		"",
+		"✓",
+	}
+
 	levelPrefixPlain = [6]string{
 		"[debug] ",
 		"[info] ",
@@ -501,14 +1767,6 @@ var (
 		"[time] ",
 	}
 
-	levelPrefixColor = [6]string{
-		"\x1b[90m[\x1b[34;1m" + "debug" + "\x1b[22;90m]\x1b[39m ",
-		"\x1b[90m[\x1b[39;1m" + "info" + "\x1b[90m]\x1b[22;39m ",
-		"\x1b[90m[\x1b[33;1m" + "warn" + "\x1b[22;90m]\x1b[39m ",
-		"\x1b[90m[\x1b[31;1m" + "error" + "\x1b[22;90m]\x1b[39m ",
-		"", // disabled; ignore
-		"\x1b[90m[\x1b[36;1m" + "time" + "\x1b[22;90m]\x1b[39m ",
-	}
 	// 1  bold on
 	// 22 bold off
 	// foreground color:
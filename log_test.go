@@ -2,9 +2,15 @@ package log
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -152,3 +158,438 @@ func TestLogSerialization(t *testing.T) {
 		}
 	}
 }
+
+func TestLogScope(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixInfo)
+	defer l.Close()
+
+	l.Info("a")
+	popCmd := l.WithScope("cmd")
+	l.Info("b")
+	popSub := l.WithScope("sub")
+	sub := l.SubLogger("[x]")
+	l.Info("c")
+	popSub()
+	l.Info("d")
+	l.WithScope("other")
+	sub.Info("e")
+	popCmd()
+	l.Info("f")
+	l.Sync()
+
+	assert.Eq("output", w.String(), ""+
+		"[info] a\n"+
+		"[info] cmd b\n"+
+		"[info] cmd>sub c\n"+
+		"[info] cmd d\n"+
+		"[info] [x] cmd>sub e\n"+
+		"[info] f\n")
+	assert.Eq("scope", len(l.Scope()), 0)
+}
+
+func TestLogAutoColor(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	l := NewLogger(&bytes.Buffer{}, "", LevelInfo, FColorAuto|FColor)
+	defer l.Close()
+	assert.Ok("FColor disabled for non-TTY writer", l.Features&FColor == 0)
+
+	l.DisableFeatures(FColorAuto)
+	l.EnableFeatures(FColor)
+	l.SetWriter(&bytes.Buffer{})
+	assert.Ok("FColor kept without FColorAuto", l.Features&FColor != 0)
+
+	l.EnableFeatures(FColorAuto)
+	assert.Ok("FColor disabled when enabling FColorAuto", l.Features&FColor == 0)
+
+	l.EnableFeatures(FColor)
+	l.RefreshAutoFeatures()
+	assert.Ok("FColor disabled by RefreshAutoFeatures", l.Features&FColor == 0)
+}
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed int
+}
+
+func (w *closeRecorder) Close() error {
+	w.closed++
+	return nil
+}
+
+func TestLogClose(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &closeRecorder{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	sub := l.SubLogger("[sub]")
+
+	sub.Info("a")
+	assert.NoErr("close sub-logger", sub.Close())
+	sub.Info("b") // discarded
+	l.Info("c")
+	assert.NoErr("sync", l.Sync())
+	assert.Eq("output after closing sub-logger", w.String(), "[sub] a\nc\n")
+
+	for i := 0; i < 1000; i++ {
+		l.Info("d")
+	}
+	assert.NoErr("close", l.Close())
+	assert.Eq("all records written", w.Len(), len("[sub] a\nc\n")+1000*2)
+	assert.Eq("writer closed", w.closed, 1)
+
+	// using a closed logger is safe
+	l.Info("e")
+	l.Error("e")
+	l.WithBuffer(1024, 0)
+	assert.NoErr("sync after close", l.Sync())
+	assert.NoErr("close after close", l.Close())
+	assert.Eq("writer closed once", w.closed, 1)
+	assert.Eq("dropped records", l.Metrics().Dropped, uint64(2))
+}
+
+// syncRecorder records writes and calls to Sync
+type syncRecorder struct {
+	bytes.Buffer
+	syncs []int // length of buffer at each Sync call
+}
+
+func (w *syncRecorder) Sync() error {
+	w.syncs = append(w.syncs, w.Len())
+	return nil
+}
+
+func TestLogFsync(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &syncRecorder{}
+	var feats Features = FFsyncError | FSyncError
+	l := NewLogger(w, "", LevelInfo, feats)
+	defer l.Close()
+
+	l.Info("a")
+	l.Sync()
+	l.Error("b") // written and synced before returning
+	assert.Eq("synced after error", len(w.syncs), 1)
+	assert.Eq("synced after writing error", w.syncs[0], len("a\nb\n"))
+	l.Warn("c")
+	l.Sync()
+	assert.Eq("not synced after warning", len(w.syncs), 1)
+
+	// buffered records are flushed and synced
+	l.DisableFeatures(FSyncError)
+	l.WithBuffer(1024, 0)
+	l.Info("d")
+	l.Error("e")
+	l.Sync()
+	assert.Eq("buffer synced after error", len(w.syncs), 2)
+	assert.Eq("buffer flushed before sync", w.syncs[1], w.Len())
+}
+
+func TestLogCB(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+
+	done := make(chan error, 1)
+	cb := func(err error) { done <- err }
+	l.LogCB(LevelWarn, cb, "critical %d", 1)
+	assert.NoErr("written", <-done)
+	assert.Eq("written before callback", w.String(), "critical 1\n")
+
+	l.WithBuffer(1024, 0)
+	l.Info("buffered")
+	l.LogCB(LevelWarn, cb, "critical %d", 2)
+	assert.NoErr("written", <-done)
+	assert.Eq("buffer flushed before callback", w.String(), "critical 1\nbuffered\ncritical 2\n")
+
+	l.LogCB(LevelDebug, cb, "disabled")
+	assert.NoErr("disabled level", <-done)
+
+	l.Close()
+	l.LogCB(LevelWarn, cb, "closed")
+	assert.Eq("closed", <-done, ErrClosed)
+}
+
+func TestLogIndent(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	var feats Features = FIndent | FPrefixError
+	l := NewLogger(w, "[p]", LevelInfo, feats)
+	defer l.Close()
+
+	l.Error("panic: boom\ngoroutine 1:\n\tmain.go:12\n")
+	l.Info("one line")
+	l.Sync()
+	l.EnableFeatures(FIndentMarker | FColor)
+	l.Error("a\nb")
+	l.Sync()
+	assert.Eq("output", w.String(), ""+
+		"[error] [p] panic: boom\n"+
+		"            goroutine 1:\n"+
+		"            \tmain.go:12\n"+
+		"[p] one line\n"+
+		currentTheme().levelPrefix[LevelError]+"[p] a\n"+
+		"          | b\n")
+}
+
+func TestLogTimeFormat(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	var feats Features = FRFC3339 | FMilliseconds | FUTC
+	l := NewLogger(w, "", LevelInfo, feats)
+	defer l.Close()
+
+	l.Info("a")
+	l.Sync()
+	l.SetTimeFormat("Jan 2 15h")
+	l.Info("b")
+	l.Sync()
+	l.SetTimeFormat("")
+	l.DisableFeatures(FMilliseconds)
+	l.Info("c")
+	l.Sync()
+
+	re := regexp.MustCompile(`^` +
+		`\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z a\n` +
+		`[A-Z][a-z]{2} \d+ \d\dh b\n` +
+		`\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ c\n$`)
+	assert.Ok("output %q", re.Match(w.Bytes()), w.String())
+}
+
+func TestLogTimeRenderer(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+	l.SetClock(func() time.Time { return time.Date(2021, 8, 4, 15, 4, 5, 0, time.UTC) })
+	months := [...]string{"janv.", "févr.", "mars", "avr.", "mai", "juin",
+		"juil.", "août", "sept.", "oct.", "nov.", "déc."}
+	l.SetTimeFormat("ignored")
+	l.SetTimeRenderer(func(buf []byte, t time.Time) []byte {
+		buf = strconv.AppendInt(buf, int64(t.Day()), 10)
+		buf = append(buf, ' ')
+		buf = append(buf, months[t.Month()-1]...)
+		return t.AppendFormat(buf, " 15:04")
+	})
+	l.Info("a")
+	l.SubLogger("[sub]").Info("b")
+	l.Sync()
+	assert.Eq("output", w.String(), "4 août 15:04 a\n4 août 15:04 [sub] b\n")
+}
+
+func TestLogElapsed(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	var feats Features = FElapsed | FTime | FPrefixInfo
+	l := NewLogger(w, "", LevelInfo, feats)
+	defer l.Close()
+
+	m := l.newRecord(LevelInfo)
+	m.time = initTime.Add(12345678 * time.Microsecond)
+	m.msg = append(m.msg, "a"...)
+	l.submit(m)
+	l.Sync()
+	l.EnableFeatures(FMicroseconds)
+	l.Info("b")
+	l.Sync()
+
+	re := regexp.MustCompile(`^\+12\.345s \[info\] a\n\+\d+\.\d{6}s \[info\] b\n$`)
+	assert.Ok("output %q", re.Match(w.Bytes()), w.String())
+
+	// a clock set before the process started
+	w.Reset()
+	l.SetClock(func() time.Time { return initTime.Add(-1500 * time.Millisecond) })
+	l.Info("c")
+	l.Sync()
+	l.DisableFeatures(FMicroseconds)
+	l.Info("d")
+	l.Sync()
+	assert.Eq("negative", w.String(), "-1.500000s [info] c\n-1.500s [info] d\n")
+}
+
+func TestLogUnixTime(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	var feats Features = FUnixTime | FTime
+	l := NewLogger(w, "", LevelInfo, feats)
+	defer l.Close()
+
+	logAt := func(t time.Time, msg string) {
+		m := l.newRecord(LevelInfo)
+		m.time = t
+		m.msg = append(m.msg, msg...)
+		l.submit(m)
+		l.Sync()
+	}
+	t0 := time.Unix(1136214245, 123456789)
+	logAt(t0, "a")
+	l.EnableFeatures(FUnixMillis)
+	logAt(t0, "b")
+	assert.Eq("output", w.String(), "1136214245 a\n1136214245123 b\n")
+}
+
+func TestLogClock(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	var feats Features = FDate | FTime | FUTC | FPrefixInfo
+	l := NewLogger(w, "", LevelInfo, feats)
+	defer l.Close()
+
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	l.SetClock(func() time.Time { return now })
+	l.Info("hello")
+	done := l.Time("work")
+	now = now.Add(1500 * time.Millisecond)
+	done()
+	l.Sync()
+	assert.Eq("output", w.String(), ""+
+		"2006-01-02 15:04:05 [info] hello\n"+
+		"2006-01-02 15:04:06 [time] work: 1.5s\n")
+}
+
+func TestLogConcurrentConfig(t *testing.T) {
+	// run with -race; changing level, features and writer while logging must not race
+	l := NewLogger(ioutil.Discard, "", LevelInfo, FTime)
+	defer l.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sub := l.SubLogger("[sub]")
+		for i := 0; i < 1000; i++ {
+			l.Info("hello %d", i)
+			sub.Warn("hello %d", i)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		l.SetLevel(Level(i % 3))
+		l.EnableFeatures(FColor | FDate)
+		l.DisableFeatures(FColor)
+		l.SetFeatures(FTime | FColorAuto)
+		l.SetWriter(ioutil.Discard)
+		l.SubLogger("[x]").Info("x")
+	}
+	<-done
+	l.Sync()
+}
+
+type slowWriter struct {
+	bytes.Buffer
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	return w.Buffer.Write(p)
+}
+
+func TestLogSetWriter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w1, w2 := &slowWriter{}, &slowWriter{}
+	l := NewLogger(w1, "", LevelInfo, 0)
+	defer l.Close()
+
+	for i := 0; i < 20; i++ {
+		l.Info("%d", i)
+	}
+	l.SetWriter(w2) // records queued above must be written to w1
+	n := bytes.Count(w1.Bytes(), []byte("\n"))
+	l.Info("after")
+	l.Sync()
+	assert.Eq("w1 lines", n, 20)
+	assert.Eq("w2", w2.String(), "after\n")
+
+	l.Close()
+	l.SetWriter(w1) // closed
+	assert.Eq("writer", l.Writer(), io.Writer(w1))
+}
+
+func TestLogOrdered(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &slowWriter{}
+	l := New(Options{Writer: w, Level: LevelInfo, Features: FSyncError, Ordered: true})
+	defer l.Close()
+
+	for i := 0; i < 20; i++ {
+		l.Info("%d", i)
+	}
+	l.Error("failed")
+	// the error is written after the queued info messages, before Error returns
+	lines := strings.Split(w.String(), "\n")
+	assert.Eq("lines", len(lines), 22)
+	assert.Eq("first", lines[0], "0")
+	assert.Eq("last", lines[20], "failed")
+
+	var cberr error = ErrClosed
+	l.LogCB(LevelError, func(err error) { cberr = err }, "with callback")
+	assert.Eq("callback", cberr, nil)
+
+	l.SetOrdered(false)
+	l.Error("unordered")
+	assert.Ok("written", strings.HasSuffix(w.String(), "with callback\nunordered\n"))
+}
+
+func TestDateTimeFragment(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	t1 := time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC)
+	assert.Eq("date", string(dateTimeFragment(t1, true, false)), "2021-03-04 ")
+	assert.Eq("time", string(dateTimeFragment(t1, false, true)), "05:06:07")
+	assert.Eq("both", string(dateTimeFragment(t1, true, true)), "2021-03-04 05:06:07")
+	t2 := t1.Add(time.Second)
+	assert.Eq("next second", string(dateTimeFragment(t2, false, true)), "05:06:08")
+	loc := time.FixedZone("X", 3600)
+	assert.Eq("other location", string(dateTimeFragment(t2.In(loc), false, true)), "06:06:08")
+}
+
+func TestLogWrapVerb(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	sink := NewMemorySink(10)
+	l := NewLogger(NewTeeWriter(TeeSink{W: w}, TeeSink{W: sink}), "", LevelInfo, 0)
+	defer l.Close()
+
+	err := fmt.Errorf("read: %w", io.EOF)
+	l.Warn("load %q failed: %w", "a.txt", err)
+	l.Info("%d%% done", 100)
+	l.Sync()
+	assert.Eq("output", w.String(), "load \"a.txt\" failed: read: EOF\n100% done\n")
+	r := sink.Records()
+	assert.Eq("error field", r[0].Fields["error"], "read: EOF")
+	assert.Eq("no error", len(r[1].Fields), 0)
+
+	assert.Ok("%w", hasWrapVerb("%w"))
+	assert.Ok("%+w", hasWrapVerb("x %+w"))
+	assert.Ok("%%w", !hasWrapVerb("100%%w"))
+	assert.Ok("%v", !hasWrapVerb("%v w"))
+}
+
+// TestVetPrintf checks that go vet recognizes the printf-like functions of this package and
+// accepts %w with an error operand
+func TestVetPrintf(t *testing.T) {
+	if testing.Short() {
+		t.Skip("short")
+	}
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+	src, err := ioutil.ReadFile("testdata/vetprintf/vetprintf.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for i, line := range strings.Split(string(src), "\n") {
+		if strings.HasSuffix(line, "// want") {
+			want = append(want, "vetprintf.go:"+strconv.Itoa(i+1)+":")
+		}
+	}
+	out, _ := exec.Command(gotool, "vet", "./testdata/vetprintf").CombinedOutput()
+	var got []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if i := strings.Index(line, "vetprintf.go:"); i != -1 {
+			s := line[i:]
+			got = append(got, s[:strings.Index(s[len("vetprintf.go:"):], ":")+len("vetprintf.go:")+1])
+		}
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("go vet reported\n%s\nexpected diagnostics at %v", out, want)
+	}
+}
@@ -0,0 +1,104 @@
+// Package logbench provides reusable benchmark scenarios for go-log, making it easy to compare
+// configurations like synchronous vs asynchronous and buffered vs unbuffered output on your own
+// hardware and with your own writers.
+//
+// Run all scenarios writing to ioutil.Discard:
+//
+//   go test -bench . -benchmem github.com/rsms/go-log/logbench
+//
+// Run all scenarios with a custom writer, e.g. from a benchmark in your own package:
+//
+//   func BenchmarkLog(b *testing.B) {
+//     f, _ := os.Create(filepath.Join(b.TempDir(), "bench.log"))
+//     defer f.Close()
+//     logbench.RunAll(b, f)
+//   }
+//
+package logbench
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-log"
+)
+
+// Scenario describes a benchmark. Each iteration logs Message with Args at LevelInfo.
+type Scenario struct {
+	Name       string
+	Level      log.Level    // level of the logger
+	Features   log.Features // features of the logger
+	BufferSize int          // if >0, output is buffered (see Logger.WithBuffer)
+	Parallel   bool         // log from GOMAXPROCS goroutines (see testing.B.RunParallel)
+	Message    string
+	Args       []interface{}
+}
+
+const (
+	features    = log.FTime | log.FMicroseconds | log.FPrefixInfo
+	constMsg    = "The quick brown fox jumps over the lazy dog"
+	formatMsg   = "The quick brown %s jumps over the lazy %s %d"
+	largeMsgLen = 4096
+)
+
+var formatArgs = []interface{}{"fox", "dog", 123}
+
+// Scenarios is the list of scenarios run by RunAll
+var Scenarios = []Scenario{
+	{Name: "disabled", Level: log.LevelWarn, Features: features, Message: formatMsg, Args: formatArgs},
+	{Name: "async/constant", Features: features, Message: constMsg},
+	{Name: "async/formatted", Features: features, Message: formatMsg, Args: formatArgs},
+	{Name: "async/large", Features: features, Message: strings.Repeat("x", largeMsgLen)},
+	{Name: "async/parallel", Features: features, Message: formatMsg, Args: formatArgs, Parallel: true},
+	{Name: "sync/constant", Features: features | log.FSync, Message: constMsg},
+	{Name: "sync/formatted", Features: features | log.FSync, Message: formatMsg, Args: formatArgs},
+	{Name: "sync/parallel", Features: features | log.FSync, Message: formatMsg, Args: formatArgs,
+		Parallel: true},
+	{Name: "buffered/constant", Features: features, BufferSize: 64 << 10, Message: constMsg},
+	{Name: "buffered/formatted", Features: features, BufferSize: 64 << 10, Message: formatMsg,
+		Args: formatArgs},
+	{Name: "buffered/parallel", Features: features, BufferSize: 64 << 10, Message: formatMsg,
+		Args: formatArgs, Parallel: true},
+}
+
+// RunAll runs all Scenarios as sub-benchmarks of b, writing to w
+func RunAll(b *testing.B, w io.Writer) {
+	for _, s := range Scenarios {
+		s := s
+		b.Run(s.Name, func(b *testing.B) { s.Run(b, w) })
+	}
+}
+
+// Run runs the scenario, writing to w. The time reported includes waiting for all messages
+// to be written. w is not closed.
+func (s Scenario) Run(b *testing.B, w io.Writer) {
+	l := log.NewLogger(writerOnly{w}, "", s.Level, s.Features)
+	defer l.Close()
+	if s.BufferSize > 0 {
+		l.WithBuffer(s.BufferSize, time.Second)
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s.Message)))
+	b.ResetTimer()
+	if s.Parallel {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				l.Info(s.Message, s.Args...)
+			}
+		})
+	} else {
+		for i := 0; i < b.N; i++ {
+			l.Info(s.Message, s.Args...)
+		}
+	}
+	l.Sync()
+	b.StopTimer()
+}
+
+// writerOnly hides all methods of a writer but Write, so that closing a logger does not close
+// the writer, which is shared by all scenarios (see RunAll)
+type writerOnly struct {
+	io.Writer
+}
@@ -0,0 +1,34 @@
+package logbench
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func BenchmarkScenarios(b *testing.B) {
+	RunAll(b, ioutil.Discard)
+}
+
+// closeCounter counts bytes written and calls to Close
+type closeCounter struct {
+	n      int
+	closed int
+}
+
+func (w *closeCounter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
+
+func (w *closeCounter) Close() error {
+	w.closed++
+	return nil
+}
+
+func TestRunKeepsWriterOpen(t *testing.T) {
+	w := &closeCounter{}
+	testing.Benchmark(func(b *testing.B) { Scenarios[1].Run(b, w) })
+	if w.closed != 0 || w.n == 0 {
+		t.Fatalf("writer closed %d times, %d bytes written", w.closed, w.n)
+	}
+}
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Field is a key-value pair attached to records, like a request ID.
+// See PushScope and ContextWithFields.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// PushScope adds a field to the diagnostic context of the calling goroutine. The field is
+// included with all messages logged by the goroutine, by any logger, until the returned
+// function is called. This provides request correlation even in code which doesn't have
+// access to a request-specific logger or context:
+//
+//   func handleRequest(w http.ResponseWriter, r *http.Request) {
+//     defer log.PushScope("reqid", r.Header.Get("X-Request-Id"))()
+//     process(r) // "[info] processing reqid=abc123"
+//   }
+//
+// Fields are rendered as " key=value" at the end of the message.
+// Goroutines started within the scope do not inherit its fields; use ContextWithFields with
+// LogContext to propagate fields across goroutines.
+//
+// The returned function must be called before the goroutine exits, usually with defer.
+// Fields of a goroutine which exits within a scope are never removed, and while any goroutine
+// has fields, every message costs a lookup of the logging goroutine's ID.
+func PushScope(key, value string) func() {
+	id := goroutineID()
+	prev := goroutineFields.get(id)
+	fields := make([]Field, len(prev)+1) // copy since records may reference prev
+	copy(fields, prev)
+	fields[len(prev)] = Field{key, value}
+	goroutineFields.set(id, fields)
+	return func() { goroutineFields.set(id, prev) }
+}
+
+type fieldsContextKey struct{}
+
+// ContextWithFields returns a copy of ctx carrying fields given as alternating keys and values.
+// The fields are included with messages logged with the context (see LogContext), after the
+// fields of the logging goroutine (see PushScope).
+//
+//   ctx = log.ContextWithFields(ctx, "reqid", id, "user", user.Name)
+//   logger.InfoContext(ctx, "hello") // "[info] hello reqid=abc123 user=bob"
+//
+func ContextWithFields(ctx context.Context, keyvals ...string) context.Context {
+	prev := FieldsFromContext(ctx)
+	fields := make([]Field, len(prev), len(prev)+len(keyvals)/2)
+	copy(fields, prev)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields = append(fields, Field{keyvals[i], keyvals[i+1]})
+	}
+	return context.WithValue(ctx, fieldsContextKey{}, fields)
+}
+
+// FieldsFromContext returns the fields added to ctx with ContextWithFields
+func FieldsFromContext(ctx context.Context) []Field {
+	fields, _ := ctx.Value(fieldsContextKey{}).([]Field)
+	return fields
+}
+
+// fieldMap holds the fields of goroutines which have called PushScope
+type fieldMap struct {
+	n  int32 // len(m); read atomically to avoid the cost of goroutineID when m is empty
+	mu sync.RWMutex
+	m  map[uint64][]Field
+}
+
+var goroutineFields = fieldMap{m: make(map[uint64][]Field)}
+
+func (fm *fieldMap) get(id uint64) []Field {
+	if atomic.LoadInt32(&fm.n) == 0 {
+		return nil
+	}
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	return fm.m[id]
+}
+
+func (fm *fieldMap) set(id uint64, fields []Field) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	if len(fields) == 0 {
+		delete(fm.m, id)
+	} else {
+		fm.m[id] = fields
+	}
+	atomic.StoreInt32(&fm.n, int32(len(fm.m)))
+}
+
+// current returns the fields of the calling goroutine
+func (fm *fieldMap) current() []Field {
+	if atomic.LoadInt32(&fm.n) == 0 {
+		return nil
+	}
+	return fm.get(goroutineID())
+}
+
+// goroutineID returns the ID of the calling goroutine, parsed from the header of its stack
+// trace ("goroutine 123 [running]:")
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = b[len("goroutine "):]
+	var id uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return id
+}
+
+// appendFields appends fields to buf as " key=value" pairs, quoting values as needed
+func appendFields(buf *[]byte, fields []Field, color bool) {
+	if color {
+		*buf = append(*buf, currentTheme().fields...)
+	}
+	for _, f := range fields {
+		*buf = append(*buf, ' ')
+		*buf = append(*buf, f.Key...)
+		*buf = append(*buf, '=')
+		if f.Value == "" || strings.ContainsAny(f.Value, " =\"\n\t") {
+			*buf = strconv.AppendQuote(*buf, f.Value)
+		} else {
+			*buf = append(*buf, f.Value...)
+		}
+	}
+	if color {
+		*buf = append(*buf, colorFgReset...)
+	}
+}
+
+// fieldMapOf returns fields as a map, or nil if fields is empty. Later fields take precedence.
+func fieldMapOf(fields []Field) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(fields))
+	for _, f := range fields {
+		m[f.Key] = f.Value
+	}
+	return m
+}
+
+// sortedFields returns the fields of m sorted by key
+func sortedFields(m map[string]string) []Field {
+	fields := make([]Field, 0, len(m))
+	for k, v := range m {
+		fields = append(fields, Field{k, v})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestPushScope(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+
+	pop := PushScope("reqid", "abc")
+	l.Info("a")
+	pop2 := PushScope("user", "bob smith")
+	l.Info("b\n")
+	done := make(chan struct{})
+	go func() {
+		l.Info("other goroutine")
+		close(done)
+	}()
+	<-done
+	pop2()
+	l.Info("c")
+	pop()
+	l.Info("d")
+	l.Sync()
+	assert.Eq("output", w.String(), ""+
+		"a reqid=abc\n"+
+		"b reqid=abc user=\"bob smith\"\n"+
+		"other goroutine\n"+
+		"c reqid=abc\n"+
+		"d\n")
+	assert.Eq("no goroutines with fields", len(goroutineFields.m), 0)
+}
+
+func TestContextWithFields(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(10)
+	l := NewLogger(sink, "", LevelInfo, 0)
+	defer l.Close()
+
+	ctx := ContextWithFields(context.Background(), "reqid", "abc")
+	ctx2 := ContextWithFields(ctx, "step", "2")
+	defer PushScope("user", "bob")()
+	l.InfoContext(ctx2, "hello")
+	l.InfoContext(ctx, "hello again")
+	l.Sync()
+
+	records := sink.Records()
+	assert.Eq("fields", fmt.Sprint(records[0].Fields), "map[reqid:abc step:2 user:bob]")
+	assert.Eq("parent context fields", fmt.Sprint(records[1].Fields), "map[reqid:abc user:bob]")
+	text := string(formatRecordsText(records[:1]))
+	assert.Ok("text format; got %q", strings.HasSuffix(text, "hello reqid=abc step=2 user=bob\n"), text)
+}
@@ -0,0 +1,141 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySink is a writer which keeps the most recent records in memory, for example for tests
+// or for showing recent log messages in a debug UI of a running program.
+//
+//   sink := log.NewMemorySink(1000)
+//   logger.SetWriter(sink)
+//   ...
+//   for _, r := range sink.Filter(func(r log.Record) bool { return r.Level >= log.LevelWarn }) {
+//     fmt.Println(r.Time, r.Level, r.Msg)
+//   }
+//
+// Records are captured with their level, time and prefix rather than as formatted text.
+// Output written to a MemorySink with Write (e.g. via io.MultiWriter or a Go log.Logger)
+// is stored as LevelInfo records, one per line.
+type MemorySink struct {
+	mu      sync.Mutex
+	records []Record      // ring buffer
+	start   int           // index of oldest record
+	n       int           // number of records
+	seq     uint64        // total number of records added
+	changed chan struct{} // closed and replaced when a record is added
+}
+
+// NewMemorySink creates a MemorySink which keeps at most capacity records
+func NewMemorySink(capacity int) *MemorySink {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &MemorySink{records: make([]Record, capacity), changed: make(chan struct{})}
+}
+
+func (s *MemorySink) add(r Record) {
+	s.mu.Lock()
+	if s.n < len(s.records) {
+		s.records[(s.start+s.n)%len(s.records)] = r
+		s.n++
+	} else {
+		s.records[s.start] = r
+		s.start = (s.start + 1) % len(s.records)
+	}
+	s.seq++
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// since returns records added after the record with sequence number seq (which are still
+// held), the sequence number of the last record and a channel which is closed when a new
+// record is added.
+func (s *MemorySink) since(seq uint64) ([]Record, uint64, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.seq - seq
+	if n > uint64(s.n) {
+		n = uint64(s.n)
+	}
+	records := make([]Record, 0, n)
+	for i := s.n - int(n); i < s.n; i++ {
+		records = append(records, s.records[(s.start+i)%len(s.records)])
+	}
+	return records, s.seq, s.changed
+}
+
+func (s *MemorySink) writeRecord(m *logRecord) error {
+	s.add(m.record())
+	return nil
+}
+
+// Write adds a LevelInfo record for each line in p
+func (s *MemorySink) Write(p []byte) (int, error) {
+	now := time.Now()
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		s.add(Record{Level: LevelInfo, Time: now, Msg: line})
+	}
+	return len(p), nil
+}
+
+// Len returns the number of records currently held
+func (s *MemorySink) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n
+}
+
+// Records returns all records currently held, oldest first
+func (s *MemorySink) Records() []Record {
+	return s.Filter(nil)
+}
+
+// Last returns the n most recent records, oldest first
+func (s *MemorySink) Last(n int) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > s.n {
+		n = s.n
+	}
+	records := make([]Record, 0, n)
+	for i := s.n - n; i < s.n; i++ {
+		records = append(records, s.records[(s.start+i)%len(s.records)])
+	}
+	return records
+}
+
+// Filter returns all records for which f returns true, oldest first.
+// A nil f matches all records.
+func (s *MemorySink) Filter(f func(Record) bool) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []Record
+	for i := 0; i < s.n; i++ {
+		r := s.records[(s.start+i)%len(s.records)]
+		if f == nil || f(r) {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// Contains returns true if a record of level with a message containing substr is held
+func (s *MemorySink) Contains(level Level, substr string) bool {
+	return len(s.Filter(func(r Record) bool {
+		return r.Level == level && strings.Contains(r.Msg, substr)
+	})) > 0
+}
+
+// Reset removes all records
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	for i := range s.records {
+		s.records[i] = Record{}
+	}
+	s.start, s.n, s.seq = 0, 0, 0
+	s.mu.Unlock()
+}
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"fmt"
+	stdlog "log"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func msgs(records []Record) string {
+	s := ""
+	for _, r := range records {
+		s += fmt.Sprintf("%s:%s;", r.Level, r.Msg)
+	}
+	return s
+}
+
+func TestMemorySink(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(3)
+	l := NewLogger(sink, "", LevelDebug, FDefault)
+	defer l.Close()
+
+	l.Info("a")
+	l.Warn("b")
+	l.Sync()
+	assert.Eq("records", msgs(sink.Records()), "info:a;warn:b;")
+
+	l.SubLogger("[sub]").Error("c")
+	l.Info("d")
+	l.Sync()
+	assert.Eq("len", sink.Len(), 3)
+	assert.Eq("oldest dropped", msgs(sink.Records()), "warn:b;error:c;info:d;")
+	assert.Eq("prefix", sink.Records()[1].Prefix, "[sub]")
+	assert.Eq("last 2", msgs(sink.Last(2)), "error:c;info:d;")
+	assert.Eq("last 10", msgs(sink.Last(10)), "warn:b;error:c;info:d;")
+	assert.Eq("filter", msgs(sink.Filter(func(r Record) bool { return r.Level >= LevelWarn })),
+		"warn:b;error:c;")
+	assert.Ok("contains", sink.Contains(LevelError, "c"))
+	assert.Ok("not contains", !sink.Contains(LevelInfo, "c"))
+
+	sink.Reset()
+	assert.Eq("len after reset", sink.Len(), 0)
+	assert.Eq("seq after reset", sink.seq, uint64(0))
+
+	// plain writes, e.g. from a Go log.Logger
+	stdlog.New(sink, "", 0).Print("hello\nworld")
+	assert.Eq("plain writes", msgs(sink.Records()), "info:hello;info:world;")
+}
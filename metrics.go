@@ -0,0 +1,172 @@
+package log
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Metrics is a snapshot of a logger's counters. See Logger.Metrics
+type Metrics struct {
+	Records      map[Level]uint64 // number of records logged, per level
+	BytesWritten uint64           // number of bytes written to the writer
+	QueueDepth   int              // number of records waiting to be written
+	Dropped      uint64           // number of records discarded without being written
+	WriteErrors  uint64           // number of failed writes
+}
+
+// metrics holds the live counters of a logger and its sub-loggers.
+// All fields are accessed atomically.
+type metrics struct {
+	records     [levelTime + 1]uint64
+	bytes       uint64
+	dropped     uint64
+	writeErrors uint64
+}
+
+func (m *metrics) logged(level Level) {
+	if level >= 0 && int(level) < len(m.records) {
+		atomic.AddUint64(&m.records[level], 1)
+	}
+}
+
+func (m *metrics) drop() {
+	atomic.AddUint64(&m.dropped, 1)
+}
+
+func (m *metrics) wrote(n int, err error) {
+	atomic.AddUint64(&m.bytes, uint64(n))
+	if err != nil {
+		atomic.AddUint64(&m.writeErrors, 1)
+	}
+}
+
+// Metrics returns a snapshot of the logger's counters.
+// Sub-loggers share counters with the logger they were created from.
+func (l *Logger) Metrics() Metrics {
+	return Metrics{
+		Records:      l.metrics.snapshotRecords(),
+		BytesWritten: atomic.LoadUint64(&l.metrics.bytes),
+		QueueDepth:   l.q.len(),
+		Dropped:      atomic.LoadUint64(&l.metrics.dropped),
+		WriteErrors:  atomic.LoadUint64(&l.metrics.writeErrors),
+	}
+}
+
+// PublishExpvar publishes the logger's counters as an expvar map with the given name.
+// Values are read when the map is visited, e.g. when /debug/vars is requested.
+// Like expvar.Publish, this panics if name is already in use.
+func (l *Logger) PublishExpvar(name string) *expvar.Map {
+	m := new(expvar.Map).Init()
+	m.Set("records", expvar.Func(func() interface{} {
+		records := make(map[string]uint64, len(l.metrics.records))
+		for level, n := range l.Metrics().Records {
+			records[level.String()] = n
+		}
+		return records
+	}))
+	m.Set("bytes_written", expvar.Func(func() interface{} {
+		return atomic.LoadUint64(&l.metrics.bytes)
+	}))
+	m.Set("queue_depth", expvar.Func(func() interface{} {
+		return l.q.len()
+	}))
+	m.Set("dropped", expvar.Func(func() interface{} {
+		return atomic.LoadUint64(&l.metrics.dropped)
+	}))
+	m.Set("write_errors", expvar.Func(func() interface{} {
+		return atomic.LoadUint64(&l.metrics.writeErrors)
+	}))
+	expvar.Publish(name, m)
+	return m
+}
+
+// WritePrometheus writes m to w in the Prometheus text exposition format.
+// Metric names are prefixed with namespace + "_" when namespace is not empty.
+//
+// This allows exposing logger metrics to Prometheus without depending on its client library:
+//
+//   http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+//     log.RootLogger.Metrics().WritePrometheus(w, "myapp_log")
+//   })
+//
+func (m Metrics) WritePrometheus(w io.Writer, namespace string) error {
+	if namespace != "" {
+		namespace += "_"
+	}
+	buf := make([]byte, 0, 1024)
+	metric := func(name, typ, help string) {
+		buf = append(buf, fmt.Sprintf("# HELP %s%s %s\n# TYPE %s%s %s\n",
+			namespace, name, help, namespace, name, typ)...)
+	}
+	metric("records_total", "counter", "Number of records logged, by level.")
+	for level := LevelDebug; level <= levelTime; level++ {
+		if n, ok := m.Records[level]; ok {
+			buf = append(buf, fmt.Sprintf("%srecords_total{level=%q} %d\n", namespace, level, n)...)
+		}
+	}
+	metric("bytes_written_total", "counter", "Number of bytes written.")
+	buf = append(buf, fmt.Sprintf("%sbytes_written_total %d\n", namespace, m.BytesWritten)...)
+	metric("queue_depth", "gauge", "Number of records waiting to be written.")
+	buf = append(buf, fmt.Sprintf("%squeue_depth %d\n", namespace, m.QueueDepth)...)
+	metric("dropped_records_total", "counter", "Number of records dropped without being written.")
+	buf = append(buf, fmt.Sprintf("%sdropped_records_total %d\n", namespace, m.Dropped)...)
+	metric("write_errors_total", "counter", "Number of failed writes.")
+	buf = append(buf, fmt.Sprintf("%swrite_errors_total %d\n", namespace, m.WriteErrors)...)
+	_, err := w.Write(buf)
+	return err
+}
+
+// LoggerStats holds the number of records logged by a named logger. See Stats
+type LoggerStats struct {
+	Name    string           // see GetLogger
+	Records map[Level]uint64 // number of records logged, per level
+}
+
+// Stats returns the number of records logged by each named logger (see GetLogger), sorted by
+// name. Records logged by sub-loggers of a named logger are counted for the named logger.
+// Records logged by a named logger are not counted for its parent.
+// This shows which part of a program logs the most, or logs errors.
+func Stats() []LoggerStats {
+	loggers := Loggers()
+	stats := make([]LoggerStats, len(loggers))
+	for i, l := range loggers {
+		stats[i] = LoggerStats{Name: l.name, Records: l.stats.snapshotRecords()}
+	}
+	return stats
+}
+
+// snapshotRecords returns the record counts of m, excluding LevelDisable
+func (m *metrics) snapshotRecords() map[Level]uint64 {
+	records := make(map[Level]uint64, len(m.records))
+	for i := range m.records {
+		if level := Level(i); level != LevelDisable {
+			records[level] = atomic.LoadUint64(&m.records[i])
+		}
+	}
+	return records
+}
+
+// WriteStatsPrometheus writes stats (see Stats) to w in the Prometheus text exposition format,
+// as the counter "records_total" labeled by logger and level. The metric name is prefixed with
+// namespace + "_" when namespace is not empty.
+func WriteStatsPrometheus(w io.Writer, stats []LoggerStats, namespace string) error {
+	if namespace != "" {
+		namespace += "_"
+	}
+	buf := make([]byte, 0, 1024)
+	buf = append(buf, fmt.Sprintf(
+		"# HELP %slogger_records_total Number of records logged, by logger and level.\n"+
+			"# TYPE %slogger_records_total counter\n", namespace, namespace)...)
+	for _, s := range stats {
+		for level := LevelDebug; level <= levelTime; level++ {
+			if n, ok := s.Records[level]; ok {
+				buf = append(buf, fmt.Sprintf("%slogger_records_total{logger=%q,level=%q} %d\n",
+					namespace, s.Name, level, n)...)
+			}
+		}
+	}
+	_, err := w.Write(buf)
+	return err
+}
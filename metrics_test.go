@@ -0,0 +1,97 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("nope") }
+
+func TestMetrics(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelDebug, 0)
+	defer l.Close()
+	sub := l.SubLogger("[sub]")
+
+	l.Info("a")
+	l.Info("b")
+	sub.Warn("c")
+	l.Debug("d")
+	l.Sync()
+
+	m := l.Metrics()
+	assert.Eq("info records", m.Records[LevelInfo], uint64(2))
+	assert.Eq("warn records", m.Records[LevelWarn], uint64(1))
+	assert.Eq("debug records", m.Records[LevelDebug], uint64(1))
+	assert.Eq("error records", m.Records[LevelError], uint64(0))
+	assert.Eq("bytes written", m.BytesWritten, uint64(w.Len()))
+	assert.Eq("queue depth", m.QueueDepth, 0)
+	assert.Eq("write errors", m.WriteErrors, uint64(0))
+	assert.Eq("sub-logger shares metrics", sub.Metrics().Records[LevelWarn], uint64(1))
+
+	l.SetWriter(failingWriter{})
+	l.Error("e")
+	l.Sync()
+	assert.Eq("write errors", l.Metrics().WriteErrors, uint64(1))
+
+	var buf bytes.Buffer
+	assert.NoErr("WritePrometheus", l.Metrics().WritePrometheus(&buf, "test"))
+	out := buf.String()
+	for _, expect := range []string{
+		"# TYPE test_records_total counter\n",
+		"test_records_total{level=\"info\"} 2\n",
+		"test_records_total{level=\"error\"} 1\n",
+		"test_write_errors_total 1\n",
+		"test_queue_depth 0\n",
+	} {
+		assert.Ok("output contains %q", strings.Contains(out, expect), expect)
+	}
+}
+
+func TestMetricsExpvar(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	l := NewLogger(&bytes.Buffer{}, "", LevelInfo, 0)
+	defer l.Close()
+	m := l.PublishExpvar(fmt.Sprintf("TestMetricsExpvar-%p", l)) // unique for -count=N
+	l.Info("hello")
+	l.Sync()
+	assert.Ok("records var", strings.Contains(m.Get("records").String(), `"info":1`))
+	assert.Eq("write_errors var", m.Get("write_errors").String(), "0")
+}
+
+func TestStats(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	name := fmt.Sprintf("TestStats-%p", t) // unique for -count=N
+	a := GetLogger(name + ".a")
+	b := GetLogger(name + ".b")
+	a.SetWriter(ioutil.Discard)
+	b.SetWriter(ioutil.Discard)
+	a.Info("1")
+	a.SubLogger("[sub]").Error("2")
+	b.Warn("3")
+	b.Warn("4")
+
+	byName := map[string]LoggerStats{}
+	for _, s := range Stats() {
+		byName[s.Name] = s
+	}
+	assert.Eq("a info", byName[name+".a"].Records[LevelInfo], uint64(1))
+	assert.Eq("a error", byName[name+".a"].Records[LevelError], uint64(1))
+	assert.Eq("b warn", byName[name+".b"].Records[LevelWarn], uint64(2))
+	assert.Eq("parent", byName[name].Records[LevelWarn], uint64(0))
+
+	var buf bytes.Buffer
+	stats := []LoggerStats{byName[name+".b"]}
+	assert.NoErr("WriteStatsPrometheus", WriteStatsPrometheus(&buf, stats, "app"))
+	assert.Ok("prometheus output %q", strings.Contains(buf.String(),
+		`app_logger_records_total{logger="`+name+`.b",level="warn"} 2`+"\n"), buf.String())
+}
@@ -0,0 +1,228 @@
+package log
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// DialOptions configures a NetWriter. The zero value of each field selects its default.
+type DialOptions struct {
+	DialTimeout  time.Duration // timeout for connecting (default 5s)
+	WriteTimeout time.Duration // timeout for each write (default 10s)
+	MinBackoff   time.Duration // initial delay between connection attempts (default 100ms)
+	MaxBackoff   time.Duration // maximum delay between connection attempts (default 30s)
+	SpillSize    int           // max bytes to hold while disconnected (default 1MB)
+}
+
+// ErrSpillFull is returned by NetWriter.Write when data was dropped because the writer has
+// been unable to deliver data for so long that its spill buffer is full.
+var ErrSpillFull = errors.New("log: network writer spill buffer full; data dropped")
+
+// NetWriter streams output to a network address, for example a Logstash or Vector collector.
+// Writes never block on the network: data is handed to a background goroutine which connects,
+// reconnects with exponential backoff when the connection fails, and holds data in a bounded
+// spill buffer while disconnected. When the spill buffer is full, the oldest data is dropped.
+//
+// With "udp" (or other packet-oriented networks) each write is sent as one datagram.
+type NetWriter struct {
+	network, addr string
+	opts          DialOptions
+
+	mu      sync.Mutex
+	cond    *sync.Cond // signalled when pending grows or closed changes
+	pending []*Buffer  // data waiting to be sent, oldest first
+	nbytes  int        // total size of pending
+	dropped uint64     // number of writes dropped
+	closed  bool
+	closech chan struct{} // closed by Close
+	done    chan struct{} // closed when the run goroutine exits
+	conn    net.Conn      // owned by the run goroutine
+}
+
+// DialWriter returns a NetWriter sending to addr on network ("tcp", "udp", "unix", ...)
+// Connecting happens in the background; DialWriter does not fail if addr is unreachable.
+// opts may be nil.
+func DialWriter(network, addr string, opts *DialOptions) *NetWriter {
+	w := &NetWriter{
+		network: network,
+		addr:    addr,
+		closech: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.DialTimeout <= 0 {
+		w.opts.DialTimeout = 5 * time.Second
+	}
+	if w.opts.WriteTimeout <= 0 {
+		w.opts.WriteTimeout = 10 * time.Second
+	}
+	if w.opts.MinBackoff <= 0 {
+		w.opts.MinBackoff = 100 * time.Millisecond
+	}
+	if w.opts.MaxBackoff < w.opts.MinBackoff {
+		w.opts.MaxBackoff = 30 * time.Second
+		if w.opts.MaxBackoff < w.opts.MinBackoff {
+			w.opts.MaxBackoff = w.opts.MinBackoff
+		}
+	}
+	if w.opts.SpillSize <= 0 {
+		w.opts.SpillSize = 1 << 20
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p for sending. Returns ErrSpillFull if older data had to be dropped
+// to make room.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	b := getBuffer()
+	b.B = append(b.B, p...)
+	return len(p), w.WriteBuffer(b)
+}
+
+// WriteBuffer queues b for sending, taking ownership of it. See BufferWriter
+func (w *NetWriter) WriteBuffer(b *Buffer) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		b.Release()
+		return errors.New("log: network writer closed")
+	}
+	w.pending = append(w.pending, b)
+	w.nbytes += len(b.B)
+	dropped := w.trim()
+	w.cond.Signal()
+	if dropped {
+		return ErrSpillFull
+	}
+	return nil
+}
+
+// Dropped returns the number of writes which have been dropped because the spill buffer was full
+func (w *NetWriter) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Close attempts to send any pending data (waiting at most DialTimeout+WriteTimeout) and then
+// closes the connection.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.closech)
+	w.cond.Signal()
+	w.mu.Unlock()
+	<-w.done
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) > 0 {
+		for _, b := range w.pending {
+			b.Release()
+		}
+		w.pending, w.nbytes = nil, 0
+		return errors.New("log: network writer closed with undelivered data")
+	}
+	return nil
+}
+
+// trim drops the oldest pending data until it fits in the spill buffer.
+// Returns true if anything was dropped. w.mu must be held.
+func (w *NetWriter) trim() bool {
+	dropped := false
+	for w.nbytes > w.opts.SpillSize && len(w.pending) > 0 {
+		w.nbytes -= len(w.pending[0].B)
+		w.pending[0].Release()
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+		w.dropped++
+		dropped = true
+	}
+	return dropped
+}
+
+// requeue puts unsent data back at the front of the pending queue
+func (w *NetWriter) requeue(bufs []*Buffer) {
+	w.mu.Lock()
+	for _, b := range bufs {
+		w.nbytes += len(b.B)
+	}
+	w.pending = append(bufs, w.pending...)
+	w.trim()
+	w.mu.Unlock()
+}
+
+func (w *NetWriter) run() {
+	defer close(w.done)
+	backoff := w.opts.MinBackoff
+	for {
+		w.mu.Lock()
+		for len(w.pending) == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		closed := w.closed
+		bufs := w.pending
+		w.pending, w.nbytes = nil, 0
+		w.mu.Unlock()
+
+		unsent, err := w.send(bufs)
+		if err != nil {
+			if w.conn != nil {
+				w.conn.Close()
+				w.conn = nil
+			}
+			w.requeue(unsent)
+			if closed {
+				return // leave unsent data for Close to report
+			}
+			select {
+			case <-time.After(backoff):
+			case <-w.closech:
+			}
+			if backoff *= 2; backoff > w.opts.MaxBackoff {
+				backoff = w.opts.MaxBackoff
+			}
+			continue
+		}
+		backoff = w.opts.MinBackoff
+		if closed {
+			if w.conn != nil {
+				w.conn.Close()
+			}
+			return
+		}
+	}
+}
+
+// send writes bufs to the connection, connecting first if needed, and releases the buffers
+// which were sent. Returns the buffers which were not sent.
+func (w *NetWriter) send(bufs []*Buffer) ([]*Buffer, error) {
+	if len(bufs) == 0 {
+		return nil, nil
+	}
+	if w.conn == nil {
+		conn, err := net.DialTimeout(w.network, w.addr, w.opts.DialTimeout)
+		if err != nil {
+			return bufs, err
+		}
+		w.conn = conn
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(w.opts.WriteTimeout))
+	for i, b := range bufs {
+		// Note: a partially-written buffer is sent again in full after reconnecting
+		if _, err := w.conn.Write(b.B); err != nil {
+			return bufs[i:], err
+		}
+		b.Release()
+	}
+	return nil, nil
+}
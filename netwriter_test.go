@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestNetWriter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoErr("listen", err) {
+		return
+	}
+	defer ln.Close()
+
+	nw := DialWriter("tcp", ln.Addr().String(), &DialOptions{MinBackoff: time.Millisecond})
+	l := NewLogger(nw, "", LevelInfo, 0)
+	defer l.Close()
+
+	l.Info("hello")
+	conn, err := ln.Accept()
+	if !assert.NoErr("accept", err) {
+		return
+	}
+	r := bufio.NewReader(conn)
+	line, _ := r.ReadString('\n')
+	assert.Eq("first line", line, "hello\n")
+
+	// reconnect after the connection is lost, delivering data logged meanwhile
+	conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		l.Info("ping")
+		ln.(*net.TCPListener).SetDeadline(time.Now().Add(10 * time.Millisecond))
+		if conn, err = ln.Accept(); err == nil {
+			break
+		}
+	}
+	if !assert.NoErr("accept after reconnect", err) {
+		return
+	}
+	defer conn.Close()
+	l.Info("after reconnect")
+	r = bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		line, err = r.ReadString('\n')
+		if err != nil || line != "ping\n" {
+			break
+		}
+	}
+	assert.Eq("line after reconnect", line, "after reconnect\n")
+	assert.NoErr("close", l.Close())
+}
+
+func TestNetWriterSpill(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	// find an address nobody listens on
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+
+	nw := DialWriter("tcp", addr, &DialOptions{MinBackoff: time.Hour, SpillSize: 10})
+	_, err := nw.Write([]byte("12345\n"))
+	assert.NoErr("write within spill size", err)
+	_, err = nw.Write([]byte("67890\n"))
+	assert.Ok("spill full", err == ErrSpillFull)
+	assert.Eq("dropped", nw.Dropped(), uint64(1))
+	assert.Err("close with undelivered data", "undelivered", nw.Close())
+	_, err = nw.Write([]byte("x"))
+	assert.Err("write after close", "closed", err)
+}
+
+func TestNetWriterUDP(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoErr("listen", err) {
+		return
+	}
+	defer pc.Close()
+
+	l := NewLogger(DialWriter("udp", pc.LocalAddr().String(), nil), "", LevelInfo, 0)
+	defer l.Close()
+	l.Info("one")
+	l.Info("two")
+
+	buf := make([]byte, 1024)
+	for _, expect := range []string{"one\n", "two\n"} {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if assert.NoErr("read", err) {
+			assert.Eq("datagram", string(buf[:n]), expect)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+// LogOnce logs a message the first time it's called with key. Later calls with the same key
+// do nothing, which is useful for warnings in code that runs often, like deprecation notices.
+// Keys are shared by l, its parent and sub-loggers.
+func (l *Logger) LogOnce(level Level, key string, format string, v ...interface{}) {
+	if l.GetLevel() <= level {
+		if ok, _ := l.q.rate.allow(key, 0, l.now()); ok {
+			l.log(level, format, v...)
+		}
+	}
+}
+
+// LogEvery logs a message at most once per interval for key. Messages which are not logged
+// because of this are counted, and the count is appended to the next message which is:
+//   "cache degraded (41 similar messages suppressed)"
+// Keys are shared by l, its parent and sub-loggers.
+func (l *Logger) LogEvery(level Level, key string, interval time.Duration, format string, v ...interface{}) {
+	if l.GetLevel() > level {
+		return
+	}
+	ok, suppressed := l.q.rate.allow(key, interval, l.now())
+	if !ok {
+		return
+	}
+	m := l.newRecord(level)
+	m.appendf(format, v...)
+	if suppressed > 0 {
+		m.msg = append(m.msg, " ("...)
+		itoa(&m.msg, suppressed, -1)
+		m.msg = append(m.msg, " similar messages suppressed)"...)
+	}
+	l.submit(m)
+}
+
+// InfoOnce logs an info message once per key. See LogOnce
+func (l *Logger) InfoOnce(key string, format string, v ...interface{}) {
+	l.LogOnce(LevelInfo, key, format, v...)
+}
+
+// WarnOnce logs a warning once per key. See LogOnce
+func (l *Logger) WarnOnce(key string, format string, v ...interface{}) {
+	l.LogOnce(LevelWarn, key, format, v...)
+}
+
+// WarnEvery logs a warning at most once per interval for key. See LogEvery
+func (l *Logger) WarnEvery(key string, interval time.Duration, format string, v ...interface{}) {
+	l.LogEvery(LevelWarn, key, interval, format, v...)
+}
+
+// rateLimits holds the state of LogOnce and LogEvery
+type rateLimits struct {
+	mu   sync.Mutex
+	keys map[string]*rateLimit
+}
+
+type rateLimit struct {
+	last       time.Time // when a message was last logged
+	suppressed int
+}
+
+// allow returns true if a message for key may be logged at time now, along with the number
+// of messages suppressed since the last one was logged. An interval of zero means "only once".
+func (r *rateLimits) allow(key string, interval time.Duration, now time.Time) (bool, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys == nil {
+		r.keys = make(map[string]*rateLimit)
+	}
+	rl := r.keys[key]
+	if rl == nil {
+		r.keys[key] = &rateLimit{last: now}
+		return true, 0
+	}
+	if interval <= 0 || now.Sub(rl.last) < interval {
+		rl.suppressed++
+		return false, 0
+	}
+	n := rl.suppressed
+	rl.last = now
+	rl.suppressed = 0
+	return true, n
+}
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestLogOnce(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		l.InfoOnce("a", "a %d", i)
+		l.SubLogger("[sub]").WarnOnce("b", "b %d", i)
+	}
+	l.Sync()
+	assert.Eq("output", w.String(), "a 0\n[sub] b 0\n")
+}
+
+func TestLogEvery(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		l.WarnEvery("k", 20*time.Millisecond, "degraded %d", i)
+	}
+	time.Sleep(30 * time.Millisecond)
+	l.WarnEvery("k", 20*time.Millisecond, "degraded %d", 3)
+	l.WarnEvery("k", 20*time.Millisecond, "degraded %d", 4)
+	l.Sync()
+	assert.Eq("output", w.String(), "degraded 0\ndegraded 3 (2 similar messages suppressed)\n")
+}
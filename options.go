@@ -0,0 +1,172 @@
+package log
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// Options configures a logger created with New
+type Options struct {
+	Writer    io.Writer        // where messages are written; os.Stdout if nil
+	Level     Level            // minimum level of messages logged; the zero value is LevelDebug
+	Features  Features         // e.g. FDefault
+	Prefix    string           // prefix of all messages, e.g. "[server]"
+	QueueSize int              // number of messages queued before logging blocks; 100 if zero
+	Formatter Formatter        // replaces the text format, e.g. FormatJSON; see TeeSink
+	Clock     func() time.Time // time source; time.Now if nil. See Logger.SetClock
+
+	// OnError is called with errors from writing messages, in addition to them being returned
+	// by Sync and Close. It is usually called from the logger's write goroutine. It may log to
+	// the logger; messages which can't be written without waiting for the write goroutine are
+	// written to os.Stderr instead.
+	OnError func(error)
+
+	// Ordered makes messages logged synchronously (see FSync) wait for messages queued before
+	// them to be written first; see Logger.SetOrdered
+	Ordered bool
+
+	// MaxRecordAge makes debug and info messages which have been queued for longer than this
+	// be dropped rather than written; see Logger.SetMaxRecordAge
+	MaxRecordAge time.Duration
+
+	// PriorityWindow makes messages of higher levels be written before queued messages of lower
+	// levels, e.g. errors before a backlog of debug messages when the writer is slow. To not
+	// starve messages of lower levels, at most PriorityWindow messages are written ahead of the
+	// oldest queued message. Messages are never reordered across calls to Sync or a Batch.
+	// Reordering doubles the number of messages which can be queued (see QueueSize), since the
+	// write goroutine takes up to QueueSize messages from the queue to choose from.
+	// Zero (the default) writes messages in the order they were logged.
+	PriorityWindow int
+
+	// RecordIDs gives every message a unique ID; see Logger.SetRecordIDs
+	RecordIDs bool
+
+	// WAL is the path of a file to which queued messages are appended before they are queued,
+	// e.g. for billing or audit events which must not be lost. If the process crashes or exits
+	// without closing the logger, messages which were not written yet are written by New the
+	// next time a logger is created with the same WAL, before any other messages.
+	//
+	// Messages are removed from the file once written: all of them whenever none are pending,
+	// and otherwise the written messages at the start of the file, once they take up at least
+	// 1 MiB and half of the file. After a crash, messages which were written but not removed
+	// yet are written again: up to about 1 MiB of messages, plus those written while a message
+	// logged before them was still pending (e.g. because of PriorityWindow).
+	//
+	// The WAL is not synced to stable storage for every message; it protects against the
+	// process crashing, not against the OS crashing. Messages logged synchronously (see FSync)
+	// are not appended to the WAL since they are written before the logging call returns.
+	// The WAL must not be shared by several loggers at once. If it can't be opened, the error
+	// is passed to OnError and the logger works without it.
+	WAL string
+
+	// Shards splits the queue into several channels of QueueSize messages each. A logger and
+	// its sub-loggers share one queue, from which one goroutine writes messages in the order
+	// they were logged. When hundreds of goroutines log at a high rate on many CPU cores, they
+	// contend for the lock of the queue's channel. With Shards > 1, they send messages on the
+	// channels in turn, each taking a sequence number by which the write goroutine receives
+	// them in order, so messages are still written in the order they were logged.
+	// Sharding adds a little overhead per message, so whether it pays off depends on the number
+	// of cores and goroutines; compare with BenchmarkThroughput. Zero or one disables sharding.
+	Shards int
+}
+
+// Option changes Options; see NewWith
+type Option func(*Options)
+
+// New makes a new logger configured by opts. For example:
+//
+//   logger := log.New(log.Options{
+//     Writer:    f,
+//     Level:     log.LevelInfo,
+//     Features:  log.FDate | log.FTime | log.FPrefixWarn | log.FPrefixError,
+//     QueueSize: 1000,
+//     OnError:   func(err error) { fmt.Fprintln(os.Stderr, "logging failed:", err) },
+//   })
+//
+// Since the zero Level is LevelDebug, a logger made without setting Options.Level logs debug
+// messages. NewWith defaults to LevelInfo instead.
+func New(opts Options) *Logger {
+	w := opts.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+	l := &Logger{
+		Level:     opts.Level,
+		Features:  opts.Features,
+		Prefix:    opts.Prefix,
+		q:         newQueue(queueSize, opts.Shards),
+		metrics:   new(metrics),
+		clock:     opts.Clock,
+		formatter: opts.Formatter,
+	}
+	l.q.onError = opts.OnError
+	if opts.Ordered {
+		l.q.ordered = 1
+	}
+	l.q.maxAge = int64(opts.MaxRecordAge)
+	l.q.priority = opts.PriorityWindow
+	if opts.RecordIDs {
+		l.q.ids = 1
+	}
+	var replay []*Record
+	if opts.WAL != "" {
+		var err error
+		if l.q.wal, replay, err = openWAL(opts.WAL); err != nil {
+			l.q.writeFailed(err)
+		}
+	}
+	l.w.Store(newWriterRef(w))
+	openQueues.add(l)
+	l.RefreshAutoFeatures()
+	go l.writeLoop()
+	l.replayWAL(replay)
+	return l
+}
+
+// NewWith makes a new logger configured by options, which are applied in order to
+// Options{Level: LevelInfo, Features: FDefault}. For example:
+//
+//   logger := log.NewWith(log.WithWriter(f), log.WithLevel(log.LevelWarn))
+//
+func NewWith(options ...Option) *Logger {
+	opts := Options{Level: LevelInfo, Features: FDefault}
+	for _, o := range options {
+		o(&opts)
+	}
+	return New(opts)
+}
+
+// WithWriter sets Options.Writer
+func WithWriter(w io.Writer) Option { return func(o *Options) { o.Writer = w } }
+
+// WithLevel sets Options.Level
+func WithLevel(level Level) Option { return func(o *Options) { o.Level = level } }
+
+// WithFeatures sets Options.Features
+func WithFeatures(feats Features) Option { return func(o *Options) { o.Features = feats } }
+
+// WithPrefix sets Options.Prefix
+func WithPrefix(prefix string) Option { return func(o *Options) { o.Prefix = prefix } }
+
+// WithQueueSize sets Options.QueueSize
+func WithQueueSize(n int) Option { return func(o *Options) { o.QueueSize = n } }
+
+// WithShards sets Options.Shards
+func WithShards(n int) Option { return func(o *Options) { o.Shards = n } }
+
+// WithOrdered sets Options.Ordered
+func WithOrdered(ordered bool) Option { return func(o *Options) { o.Ordered = ordered } }
+
+// WithFormatter sets Options.Formatter
+func WithFormatter(f Formatter) Option { return func(o *Options) { o.Formatter = f } }
+
+// WithClock sets Options.Clock
+func WithClock(now func() time.Time) Option { return func(o *Options) { o.Clock = now } }
+
+// WithErrorHandler sets Options.OnError
+func WithErrorHandler(f func(error)) Option { return func(o *Options) { o.OnError = f } }
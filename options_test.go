@@ -0,0 +1,67 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+type errorWriter struct{ err error }
+
+func (w errorWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func TestNew(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	l := New(Options{
+		Writer:    w,
+		Level:     LevelInfo,
+		Prefix:    "[p]",
+		QueueSize: 10,
+		Formatter: FormatJSON,
+		Clock:     func() time.Time { return now },
+	})
+	defer l.Close()
+	assert.Eq("queue size", cap(l.q.ch), 10)
+
+	l.Debug("not logged")
+	l.SubLogger("[sub]").Info("hello")
+	l.Sync()
+	assert.Eq("output", w.String(),
+		`{"level":"info","time":"2006-01-02T15:04:05Z","prefix":"[p][sub]","msg":"hello"}`+"\n")
+
+	// OnError
+	var errs []error
+	werr := errors.New("disk full")
+	l2 := NewWith(WithWriter(errorWriter{werr}), WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+	defer l2.Close()
+	assert.Eq("features", l2.GetFeatures()&^FColor, FDefault)
+	assert.Eq("NewWith level", l2.Level, LevelInfo)
+	l2.Info("a")
+	l2.Info("b")
+	assert.Eq("sync error", l2.Sync(), werr)
+	assert.Eq("errors", len(errs), 2)
+}
+
+func TestNewDefaultLevel(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := New(Options{Writer: w})
+	assert.Eq("New level", l.Level, LevelDebug)
+	l.Debug("a")
+	assert.NoErr("close", l.Close())
+	assert.Eq("New logs debug", w.String(), "a\n")
+
+	w.Reset()
+	l = NewWith(WithWriter(w), WithFeatures(0))
+	l.Debug("b")
+	l.Info("c")
+	assert.NoErr("close", l.Close())
+	assert.Eq("NewWith doesn't log debug", w.String(), "c\n")
+}
@@ -0,0 +1,145 @@
+package log
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// OriginFormat controls how the source location of debug messages is rendered when
+// FDebugOrigin is enabled; see Logger.SetOriginFormat
+type OriginFormat int
+
+const (
+	OriginFile   OriginFormat = iota // "dir/file.go:123", relative to the working directory
+	OriginFunc                       // "pkg.Func file.go:123"
+	OriginModule                     // "dir/file.go:123", relative to the file's module directory
+)
+
+// SetOriginFormat sets how the source location of debug messages is rendered when FDebugOrigin
+// is enabled. The default, OriginFile, renders a file path relative to the working directory of
+// the process at the time the first origin is recorded, or the file's parent directory and name
+// if the file is outside of the working directory.
+//
+// OriginModule renders a file path relative to the directory of the Go module which contains the
+// file (the closest parent directory with a go.mod file), which doesn't depend on the working
+// directory. This requires the source files to be present where the program runs, as is usually
+// the case during development; otherwise the file's parent directory and name are rendered.
+//
+// OriginFunc renders the function and file name instead:
+//
+//   logger.SetOriginFormat(log.OriginFunc)
+//   logger.Debug("cache miss") // "[debug] cache miss (server/cache.(*LRU).Get lru.go:82)"
+//
+// Function names are qualified by the import path of their package, with the path of the
+// package's module trimmed according to the build info of the program. Unlike file paths,
+// these don't depend on where the program was built or which directory it runs in.
+// Sub-loggers created after the call inherit the format.
+func (l *Logger) SetOriginFormat(format OriginFormat) {
+	l.originFormat = format
+}
+
+// moduleRoots maps source directories to the directory of the module which contains them,
+// or "" if not found
+var moduleRoots struct {
+	sync.Mutex
+	m map[string]string
+}
+
+// moduleSrcFilename returns file relative to the directory of its module; see OriginModule
+func moduleSrcFilename(file string) string {
+	dir := path.Dir(file)
+	moduleRoots.Lock()
+	root, ok := moduleRoots.m[dir]
+	if !ok {
+		root = findModuleRoot(dir)
+		if moduleRoots.m == nil {
+			moduleRoots.m = make(map[string]string)
+		}
+		moduleRoots.m[dir] = root
+	}
+	moduleRoots.Unlock()
+	if root == "" {
+		return shortSrcFilename(file)
+	}
+	return strings.TrimPrefix(file[len(root):], "/")
+}
+
+// findModuleRoot returns the closest parent directory of dir (or dir itself) which contains a
+// go.mod file, or "" if there is none
+func findModuleRoot(dir string) string {
+	for {
+		if fi, err := os.Stat(filepath.Join(filepath.FromSlash(dir), "go.mod")); err == nil &&
+			!fi.IsDir() {
+			return dir
+		}
+		parent := path.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// appendFuncOrigin records the function, file name and line of the caller in m.origin,
+// like "pkg.Func file.go:123". calldepth is like for runtime.Caller.
+func (m *logRecord) appendFuncOrigin(calldepth int) {
+	var pc [1]uintptr
+	if runtime.Callers(calldepth+2, pc[:]) == 0 {
+		m.origin = append(m.origin[:0], "???:0"...)
+		return
+	}
+	frame, _ := runtime.CallersFrames(pc[:]).Next()
+	m.origin = append(m.origin[:0], trimModulePath(frame.Function, buildModules())...)
+	m.origin = append(m.origin, ' ')
+	m.origin = append(m.origin, path.Base(frame.File)...)
+	m.origin = append(m.origin, ':')
+	itoa(&m.origin, frame.Line, -1)
+}
+
+// trimModulePath trims the longest of modules which prefixes the package path of the qualified
+// function name fn, e.g. "example.com/app/server.Run" -> "server.Run" for module
+// "example.com/app". Functions of a module's root package keep the last element of the module
+// path, e.g. "app.main".
+func trimModulePath(fn string, modules []string) string {
+	best := ""
+	for _, mod := range modules {
+		if len(mod) > len(best) && strings.HasPrefix(fn, mod) && len(fn) > len(mod) &&
+			(fn[len(mod)] == '/' || fn[len(mod)] == '.') {
+			best = mod
+		}
+	}
+	if best == "" {
+		return fn
+	}
+	if fn[len(best)] == '/' {
+		return fn[len(best)+1:]
+	}
+	return path.Base(best) + fn[len(best):]
+}
+
+var buildModulesOnce struct {
+	sync.Once
+	modules []string
+}
+
+// buildModules returns the module paths of the program's main module and its dependencies
+func buildModules() []string {
+	buildModulesOnce.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		if info.Main.Path != "" {
+			buildModulesOnce.modules = append(buildModulesOnce.modules, info.Main.Path)
+		}
+		for _, dep := range info.Deps {
+			buildModulesOnce.modules = append(buildModulesOnce.modules, dep.Path)
+		}
+	})
+	return buildModulesOnce.modules
+}
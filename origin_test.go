@@ -0,0 +1,75 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestOriginFunc(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelDebug, FDebugOrigin)
+	defer l.Close()
+	l.SetOriginFormat(OriginFunc)
+	l.Debug("a")
+	l.SubLogger("[sub]").Debug("b")
+	l.Sync()
+	assert.Ok("output", regexp.MustCompile(
+		`^a \(\S*log\.TestOriginFunc origin_test\.go:\d+\)\n`+
+			`\[sub\] b \(\S*log\.TestOriginFunc origin_test\.go:\d+\)\n$`,
+	).MatchString(w.String()))
+}
+
+func TestTrimModulePath(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	modules := []string{"example.com/app", "example.com/app/sub", "example.com/lib"}
+	assert.Eq("package", trimModulePath("example.com/app/server.Run", modules), "server.Run")
+	assert.Eq("nested module", trimModulePath("example.com/app/sub/x.F", modules), "x.F")
+	assert.Eq("root package", trimModulePath("example.com/app.main", modules), "app.main")
+	assert.Eq("method", trimModulePath("example.com/lib/c.(*T).M", modules), "c.(*T).M")
+	assert.Eq("other", trimModulePath("example.com/application.F", modules),
+		"example.com/application.F")
+	assert.Eq("std", trimModulePath("net/http.(*Server).Serve", modules),
+		"net/http.(*Server).Serve")
+}
+
+func TestOriginModule(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(10)
+	l := NewLogger(sink, "", LevelDebug, FDebugOrigin)
+	defer l.Close()
+	l.Debug("a")
+
+	// origins don't change when the working directory changes
+	wd, err := os.Getwd()
+	assert.NoErr("getwd", err)
+	assert.NoErr("chdir", os.Chdir(os.TempDir()))
+	defer os.Chdir(wd)
+	l.Debug("b")
+	l.SetOriginFormat(OriginModule)
+	l.Debug("c")
+	l.Sync()
+	r := sink.Records()
+	assert.Ok("a", regexp.MustCompile(`^origin_test\.go:\d+$`).MatchString(r[0].Caller))
+	assert.Ok("b", regexp.MustCompile(`^origin_test\.go:\d+$`).MatchString(r[1].Caller))
+	assert.Ok("c", regexp.MustCompile(`^origin_test\.go:\d+$`).MatchString(r[2].Caller))
+}
+
+func TestFindModuleRoot(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir, err := ioutil.TempDir("", "modroot")
+	assert.NoErr("tempdir", err)
+	defer os.RemoveAll(dir)
+	assert.NoErr("mkdir", os.MkdirAll(filepath.Join(dir, "a", "b"), 0755))
+	assert.NoErr("go.mod", ioutil.WriteFile(filepath.Join(dir, "a", "go.mod"), nil, 0644))
+	root := filepath.ToSlash(dir)
+	assert.Eq("parent", findModuleRoot(root+"/a/b"), root+"/a")
+	assert.Eq("self", findModuleRoot(root+"/a"), root+"/a")
+	assert.Eq("file", moduleSrcFilename(root+"/a/b/c.go"), "b/c.go")
+}
@@ -0,0 +1,332 @@
+package log
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLPOptions configures an OTLPExporter. The zero value of each field selects its default.
+type OTLPOptions struct {
+	ServiceName   string            // "service.name" resource attribute
+	Resource      map[string]string // additional resource attributes
+	Headers       map[string]string // HTTP headers, e.g. for authentication
+	Client        *http.Client      // defaults to a client with a 10s timeout
+	BatchSize     int               // max records per request (default 512)
+	FlushInterval time.Duration     // max time a record waits before being sent (default 5s)
+	MaxQueue      int               // max records waiting to be sent (default 8192)
+}
+
+// OTLPExporter sends records to an OpenTelemetry collector using OTLP/HTTP with JSON encoding.
+// Records are sent in batches from a background goroutine. Messages logged with a context
+// (see LogContext) carry the trace and span IDs of that context and are thus correlated with
+// traces in OpenTelemetry backends.
+//
+//   exp := log.NewOTLPExporter("http://localhost:4318/v1/logs", &log.OTLPOptions{
+//     ServiceName: "myservice",
+//   })
+//   logger.SetWriter(exp)
+//
+// When the queue is full, because the collector is slow or unreachable, the oldest records
+// are dropped.
+type OTLPExporter struct {
+	endpoint string
+	opts     OTLPOptions
+	resource []otlpKeyValue
+
+	mu      sync.Mutex
+	queue   []Record
+	dropped uint64
+	err     error         // last export error
+	wakeup  chan struct{} // signals the run goroutine
+	flushch chan chan error
+	closed  bool
+	done    chan struct{}
+	lastErr error // error of the export when closing
+}
+
+// NewOTLPExporter creates an exporter posting to endpoint, which is usually
+// "http://{collector}:4318/v1/logs". opts may be nil.
+func NewOTLPExporter(endpoint string, opts *OTLPOptions) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint: endpoint,
+		wakeup:   make(chan struct{}, 1),
+		flushch:  make(chan chan error),
+		done:     make(chan struct{}),
+	}
+	if opts != nil {
+		e.opts = *opts
+	}
+	if e.opts.Client == nil {
+		e.opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if e.opts.BatchSize <= 0 {
+		e.opts.BatchSize = 512
+	}
+	if e.opts.FlushInterval <= 0 {
+		e.opts.FlushInterval = 5 * time.Second
+	}
+	if e.opts.MaxQueue < e.opts.BatchSize {
+		e.opts.MaxQueue = 8192
+		if e.opts.MaxQueue < e.opts.BatchSize {
+			e.opts.MaxQueue = e.opts.BatchSize
+		}
+	}
+	if e.opts.ServiceName != "" {
+		e.resource = append(e.resource, otlpString("service.name", e.opts.ServiceName))
+	}
+	for k, v := range e.opts.Resource {
+		e.resource = append(e.resource, otlpString(k, v))
+	}
+	go e.run()
+	return e
+}
+
+// Write exports each line of p as a LevelInfo record
+func (e *OTLPExporter) Write(p []byte) (int, error) {
+	now := time.Now()
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		e.WriteRecord(&Record{Level: LevelInfo, Time: now, Msg: line})
+	}
+	return len(p), nil
+}
+
+func (e *OTLPExporter) writeRecord(m *logRecord) error {
+	r := m.record()
+	return e.WriteRecord(&r)
+}
+
+// WriteRecord queues r for exporting. Returns the error of the last failed export, if any.
+func (e *OTLPExporter) WriteRecord(r *Record) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return fmt.Errorf("log: OTLP exporter closed")
+	}
+	if len(e.queue) >= e.opts.MaxQueue {
+		copy(e.queue, e.queue[1:])
+		e.queue = e.queue[:len(e.queue)-1]
+		e.dropped++
+	}
+	e.queue = append(e.queue, *r)
+	if len(e.queue) >= e.opts.BatchSize {
+		e.signal()
+	}
+	err := e.err
+	e.err = nil
+	e.mu.Unlock()
+	return err
+}
+
+// Dropped returns the number of records dropped because the queue was full
+func (e *OTLPExporter) Dropped() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dropped
+}
+
+// Flush exports all queued records and returns the first error encountered
+func (e *OTLPExporter) Flush() error {
+	ch := make(chan error)
+	select {
+	case e.flushch <- ch:
+		return <-ch
+	case <-e.done:
+		return nil
+	}
+}
+
+// Close exports all queued records and stops the exporter
+func (e *OTLPExporter) Close() error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.wakeup)
+	}
+	e.mu.Unlock()
+	<-e.done
+	return e.lastErr
+}
+
+// signal wakes up run without blocking. e.mu must be held, since Close closes e.wakeup.
+func (e *OTLPExporter) signal() {
+	select {
+	case e.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case _, ok := <-e.wakeup:
+			if !ok {
+				e.lastErr = e.export(true)
+				return
+			}
+			e.export(false)
+		case <-ticker.C:
+			e.export(true)
+		case ch := <-e.flushch:
+			ch <- e.export(true)
+		}
+	}
+}
+
+// export sends queued records in batches. Unless all is true, only full batches are sent.
+func (e *OTLPExporter) export(all bool) error {
+	var firstErr error
+	for {
+		e.mu.Lock()
+		n := len(e.queue)
+		if n == 0 || (!all && n < e.opts.BatchSize) {
+			e.mu.Unlock()
+			return firstErr
+		}
+		if n > e.opts.BatchSize {
+			n = e.opts.BatchSize
+		}
+		batch := make([]Record, n)
+		copy(batch, e.queue)
+		e.queue = e.queue[:copy(e.queue, e.queue[n:])]
+		e.mu.Unlock()
+
+		if err := e.post(batch); err != nil {
+			e.mu.Lock()
+			e.err = err
+			e.mu.Unlock()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+}
+
+func (e *OTLPExporter) post(batch []Record) error {
+	records := make([]otlpLogRecord, len(batch))
+	for i := range batch {
+		records[i] = otlpRecord(&batch[i])
+	}
+	var req otlpRequest
+	req.ResourceLogs = []otlpResourceLogs{{
+		Resource: otlpResource{Attributes: e.resource},
+		ScopeLogs: []otlpScopeLogs{{
+			Scope:      otlpScope{Name: "github.com/rsms/go-log"},
+			LogRecords: records,
+		}},
+	}}
+	body, err := json.Marshal(&req)
+	if err != nil {
+		return err
+	}
+	hreq, err := http.NewRequest("POST", e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.Headers {
+		hreq.Header.Set(k, v)
+	}
+	res, err := e.opts.Client.Do(hreq)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("log: OTLP export to %s failed: %s", e.endpoint, res.Status)
+	}
+	return nil
+}
+
+// OTLP/JSON data model; see https://github.com/open-telemetry/opentelemetry-proto
+
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpAnyValue   `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID        string         `json:"traceId,omitempty"`
+	SpanID         string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{key, otlpAnyValue{value}}
+}
+
+// otlpSeverity maps levels to OpenTelemetry severity numbers
+var otlpSeverity = [...]int{
+	LevelDebug:   5,  // DEBUG
+	LevelInfo:    9,  // INFO
+	LevelWarn:    13, // WARN
+	LevelError:   17, // ERROR
+	LevelDisable: 9,
+	levelTime:    9,
+}
+
+func otlpRecord(r *Record) otlpLogRecord {
+	lr := otlpLogRecord{
+		TimeUnixNano:   strconv.FormatInt(r.Time.UnixNano(), 10),
+		SeverityNumber: 9,
+		SeverityText:   strings.ToUpper(r.Level.String()),
+		Body:           otlpAnyValue{strings.TrimSuffix(r.Msg, "\n")},
+	}
+	if r.Level >= 0 && int(r.Level) < len(otlpSeverity) {
+		lr.SeverityNumber = otlpSeverity[r.Level]
+	}
+	if r.Prefix != "" {
+		lr.Attributes = append(lr.Attributes, otlpString("log.prefix", r.Prefix))
+	}
+	if len(r.Scope) > 0 {
+		lr.Attributes = append(lr.Attributes, otlpString("log.scope", strings.Join(r.Scope, ">")))
+	}
+	for _, f := range sortedFields(r.Fields) {
+		lr.Attributes = append(lr.Attributes, otlpString(f.Key, f.Value))
+	}
+	if r.TraceID != "" {
+		if _, err := hex.DecodeString(r.TraceID); err == nil {
+			lr.TraceID, lr.SpanID = r.TraceID, r.SpanID
+		}
+	}
+	return lr
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var mu sync.Mutex
+	var requests []otlpRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		requests = append(requests, req)
+		auth = r.Header.Get("Authorization")
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	exp := NewOTLPExporter(srv.URL+"/v1/logs", &OTLPOptions{
+		ServiceName: "test",
+		Headers:     map[string]string{"Authorization": "Bearer x"},
+		BatchSize:   2,
+	})
+	l := NewLogger(exp, "[p]", LevelDebug, FDefault)
+
+	tc := TraceContext{SpanID: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+	tc.TraceID[15] = 0xff
+	ctx := ContextWithTrace(context.Background(), tc)
+
+	l.WarnContext(ctx, "traced %d", 1)
+	l.Info("not traced")
+	l.Error("third")
+	assert.NoErr("close", l.Close()) // closes exporter
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Eq("requests", len(requests), 2) // batch of 2 + final flush of 1
+	assert.Eq("auth header", auth, "Bearer x")
+	var records []otlpLogRecord
+	for _, req := range requests {
+		rl := req.ResourceLogs[0]
+		assert.Eq("service.name", rl.Resource.Attributes[0], otlpString("service.name", "test"))
+		records = append(records, rl.ScopeLogs[0].LogRecords...)
+	}
+	if !assert.Eq("records", len(records), 3) {
+		return
+	}
+	r := records[0]
+	assert.Eq("body", r.Body.StringValue, "traced 1")
+	assert.Eq("severity", r.SeverityNumber, 13)
+	assert.Eq("severity text", r.SeverityText, "WARN")
+	assert.Eq("trace id", r.TraceID, "000000000000000000000000000000ff")
+	assert.Eq("span id", r.SpanID, "0102030405060708")
+	assert.Eq("prefix attribute", r.Attributes[0], otlpString("log.prefix", "[p]"))
+	assert.Eq("untraced", records[1].TraceID, "")
+	assert.Eq("error severity", records[2].SeverityNumber, 17)
+}
+
+func TestOTLPExporterWriteClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	for i := 0; i < 10; i++ {
+		exp := NewOTLPExporter(srv.URL+"/v1/logs", &OTLPOptions{BatchSize: 1})
+		// writes racing with Close must not send on the closed wakeup channel
+		var wg, started sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			started.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					exp.WriteRecord(&Record{Level: LevelInfo, Msg: "x"})
+					if i == 0 {
+						started.Done()
+					}
+				}
+			}()
+		}
+		started.Wait()
+		exp.Close()
+		wg.Wait()
+	}
+}
+
+func TestTraceExtractor(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	defer SetTraceExtractor(nil)
+	var tc TraceContext
+	tc.TraceID[0] = 1
+	SetTraceExtractor(func(ctx context.Context) TraceContext { return tc })
+	assert.Eq("custom extractor", TraceFromContext(context.Background()), tc)
+
+	SetTraceExtractor(nil)
+	assert.Ok("default extractor", !TraceFromContext(context.Background()).IsValid())
+	assert.Eq("ContextWithTrace", TraceFromContext(ContextWithTrace(context.Background(), tc)), tc)
+
+	sink := NewMemorySink(10)
+	l := NewLogger(sink, "", LevelDebug, FDefault)
+	defer l.Close()
+	l.InfoContext(ContextWithTrace(context.Background(), tc), "hello")
+	l.DebugContext(context.Background(), "debug")
+	l.Sync()
+	records := sink.Records()
+	assert.Eq("record trace id", records[0].TraceID, "01000000000000000000000000000000")
+	assert.Eq("debug msg", records[1].Msg, "debug")
+	assert.Ok("debug origin", strings.Contains(records[1].Caller, "otlp_test.go:"))
+}
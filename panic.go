@@ -0,0 +1,70 @@
+package log
+
+import (
+	"runtime/debug"
+)
+
+// HandlePanic logs a panic, including a stack trace, with RootLogger, waits for all messages
+// to be written and then continues panicking. Must be called directly by defer:
+//
+//   func main() {
+//     defer log.HandlePanic()
+//     ...
+//   }
+//
+func HandlePanic() {
+	if r := recover(); r != nil {
+		RootLogger.logPanic(r, debug.Stack())
+		panic(r)
+	}
+}
+
+// RecoverAndLog is like HandlePanic but recovers from the panic instead of continuing it.
+// Must be called directly by defer.
+func RecoverAndLog() {
+	if r := recover(); r != nil {
+		RootLogger.logPanic(r, debug.Stack())
+	}
+}
+
+// Go runs f in a new goroutine which logs any panic with RootLogger. See Logger.Go
+func Go(f func()) { RootLogger.Go(f) }
+
+// HandlePanic logs a panic, including a stack trace, waits for all messages to be written
+// and then continues panicking. Must be called directly by defer:
+//
+//   defer logger.HandlePanic()
+//
+// Since messages are written asynchronously, messages logged right before a program crashes
+// are otherwise easily lost.
+func (l *Logger) HandlePanic() {
+	if r := recover(); r != nil {
+		l.logPanic(r, debug.Stack())
+		panic(r)
+	}
+}
+
+// RecoverAndLog is like HandlePanic but recovers from the panic instead of continuing it.
+// Must be called directly by defer.
+func (l *Logger) RecoverAndLog() {
+	if r := recover(); r != nil {
+		l.logPanic(r, debug.Stack())
+	}
+}
+
+// Go runs f in a new goroutine, like the go statement, with HandlePanic installed.
+// This makes sure that a panic in f is logged and that earlier messages are written before
+// the program crashes.
+func (l *Logger) Go(f func()) {
+	go func() {
+		defer l.HandlePanic()
+		f()
+	}()
+}
+
+func (l *Logger) logPanic(r interface{}, stack []byte) {
+	if l.GetLevel() <= LevelError {
+		l.log(LevelError, "panic: %v\n%s", r, stack)
+	}
+	l.Sync()
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestHandlePanic(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixError)
+	defer l.Close()
+
+	assert.Panic("boom", func() {
+		defer l.HandlePanic()
+		l.Info("before panic")
+		panic("boom")
+	})
+	// no Sync needed; HandlePanic syncs
+	out := w.String()
+	assert.Ok("message before panic written; got %q", strings.HasPrefix(out, "before panic\n"), out)
+	assert.Ok("panic value logged; got %q", strings.Contains(out, "[error] panic: boom\n"), out)
+	assert.Ok("stack trace logged; got %q", strings.Contains(out, "TestHandlePanic"), out)
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixError)
+	defer l.Close()
+
+	func() {
+		defer l.RecoverAndLog()
+		panic("boom")
+	}()
+	assert.Ok("panic logged", strings.HasPrefix(w.String(), "[error] panic: boom\n"))
+}
+
+func TestGo(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+
+	done := make(chan struct{})
+	l.Go(func() {
+		l.Info("in goroutine")
+		close(done)
+	})
+	<-done
+	l.Sync()
+	assert.Eq("output", w.String(), "in goroutine\n")
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"container/list"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Partitioner routes output to separate sinks by key, for example to keep the logs of each
+// customer of a multi-tenant service in separate files. Sinks are opened lazily on first write
+// and at most maxOpen sinks are kept open; the least recently used sink is closed when the
+// limit is reached and is opened again when written to.
+//
+// Records are routed by a key taken from each record, like the value of a field, with Router:
+//
+//   p := log.NewPartitioner(log.PartitionFiles("/var/log/tenants"), 100)
+//   logger.SetWriter(p.Router(log.PartitionByField("tenant"), os.Stderr))
+//   ...
+//   logger.WithFields("tenant", tenantID).Info("hello") // to /var/log/tenants/{tenantID}.log
+//
+// Alternatively, Writer returns the writer of a fixed key, e.g. for a logger per tenant:
+//
+//   tenantLogger := logger.SubLogger("[" + tenantID + "]")
+//   tenantLogger.SetWriter(p.Writer(tenantID))
+//
+type Partitioner struct {
+	open    func(key string) (io.WriteCloser, error)
+	maxOpen int
+
+	mu    sync.Mutex
+	sinks map[string]*list.Element // values are *partitionSink
+	lru   list.List                // most recently used at the front
+}
+
+type partitionSink struct {
+	key string
+	w   io.WriteCloser
+}
+
+// NewPartitioner creates a Partitioner which calls open to create the sink for a key.
+// A maxOpen of zero or less means there is no limit on the number of open sinks.
+func NewPartitioner(open func(key string) (io.WriteCloser, error), maxOpen int) *Partitioner {
+	return &Partitioner{
+		open:    open,
+		maxOpen: maxOpen,
+		sinks:   make(map[string]*list.Element),
+	}
+}
+
+// PartitionFiles returns a function for NewPartitioner which opens (or creates) the file
+// dir/{key}.log for appending. Keys which are not valid file names are rejected.
+func PartitionFiles(dir string) func(key string) (io.WriteCloser, error) {
+	return func(key string) (io.WriteCloser, error) {
+		if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+			return nil, errors.New("invalid partition key " + key)
+		}
+		filename := filepath.Join(dir, key+".log")
+		return os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	}
+}
+
+// Writer returns a writer which writes to the sink of key
+func (p *Partitioner) Writer(key string) io.Writer {
+	return &partitionWriter{p, key}
+}
+
+// Router returns a writer which writes each record to the sink of the key returned by key for
+// the record (see PartitionByField and PartitionByPrefix). Records for which key returns ""
+// and output written with Write are written to fallback, or discarded if fallback is nil.
+// Closing the writer, e.g. by closing a logger which uses it, closes p.
+func (p *Partitioner) Router(key func(r *Record) string, fallback io.Writer) io.Writer {
+	return &partitionRouter{p, key, fallback}
+}
+
+// PartitionByField returns a key function for Partitioner.Router which returns the value of
+// the field name of a record
+func PartitionByField(name string) func(r *Record) string {
+	return func(r *Record) string { return r.Fields[name] }
+}
+
+// PartitionByPrefix returns a key function for Partitioner.Router which returns the last
+// bracketed component of the prefix of a record, e.g. "acme" for "[api][acme]". This suits
+// sub-loggers created with SubLogger("[" + key + "]").
+func PartitionByPrefix() func(r *Record) string {
+	return func(r *Record) string {
+		prefix := strings.TrimSpace(r.Prefix)
+		if !strings.HasSuffix(prefix, "]") {
+			return ""
+		}
+		start := strings.LastIndexByte(prefix, '[')
+		if start == -1 {
+			return ""
+		}
+		return prefix[start+1 : len(prefix)-1]
+	}
+}
+
+// Close closes all open sinks. The Partitioner can still be used after Close; sinks are
+// opened again as needed.
+func (p *Partitioner) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		if e2 := e.Value.(*partitionSink).w.Close(); e2 != nil && err == nil {
+			err = e2
+		}
+	}
+	p.lru.Init()
+	p.sinks = make(map[string]*list.Element)
+	return err
+}
+
+func (p *Partitioner) write(key string, b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.sinks[key]
+	if e != nil {
+		p.lru.MoveToFront(e)
+	} else {
+		w, err := p.open(key)
+		if err != nil {
+			return 0, err
+		}
+		if p.maxOpen > 0 && p.lru.Len() >= p.maxOpen {
+			oldest := p.lru.Remove(p.lru.Back()).(*partitionSink)
+			delete(p.sinks, oldest.key)
+			oldest.w.Close()
+		}
+		e = p.lru.PushFront(&partitionSink{key, w})
+		p.sinks[key] = e
+	}
+	return e.Value.(*partitionSink).w.Write(b)
+}
+
+type partitionWriter struct {
+	p   *Partitioner
+	key string
+}
+
+func (w *partitionWriter) Write(b []byte) (int, error) {
+	return w.p.write(w.key, b)
+}
+
+type partitionRouter struct {
+	p        *Partitioner
+	key      func(r *Record) string
+	fallback io.Writer
+}
+
+func (w *partitionRouter) writeRecord(m *logRecord) error {
+	r := m.record()
+	key := w.key(&r)
+	if key == "" {
+		if w.fallback == nil {
+			return nil
+		}
+		if rw, ok := w.fallback.(recordWriter); ok {
+			return rw.writeRecord(m)
+		}
+	}
+	b := getBuffer()
+	m.format(&b.B)
+	var err error
+	if key == "" {
+		_, err = writeBuffer(w.fallback, b)
+	} else {
+		_, err = w.p.write(key, b.B)
+		b.Release()
+	}
+	return err
+}
+
+func (w *partitionRouter) Write(b []byte) (int, error) {
+	if w.fallback == nil {
+		return len(b), nil
+	}
+	return w.fallback.Write(b)
+}
+
+func (w *partitionRouter) Close() error {
+	return w.p.Close()
+}
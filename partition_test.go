@@ -0,0 +1,78 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestPartitioner(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir := t.TempDir()
+	p := NewPartitioner(PartitionFiles(dir), 2)
+	l := NewLogger(ioutil.Discard, "", LevelInfo, 0)
+	defer l.Close()
+
+	tenant := func(key string) *Logger {
+		sub := l.SubLogger("[" + key + "]")
+		sub.SetWriter(p.Writer(key))
+		return sub
+	}
+	a, b, c := tenant("a"), tenant("b"), tenant("c")
+	a.Info("1")
+	b.Info("2")
+	c.Info("3") // closes a
+	a.Info("4") // reopens a, closes b
+	l.Sync()
+	assert.Eq("open sinks", p.lru.Len(), 2)
+	assert.NoErr("close", p.Close())
+	assert.Eq("open sinks after close", p.lru.Len(), 0)
+
+	for key, expect := range map[string]string{
+		"a": "[a] 1\n[a] 4\n",
+		"b": "[b] 2\n",
+		"c": "[c] 3\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, key+".log"))
+		if assert.NoErr("read %s.log", err, key) {
+			assert.Eq("%s.log", string(data), expect, key)
+		}
+	}
+
+	// invalid keys are rejected
+	_, err := p.Writer("../x").Write([]byte("x"))
+	assert.Err("invalid key", "invalid partition key", err)
+}
+
+func TestPartitionRouter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir := t.TempDir()
+	p := NewPartitioner(PartitionFiles(dir), 0)
+	var fallback bytes.Buffer
+	l := NewLogger(p.Router(PartitionByField("tenant"), &fallback), "", LevelInfo, 0)
+	l.WithFields("tenant", "a").Info("1")
+	l.WithFields("tenant", "b").Info("2")
+	l.Info("3")
+	assert.NoErr("close", l.Close())
+	assert.Eq("sinks closed with logger", p.lru.Len(), 0)
+	assert.Eq("fallback", fallback.String(), "3\n")
+
+	l = NewLogger(p.Router(PartitionByPrefix(), nil), "[api]", LevelInfo, 0)
+	l.SubLogger("[a]").Info("4")
+	l.Info("no key")
+	assert.NoErr("close", l.Close())
+
+	for key, expect := range map[string]string{
+		"a":   "1 tenant=a\n[api][a] 4\n",
+		"b":   "2 tenant=b\n",
+		"api": "[api] no key\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, key+".log"))
+		if assert.NoErr("read %s.log", err, key) {
+			assert.Eq("%s.log", string(data), expect, key)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prettify reads records in JSON (as written by FormatJSON) or logfmt
+// ("time=... level=warn msg=\"...\" key=value") from src, one per line, and writes them to dst
+// in the human-readable format of a logger with features feats. Lines which are neither are
+// copied as-is. FColorAuto in feats enables colors if dst is a terminal.
+//
+// This makes structured production logs comfortable to read:
+//
+//   kubectl logs -f myapp | logpretty
+//
+// See the cmd/logpretty program.
+func Prettify(dst io.Writer, src io.Reader, feats Features) error {
+	if feats&FColorAuto != 0 {
+		feats = featuresWithAutoColor(dst, feats&^FColor)
+	}
+	br := bufio.NewReader(src)
+	bw := bufio.NewWriter(dst)
+	var buf []byte
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			buf = buf[:0]
+			trimmed := strings.TrimRight(line, "\r\n")
+			if r, ok := parseStructuredLine(trimmed); ok {
+				formatRecord(&buf, r, feats)
+			} else {
+				buf = append(buf, trimmed...)
+				buf = append(buf, '\n')
+			}
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+			if br.Buffered() == 0 {
+				// flush when there's no more input at hand, for "tail -f"
+				if err := bw.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return bw.Flush()
+		} else if err != nil {
+			bw.Flush()
+			return err
+		}
+	}
+}
+
+// formatRecord appends r to buf formatted like a logger with feats would
+func formatRecord(buf *[]byte, r *Record, feats Features) {
+	m := logRecord{
+		logger: &Logger{Features: feats, Prefix: r.Prefix},
+		level:  r.Level,
+		time:   r.Time,
+		scope:  r.Scope,
+		fields: sortedFields(r.Fields),
+		msg:    []byte(r.Msg),
+	}
+	if m.level < LevelDebug || m.level > levelTime || m.level == LevelDisable {
+		m.level = LevelInfo
+	}
+	m.format(buf)
+}
+
+// parseStructuredLine parses a JSON or logfmt record
+func parseStructuredLine(line string) (*Record, bool) {
+	if strings.HasPrefix(line, "{") {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			return nil, false
+		}
+		return &r, true
+	}
+	kv, ok := parseLogfmt(line)
+	if !ok {
+		return nil, false
+	}
+	r := &Record{Level: LevelInfo}
+	for _, f := range kv {
+		switch f.Key {
+		case "time", "ts":
+			if t, err := time.Parse(time.RFC3339Nano, f.Value); err == nil {
+				r.Time = t
+				continue
+			}
+		case "level", "lvl":
+			if level, err := ParseLevel(f.Value); err == nil {
+				r.Level = level
+				continue
+			}
+		case "msg", "message":
+			r.Msg = f.Value
+			continue
+		case "prefix":
+			r.Prefix = f.Value
+			continue
+		}
+		if r.Fields == nil {
+			r.Fields = make(map[string]string)
+		}
+		r.Fields[f.Key] = f.Value
+	}
+	return r, true
+}
+
+// parseLogfmt parses a line of key=value pairs. Values may be quoted Go strings.
+// Returns false if line is not logfmt or has no "msg" or "message" key.
+func parseLogfmt(line string) ([]Field, bool) {
+	var fields []Field
+	hasMsg := false
+	for {
+		line = strings.TrimLeft(line, " ")
+		if line == "" {
+			break
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 1 || strings.ContainsAny(line[:eq], " \"") {
+			return nil, false
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, false
+			}
+			v, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			value = v
+			line = line[end+1:]
+		} else {
+			end := strings.IndexByte(line, ' ')
+			if end == -1 {
+				end = len(line)
+			}
+			value = line[:end]
+			line = line[end:]
+		}
+		if key == "msg" || key == "message" {
+			hasMsg = true
+		}
+		fields = append(fields, Field{key, value})
+	}
+	return fields, hasMsg
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestPrettify(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf []byte
+	r := Record{
+		Level:  LevelWarn,
+		Time:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Prefix: "[srv]",
+		Msg:    "disk full",
+		Fields: map[string]string{"disk": "sda1"},
+	}
+	FormatJSON(&buf, &r)
+	input := string(buf) +
+		`time=2020-01-02T03:04:06Z level=error msg="oh \"no\"" reqid=abc` + "\n" +
+		"plain text\n"
+
+	var feats Features = FTime | FUTC | FPrefixWarn | FPrefixError
+	out := &bytes.Buffer{}
+	assert.NoErr("Prettify", Prettify(out, strings.NewReader(input), feats))
+	assert.Eq("output", out.String(), ""+
+		"03:04:05 [warn] [srv] disk full disk=sda1\n"+
+		"03:04:06 [error] oh \"no\" reqid=abc\n"+
+		"plain text\n")
+}
@@ -0,0 +1,89 @@
+package log
+
+import "sync/atomic"
+
+// priorityQueue reorders records received by writeLoop so that records of higher levels are
+// written before records of lower levels which were logged before them; see
+// Options.PriorityWindow.
+//
+// Records are received from the queue's channel into records, up to the capacity of the
+// channel. Control records (e.g. for Sync) are barriers: no record is moved across one.
+type priorityQueue struct {
+	window  int          // max number of records written ahead of records[0]; 0 if disabled
+	records []*logRecord // received but not yet written, in the order they were logged
+	skips   int          // number of records written ahead of records[0]
+	closed  bool         // the channel was closed
+}
+
+// held returns true if records have been received but not yet returned by next, or if the
+// channel has been closed
+func (p *priorityQueue) held() bool {
+	return len(p.records) > 0 || p.closed
+}
+
+// next adds m (if not nil) and any records waiting in q.ch, then removes and returns the
+// record to write next. Returns false when the channel is closed and all records have been
+// returned.
+func (p *priorityQueue) next(q *queue, m *logRecord) (*logRecord, bool) {
+	if m != nil {
+		p.records = append(p.records, m)
+		atomic.AddInt32(&q.held, 1)
+	}
+	p.fill(q)
+	if len(p.records) == 0 {
+		return nil, false
+	}
+	i := 0
+	if p.skips < p.window {
+		for j, m := range p.records {
+			if m.level > levelTime {
+				break // control record
+			}
+			if priority(m.level) > priority(p.records[i].level) {
+				i = j
+			}
+		}
+	}
+	if i == 0 {
+		p.skips = 0
+	} else {
+		p.skips++
+	}
+	m = p.records[i]
+	copy(p.records[i:], p.records[i+1:])
+	p.records[len(p.records)-1] = nil
+	p.records = p.records[:len(p.records)-1]
+	atomic.AddInt32(&q.held, -1)
+	return m, true
+}
+
+// fill receives records waiting in q.ch, without blocking
+func (p *priorityQueue) fill(q *queue) {
+	for !p.closed && len(p.records) < cap(q.ch) {
+		m, ch := q.next()
+		if m == nil {
+			var ok bool
+			select {
+			case m, ok = <-ch:
+			default:
+				return
+			}
+			if m, ok = q.accept(m, ok); !ok {
+				p.closed = true
+				return
+			} else if m == nil {
+				continue // received before its turn
+			}
+		}
+		p.records = append(p.records, m)
+		atomic.AddInt32(&q.held, 1)
+	}
+}
+
+// priority returns the priority of records of level; records of levelTime are like info
+func priority(level Level) Level {
+	if level == levelTime {
+		return LevelInfo
+	}
+	return level
+}
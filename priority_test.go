@@ -0,0 +1,48 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestPriorityWindow(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	bw := &blockingWriter{unblock: make(chan struct{})}
+	l := New(Options{Writer: bw, Level: LevelDebug, QueueSize: 10, PriorityWindow: 2})
+	wedge := func() {
+		l.Info("first")
+		for l.Metrics().QueueDepth > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	wedge()
+	for _, msg := range []string{"d1", "d2", "d3", "d4"} {
+		l.Debug(msg)
+	}
+	for _, msg := range []string{"e1", "e2", "e3"} {
+		l.Error(msg)
+	}
+	assert.Eq("queue depth", l.Metrics().QueueDepth, 7)
+	close(bw.unblock)
+	assert.NoErr("sync", l.Sync())
+	assert.Eq("errors first, within window", bw.String(), "first\ne1\ne2\nd1\ne3\nd2\nd3\nd4\n")
+
+	// records are not moved across a Sync
+	bw.Reset()
+	bw.unblock = make(chan struct{})
+	wedge()
+	l.Debug("d")
+	synced := make(chan error)
+	go func() { synced <- l.Sync() }()
+	for l.Metrics().QueueDepth < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Error("e")
+	close(bw.unblock)
+	assert.NoErr("sync", <-synced)
+	assert.NoErr("close", l.Close())
+	assert.Eq("not reordered across sync", bw.String(), "first\nd\ne\n")
+}
@@ -0,0 +1,209 @@
+package log
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Progress reports the progress of a long-running operation. On a terminal it is shown as a
+// status line at the bottom of the output which is updated in place, with regular messages
+// printed above it. On other writers, only a summary is logged when the operation is done.
+//
+//   p := logger.Progress("downloading", size)
+//   for ... {
+//     p.Add(int64(n))
+//   }
+//   p.Done() // "[info] downloading: done (1048576/1048576) in 2.1s"
+//
+// Messages logged synchronously (see FSync) may overwrite the status line.
+type Progress struct {
+	l       *Logger
+	label   string
+	total   int64
+	start   time.Time
+	live    bool  // draw a status line
+	current int64 // atomic
+	sent    int64 // atomic; UnixNano of last status line update
+	done    int32 // atomic
+}
+
+// progressUpdateInterval limits how often the status line is redrawn
+const progressUpdateInterval = 100 * time.Millisecond
+
+// Progress starts reporting progress of an operation with label, like "downloading".
+// total is the amount of work, or zero if unknown. See the Progress type.
+func (l *Logger) Progress(label string, total int64) *Progress {
+	return l.newProgress(label, total, isTerminal(l.Writer()))
+}
+
+func (l *Logger) newProgress(label string, total int64, live bool) *Progress {
+	p := &Progress{l: l, label: label, total: total, start: time.Now(), live: live}
+	p.update(true)
+	return p
+}
+
+// Add adds n to the amount of work done
+func (p *Progress) Add(n int64) {
+	atomic.AddInt64(&p.current, n)
+	p.update(false)
+}
+
+// Set sets the amount of work done
+func (p *Progress) Set(n int64) {
+	atomic.StoreInt64(&p.current, n)
+	p.update(false)
+}
+
+// Done removes the status line and logs a summary as an info message
+func (p *Progress) Done() {
+	if !atomic.CompareAndSwapInt32(&p.done, 0, 1) {
+		return
+	}
+	p.update(true)
+	current := atomic.LoadInt64(&p.current)
+	elapsed := time.Since(p.start).Round(time.Millisecond)
+	if p.total > 0 {
+		p.l.Info("%s: done (%d/%d) in %s", p.label, current, p.total, elapsed)
+	} else {
+		p.l.Info("%s: done (%d) in %s", p.label, current, elapsed)
+	}
+}
+
+// update sends p to the writeLoop for redrawing the status line, unless it was recently sent
+func (p *Progress) update(force bool) {
+	if !p.live {
+		return
+	}
+	now := time.Now().UnixNano()
+	sent := atomic.LoadInt64(&p.sent)
+	if !force && now-sent < int64(progressUpdateInterval) {
+		return
+	}
+	if !atomic.CompareAndSwapInt64(&p.sent, sent, now) && !force {
+		return // another goroutine is sending
+	}
+	m := logRecordFree.Get().(*logRecord)
+	m.level = ctlProgress
+	m.ctlarg = progressUpdate{p, atomic.LoadInt64(&p.current), atomic.LoadInt32(&p.done) != 0}
+	if !p.l.q.send(m) {
+		m.free()
+	}
+}
+
+// progressUpdate is the state of a Progress at the time of an update
+type progressUpdate struct {
+	p       *Progress
+	current int64
+	done    bool
+}
+
+// appendStatus appends the status line text of u to buf, like
+// "downloading [#########-----------]  45% 450/1000"
+func (u progressUpdate) appendStatus(buf []byte) []byte {
+	p, current := u.p, u.current
+	buf = append(buf, p.label...)
+	if p.total > 0 {
+		const width = 20
+		filled := int(current * width / p.total)
+		if filled > width {
+			filled = width
+		}
+		buf = append(buf, " ["...)
+		buf = append(buf, strings.Repeat("#", filled)...)
+		buf = append(buf, strings.Repeat("-", width-filled)...)
+		buf = append(buf, "] "...)
+		pct := strconv.FormatInt(current*100/p.total, 10)
+		buf = append(buf, strings.Repeat(" ", 3-len(pct))...)
+		buf = append(buf, pct...)
+		buf = append(buf, "% "...)
+		buf = strconv.AppendInt(buf, current, 10)
+		buf = append(buf, '/')
+		buf = strconv.AppendInt(buf, p.total, 10)
+	} else {
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, current, 10)
+	}
+	return buf
+}
+
+// statusLine is the state of the status line showing active Progress, owned by a writeLoop
+type statusLine struct {
+	w        io.Writer
+	progress []progressUpdate
+	drawn    bool
+	buf      []byte
+}
+
+// covers returns true if the status line is drawn on w
+func (s *statusLine) covers(w io.Writer) bool {
+	return s.drawn && sameWriter(s.w, w)
+}
+
+// update adds, redraws or removes a Progress
+func (s *statusLine) update(u progressUpdate) {
+	i := 0
+	for i < len(s.progress) && s.progress[i].p != u.p {
+		i++
+	}
+	if u.done {
+		if i < len(s.progress) {
+			s.progress = append(s.progress[:i], s.progress[i+1:]...)
+		}
+	} else if i == len(s.progress) {
+		s.progress = append(s.progress, u)
+	} else {
+		s.progress[i] = u
+	}
+	if s.drawn {
+		s.clear()
+	}
+	if len(s.progress) > 0 {
+		s.w = s.progress[0].p.l.Writer()
+		s.draw()
+	}
+}
+
+// swapWriter moves the status line from writer w to w2, if it is drawn on w
+func (s *statusLine) swapWriter(w, w2 io.Writer) {
+	if s.covers(w) {
+		s.clear()
+		s.w = w2
+		s.draw()
+	}
+}
+
+func (s *statusLine) clear() {
+	if s.drawn {
+		io.WriteString(s.w, "\r\x1b[2K")
+		s.drawn = false
+	}
+}
+
+func (s *statusLine) draw() {
+	if len(s.progress) == 0 {
+		return
+	}
+	s.buf = append(s.buf[:0], "\r\x1b[2K"...)
+	for i, u := range s.progress {
+		if i > 0 {
+			s.buf = append(s.buf, "  |  "...)
+		}
+		s.buf = u.appendStatus(s.buf)
+	}
+	s.w.Write(s.buf)
+	s.drawn = true
+}
+
+// isTerminal returns true if w is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return false
+	}
+	st, err := f.Stat()
+	return err == nil && (st.Mode()&os.ModeCharDevice) != 0
+}
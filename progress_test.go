@@ -0,0 +1,47 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestProgress(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixInfo)
+	defer l.Close()
+
+	p := l.newProgress("dl", 10, true)
+	l.Info("a")
+	p.Set(5)       // too soon after the previous update; not drawn
+	p.update(true) // draw
+	l.Info("b")
+	p.Done()
+	l.Sync()
+
+	re := regexp.MustCompile(`in [0-9.]+[µm]?s`)
+	out := re.ReplaceAllString(w.String(), "in T")
+	out = strings.Replace(out, "\r\x1b[2K", "<CL>", -1)
+	assert.Eq("output", out, ""+
+		"<CL>dl [--------------------]   0% 0/10"+
+		"<CL>[info] a\n"+
+		"<CL>dl [--------------------]   0% 0/10"+
+		"<CL><CL>dl [##########----------]  50% 5/10"+
+		"<CL>[info] b\n"+
+		"<CL>dl [##########----------]  50% 5/10"+
+		"<CL>[info] dl: done (5/10) in T\n")
+
+	// not a terminal; only the summary is logged
+	w.Reset()
+	p = l.Progress("copy", 0)
+	p.Add(3)
+	p.Add(4)
+	p.Done()
+	p.Done() // no-op
+	l.Sync()
+	assert.Eq("output", re.ReplaceAllString(w.String(), "in T"), "[info] copy: done (7) in T\n")
+}
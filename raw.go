@@ -0,0 +1,52 @@
+package log
+
+// WithFeatures returns a sub-logger with feats enabled in addition to the features of l, for
+// changing how some messages are formatted without changing l:
+//
+//   logger.WithFeatures(log.FMicroseconds).Debug("tick")
+//
+func (l *Logger) WithFeatures(feats Features) *Logger {
+	l2 := l.SubLogger("")
+	l2.EnableFeatures(feats)
+	return l2
+}
+
+// Raw writes msg as it is, followed by a newline if it does not end with one, without header,
+// fields or origin. This is useful for pre-formatted blocks like tables or the output of other
+// programs, which are hard to read with a timestamp and prefix in front of each line:
+//
+//   logger.Info("results:")
+//   logger.Raw(table.String())
+//
+// Raw messages are logged with LevelInfo and are thus not written if the level of l is higher.
+// They are written in order with other messages of l. Writers which receive records rather than
+// text, and loggers with a formatter (see Options.Formatter), get a record with msg as message.
+func (l *Logger) Raw(msg string) {
+	if l.GetLevel() <= LevelInfo {
+		m := l.newRecord(LevelInfo)
+		m.raw = true
+		m.msg = append(m.msg, msg...)
+		l.submit(m)
+	}
+}
+
+// WriteRaw is like Raw but logs b with the given level. b is copied and may be reused when
+// WriteRaw returns. This allows forwarding output which is already formatted, like log lines
+// received from another process, through l so that it is written in order with messages logged
+// by l:
+//
+//   for scanner.Scan() {
+//     logger.WriteRaw(log.LevelInfo, scanner.Bytes())
+//   }
+//
+// Each call is written as a whole, never interleaved with other messages, with a newline
+// appended if b does not end with one. Multi-line input may thus be passed in one call to keep
+// its lines together.
+func (l *Logger) WriteRaw(level Level, b []byte) {
+	if l.GetLevel() <= level {
+		m := l.newRecord(level)
+		m.raw = true
+		m.msg = append(m.msg, b...)
+		l.submit(m)
+	}
+}
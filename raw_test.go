@@ -0,0 +1,58 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestWithFeatures(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixInfo)
+	defer l.Close()
+
+	l.WithFeatures(FTime | FMicroseconds).Info("a")
+	l.Info("b")
+	l.Sync()
+	assert.Ok("output "+w.String(),
+		regexp.MustCompile(`^\d\d:\d\d:\d\d\.\d{6} \[info\] a\n\[info\] b\n$`).Match(w.Bytes()))
+	assert.Eq("features of l unchanged", l.GetFeatures(), Features(FPrefixInfo))
+}
+
+func TestRaw(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "[p]", LevelInfo, FPrefixInfo)
+	defer l.Close()
+
+	done := PushScope("k", "v")
+	l.Info("table:")
+	l.Raw("a | b\n--+--\n1 | 2")
+	l.Raw("end\n")
+	done()
+	l.SetLevel(LevelWarn)
+	l.Raw("not written")
+	l.Sync()
+	assert.Eq("output", w.String(), "[info] [p] table: k=v\na | b\n--+--\n1 | 2\nend\n")
+}
+
+func TestWriteRaw(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(10)
+	w := &bytes.Buffer{}
+	l := NewLogger(NewTeeWriter(TeeSink{W: w, Features: FPrefixWarn}, TeeSink{W: sink}), "", LevelInfo, 0)
+	defer l.Close()
+
+	b := []byte("remote: a\nremote: b")
+	l.WriteRaw(LevelWarn, b)
+	copy(b, "XXXXXX")
+	l.Warn("local")
+	l.WriteRaw(LevelDebug, []byte("not written"))
+	l.Sync()
+	assert.Eq("output", w.String(), "remote: a\nremote: b\n[warn] local\n")
+	assert.Eq("record level", sink.Records()[0].Level, LevelWarn)
+	assert.Eq("record msg", sink.Records()[0].Msg, "remote: a\nremote: b")
+}
@@ -0,0 +1,160 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reader parses text written by loggers back into records, for tools which filter, convert
+// or otherwise post-process logs:
+//
+//   r := log.NewReader(file)
+//   for {
+//     rec, err := r.Read()
+//     if err != nil {
+//       break // io.EOF at the end
+//     }
+//     if rec.Level >= log.LevelWarn {
+//       fmt.Println(rec.Time, rec.Msg)
+//     }
+//   }
+//
+// Dates, times, level prefixes and bracketed logger prefixes like "[server][http]" are
+// recognized and ANSI colors are removed. Scopes, fields and debug origins are left in Msg.
+// Lines which don't start with a header like that of the first record, such as the lines of
+// a stack trace, are continuation lines of multi-line messages. Records without a level prefix
+// get LevelInfo and records without a date get the zero date.
+type Reader struct {
+	// Location is the time zone of times in the log. Defaults to time.Local.
+	// Use time.UTC for logs written with FUTC.
+	Location *time.Location
+
+	r       *bufio.Reader
+	next    string // next line, if hasNext
+	hasNext bool
+	shape   int // header parts of the first record; -1 until known
+}
+
+// header parts
+const (
+	readerDate = 1 << iota
+	readerTime
+	readerLevel
+)
+
+var (
+	readerHeaderRe = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} )?(\d{2}:\d{2}:\d{2}(?:\.\d{1,9})? )?` +
+		`(\[(?:debug|info|warn|error|time)\] )?((?:\[[^\]\s]*\])+ )?`)
+	readerColorRe = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// NewReader creates a Reader reading from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), shape: -1}
+}
+
+// Read returns the next record. Returns io.EOF when there are no more records.
+func (r *Reader) Read() (*Record, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	rec, shape := r.parseHeader(line)
+	if r.shape == -1 {
+		r.shape = shape
+	}
+	for {
+		next, err := r.readLine()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if r.shape == 0 || r.lineShape(next)&r.shape != 0 {
+			r.next, r.hasNext = next, true
+			break
+		}
+		rec.Msg += "\n" + next
+	}
+	return rec, nil
+}
+
+// readLine returns the next line, without its line terminator and colors
+func (r *Reader) readLine() (string, error) {
+	if r.hasNext {
+		r.hasNext = false
+		return r.next, nil
+	}
+	line, err := r.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if strings.IndexByte(line, '\x1b') != -1 {
+		line = readerColorRe.ReplaceAllString(line, "")
+	}
+	return line, nil
+}
+
+func (r *Reader) lineShape(line string) int {
+	_, shape := r.parseHeader(line)
+	return shape
+}
+
+// parseHeader parses the header of line, returning a record and the parts found in the header
+func (r *Reader) parseHeader(line string) (*Record, int) {
+	rec := &Record{Level: LevelInfo}
+	shape := 0
+	match := readerHeaderRe.FindStringSubmatchIndex(line)
+	part := func(i int) string {
+		if match[2*i] < 0 {
+			return ""
+		}
+		return line[match[2*i] : match[2*i+1]-1] // excluding trailing space
+	}
+	loc := r.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	var year, day int
+	month := time.January
+	if s := part(1); s != "" {
+		shape |= readerDate
+		year, _ = strconv.Atoi(s[0:4])
+		m, _ := strconv.Atoi(s[5:7])
+		month = time.Month(m)
+		day, _ = strconv.Atoi(s[8:10])
+	}
+	var hour, min, sec, nsec int
+	if s := part(2); s != "" {
+		shape |= readerTime
+		hour, _ = strconv.Atoi(s[0:2])
+		min, _ = strconv.Atoi(s[3:5])
+		sec, _ = strconv.Atoi(s[6:8])
+		if len(s) > 9 {
+			frac := s[9:]
+			nsec, _ = strconv.Atoi(frac + strings.Repeat("0", 9-len(frac)))
+		}
+	}
+	if shape != 0 {
+		if year == 0 {
+			day = 1
+		}
+		rec.Time = time.Date(year, month, day, hour, min, sec, nsec, loc)
+	}
+	if s := part(3); s != "" {
+		shape |= readerLevel
+		if name := s[1 : len(s)-1]; name == "time" {
+			rec.Level = levelTime
+		} else {
+			rec.Level, _ = ParseLevel(name)
+		}
+	}
+	rec.Prefix = part(4)
+	rec.Msg = line[match[1]:]
+	return rec, shape
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestReader(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	var feats Features = FDate | FMicroseconds | FUTC | FColor |
+		FPrefixDebug | FPrefixInfo | FPrefixWarn | FPrefixError
+	l := NewLogger(w, "", LevelDebug, feats)
+	defer l.Close()
+	sub := l.SubLogger("[srv][http]")
+
+	start := time.Now()
+	l.Info("hello")
+	sub.Error("crashed\ngoroutine 1 [running]:\n\tmain.go:12")
+	l.Warn("[not a prefix]")
+	l.Sync()
+
+	r := NewReader(strings.NewReader(w.String()))
+	r.Location = time.UTC
+	var records []*Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoErr("read", err)
+		records = append(records, rec)
+	}
+	if !assert.Eq("records", len(records), 3) {
+		return
+	}
+	assert.Eq("msg", records[0].Msg, "hello")
+	assert.Eq("level", records[0].Level, LevelInfo)
+	d := records[0].Time.Sub(start)
+	assert.Ok("time; got %v", d > -time.Millisecond && d < time.Second, records[0].Time)
+
+	assert.Eq("level", records[1].Level, LevelError)
+	assert.Eq("prefix", records[1].Prefix, "[srv][http]")
+	assert.Eq("multi-line msg", records[1].Msg, "crashed\ngoroutine 1 [running]:\n\tmain.go:12")
+
+	assert.Eq("level", records[2].Level, LevelWarn)
+	assert.Eq("bracketed msg", records[2].Prefix+"|"+records[2].Msg, "|[not a prefix]")
+
+	// without headers, every line is a record
+	r = NewReader(strings.NewReader("a\nb\n"))
+	r1, _ := r.Read()
+	r2, _ := r.Read()
+	assert.Eq("no header", r1.Msg+r2.Msg, "ab")
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorID logs an error with a unique ID and returns the ID, e.g. "k3x9qe2m7a", so that a
+// message shown to a user can refer to the log record with the full details:
+//
+//   id := logger.ErrorID("charge %s failed: %v", order, err)
+//   http.Error(w, "internal error (error id "+id+")", 500)
+//   // log: "charge 1234 failed: card declined id=k3x9qe2m7a"
+//
+// In text output the ID follows the fields; structured output (e.g. FormatJSON) has it in the
+// field "id". Returns "" if errors are not logged by l.
+func (l *Logger) ErrorID(format string, v ...interface{}) string {
+	if l.GetLevel() > LevelError {
+		return ""
+	}
+	m := l.newRecord(LevelError)
+	if m.id == "" {
+		m.id = newRecordID()
+	}
+	id := m.id
+	if !m.deferf(format, v) {
+		m.appendf(format, v...)
+	}
+	l.submit(m)
+	return id
+}
+
+// SetRecordIDs controls whether every message gets a unique ID like the ones of ErrorID.
+// Since no two messages are the same, messages with IDs are never suppressed as repetitions
+// (see SuppressDuplicates).
+//
+// The setting applies to l, its parent and sub-loggers, which share a queue.
+func (l *Logger) SetRecordIDs(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&l.q.ids, v)
+}
+
+// recordIDSeq is the counter from which record IDs are made; accessed atomically.
+// It starts at a random number, so that IDs are unlikely to collide with those of other
+// processes. newRecordID runs it through a permutation of 50-bit integers, so IDs are unique
+// within a process while looking unrelated to each other.
+var recordIDSeq uint64
+
+const recordIDChars = "0123456789abcdefghjkmnpqrstvwxyz" // Crockford's base32, lower case
+
+func init() {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		binary.LittleEndian.PutUint64(b[:], uint64(time.Now().UnixNano()))
+	}
+	recordIDSeq = binary.LittleEndian.Uint64(b[:])
+}
+
+// newRecordID returns a new record ID of 10 characters
+func newRecordID() string {
+	const mask = 1<<50 - 1
+	x := atomic.AddUint64(&recordIDSeq, 1) & mask
+	x = (x * 0x2545f4914f6cdd1d) & mask
+	x ^= x >> 25
+	x = (x * 0x1b873593e6546b) & mask
+	x ^= x >> 23
+	var b [10]byte
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = recordIDChars[x&31]
+		x >>= 5
+	}
+	return string(b[:])
+}
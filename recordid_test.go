@@ -0,0 +1,62 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestErrorID(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "[api]", LevelInfo, 0)
+	id := l.ErrorID("charge %d failed", 1234)
+	assert.Eq("id length", len(id), 10)
+	assert.Ok("ids differ", l.ErrorID("x") != id)
+	l.Info("no id")
+	assert.NoErr("sync", l.Sync())
+	lines := strings.SplitAfter(buf.String(), "\n")
+	assert.Eq("text", lines[0], "[api] charge 1234 failed id="+id+"\n")
+	assert.Eq("no id", lines[2], "[api] no id\n")
+
+	sink := NewMemorySink(10)
+	l.SetWriter(sink)
+	id = l.WithFields("order", "1234").ErrorID("failed")
+	l.SetLevel(LevelDisable)
+	assert.Eq("not logged", l.ErrorID("failed"), "")
+	assert.NoErr("close", l.Close())
+	r := sink.Last(1)[0]
+	assert.Eq("id field", r.Fields["id"], id)
+	assert.Eq("other field", r.Fields["order"], "1234")
+}
+
+func TestRecordIDs(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(10)
+	l := New(Options{Writer: sink, RecordIDs: true})
+	l.SuppressDuplicates(time.Minute)
+	l.Info("a")
+	l.Info("a")
+	l.SetRecordIDs(false)
+	l.Info("b")
+	assert.NoErr("close", l.Close())
+	records := sink.Records()
+	assert.Eq("written", len(records), 3)
+	if len(records) == 3 {
+		assert.Eq("id", len(records[0].Fields["id"]), 10)
+		assert.Ok("unique", records[0].Fields["id"] != records[1].Fields["id"])
+		assert.Eq("disabled", records[2].Fields["id"], "")
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100000; i++ {
+		id := newRecordID()
+		if seen[id] {
+			t.Fatalf("duplicate id %q", id)
+		}
+		seen[id] = true
+	}
+}
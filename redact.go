@@ -0,0 +1,136 @@
+package log
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Redactor transforms a message before it is written, for example to mask secrets.
+// msg may be modified in place. Returns the resulting message.
+//
+// Redactors are also applied to each field of a message, including static fields
+// (see SetStaticFields) and fields of the goroutine (see PushScope), as if the field was
+// the message key="value", with value quoted like a Go string. If the result is not in that
+// form, the value of the field is replaced by Redacted. Values of template placeholders
+// (see InfoT) are redacted in the same way, both as fields and in the message.
+type Redactor func(msg []byte) []byte
+
+// Redacted is the text which RegexRedactor and KeyRedactor replace sensitive values with
+const Redacted = "[REDACTED]"
+
+// AddRedactor adds a redactor which is applied to all messages logged by l, its parent and
+// sub-loggers, before they are passed on to writers, error reporters (see ReportErrors) and
+// expectations (see Expect). Redactors are applied in the order they were added.
+//
+//   logger.AddRedactor(log.RegexRedactor(`password=\S+`))
+//   logger.AddRedactor(log.KeyRedactor("token", "secret"))
+//   logger.Info("login user=bob password=hunter2 token: abc123")
+//   // "login user=bob [REDACTED] token: [REDACTED]"
+//
+func (l *Logger) AddRedactor(r Redactor) {
+	q := l.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	rs, _ := q.redactors.Load().([]Redactor)
+	rs2 := make([]Redactor, len(rs)+1) // copy since submit may be reading rs
+	copy(rs2, rs)
+	rs2[len(rs)] = r
+	q.redactors.Store(rs2)
+}
+
+// RegexRedactor returns a redactor which replaces text matching the regular expression
+// pattern with Redacted. If pattern has capture groups, only the text of the groups is replaced;
+// e.g. `password=(\S+)` keeps "password=". Panics if pattern is not a valid regular expression.
+func RegexRedactor(pattern string) Redactor {
+	re := regexp.MustCompile(pattern)
+	groups := re.NumSubexp() > 0
+	return func(msg []byte) []byte {
+		matches := re.FindAllSubmatchIndex(msg, -1)
+		if matches == nil {
+			return msg
+		}
+		out := make([]byte, 0, len(msg))
+		prev := 0
+		for _, match := range matches {
+			if !groups {
+				out = append(out, msg[prev:match[0]]...)
+				out = append(out, Redacted...)
+				prev = match[1]
+				continue
+			}
+			for i := 2; i < len(match); i += 2 {
+				start, end := match[i], match[i+1]
+				if start < prev { // unmatched group (-1) or nested group
+					continue
+				}
+				out = append(out, msg[prev:start]...)
+				out = append(out, Redacted...)
+				prev = end
+			}
+		}
+		return append(out, msg[prev:]...)
+	}
+}
+
+// KeyRedactor returns a redactor which replaces the values of the given keys (case insensitive)
+// with Redacted, in the forms key=value, key: value, key="value" and "key":"value".
+// The values of fields with the given keys are thus redacted as a whole.
+func KeyRedactor(keys ...string) Redactor {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = regexp.QuoteMeta(key)
+	}
+	return RegexRedactor(`(?i)\b(?:` + strings.Join(quoted, "|") +
+		`)"?\s*[:=]\s*(?:"((?:[^"\\]|\\.)*)"|([^\s",;&]+))`)
+}
+
+// redact applies the redactors of q to m
+func (q *queue) redact(m *logRecord) {
+	rs, _ := q.redactors.Load().([]Redactor)
+	if len(rs) == 0 {
+		return
+	}
+	for _, r := range rs {
+		m.msg = r(m.msg)
+	}
+	m.fields = redactFields(rs, m.fields)
+	m.static = redactFields(rs, m.static)
+}
+
+// redactFields applies rs to the values of fields. fields may be shared with loggers and other
+// records, so it is copied if any value is redacted.
+func redactFields(rs []Redactor, fields []Field) []Field {
+	copied := false
+	for i, f := range fields {
+		value := redactField(rs, f)
+		if value == f.Value {
+			continue
+		}
+		if !copied {
+			fields = append([]Field(nil), fields...)
+			copied = true
+		}
+		fields[i].Value = value
+	}
+	return fields
+}
+
+// redactField applies rs to f in the form key="value" and returns the resulting value
+func redactField(rs []Redactor, f Field) string {
+	b := make([]byte, 0, len(f.Key)+len(f.Value)+3)
+	b = append(b, f.Key...)
+	b = append(b, '=')
+	b = strconv.AppendQuote(b, f.Value)
+	for _, r := range rs {
+		b = r(b)
+	}
+	if len(b) < len(f.Key)+1 || string(b[:len(f.Key)]) != f.Key || b[len(f.Key)] != '=' {
+		return Redacted
+	}
+	value, err := strconv.Unquote(string(b[len(f.Key)+1:]))
+	if err != nil {
+		return Redacted
+	}
+	return value
+}
@@ -0,0 +1,73 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestRedact(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	redact := func(r Redactor, msg string) string { return string(r([]byte(msg))) }
+
+	r := RegexRedactor(`password=\S+`)
+	assert.Eq("regex", redact(r, "a password=x b password=y"), "a [REDACTED] b [REDACTED]")
+	assert.Eq("no match", redact(r, "nothing"), "nothing")
+
+	r = RegexRedactor(`password=(\S+)`)
+	assert.Eq("regex group", redact(r, "a password=x b"), "a password=[REDACTED] b")
+
+	r = KeyRedactor("token", "api.key")
+	assert.Eq("key=value", redact(r, "token=abc&x=1"), "token=[REDACTED]&x=1")
+	assert.Eq("key: value", redact(r, "Token: abc def"), "Token: [REDACTED] def")
+	assert.Eq("json", redact(r, `{"api.key":"abc","n":1}`), `{"api.key":"[REDACTED]","n":1}`)
+	assert.Eq("other keys", redact(r, "apiXkey=abc tokens=1"), "apiXkey=abc tokens=1")
+
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+	l.AddRedactor(KeyRedactor("password"))
+	l.SubLogger("[sub]").AddRedactor(RegexRedactor(`\d{4}-\d{4}`))
+	l.Expect(LevelInfo, `hunter2`, 0)
+	l.Info("login password=hunter2 card 1234-5678")
+	l.Sync()
+	assert.Eq("output", w.String(), "login password=[REDACTED] card [REDACTED]\n")
+	assert.NoErr("expectations see redacted message", l.Verify())
+}
+
+func TestRedactFields(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(10)
+	l := NewLogger(sink, "", LevelInfo, 0)
+	l.SetStaticFields("region", "eu", "api_key", "k1")
+	l.AddRedactor(KeyRedactor("password", "api_key"))
+	l.AddRedactor(RegexRedactor(`\d{4}-\d{4}`))
+	l.InfoT("login {user} with {password}", V{"user": "bob", "password": "hunter 2"})
+	l.Info("pw %w", errors.New("password=hunter2"))
+	pop := PushScope("card", "1234-5678")
+	l.Info("paid")
+	pop()
+	assert.NoErr("close", l.Close())
+
+	records := sink.Records()
+	if !assert.Eq("records", len(records), 3) {
+		return
+	}
+	assert.Eq("template msg", records[0].Msg, "login bob with [REDACTED]")
+	assert.Eq("template field", records[0].Fields["password"], Redacted)
+	assert.Eq("other field", records[0].Fields["user"], "bob")
+	assert.Eq("static field", records[0].Fields["api_key"], Redacted)
+	assert.Eq("other static field", records[0].Fields["region"], "eu")
+	assert.Eq("error field", records[1].Fields["error"], "password=[REDACTED]")
+	assert.Eq("scope field", records[2].Fields["card"], Redacted)
+
+	// the logger's fields are not modified
+	l2 := NewLogger(sink, "", LevelInfo, 0).WithFields("password", "x")
+	l2.AddRedactor(KeyRedactor("password"))
+	l2.Info("a")
+	assert.NoErr("close", l2.Close())
+	assert.Eq("logger field", l2.fields[0].Value, "x")
+	assert.Eq("redacted", sink.Last(1)[0].Fields["password"], Redacted)
+}
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Messages may be logged while the records of a queue are being written, by the queue's
+// writer, a Formatter or an OnError function, e.g. to report a failure. Such a message can't
+// always be queued: waiting for the writeLoop to make room in the queue, to write the message
+// (see SetOrdered) or to Sync would wait for the goroutine doing the waiting, and writing it
+// directly would call the writer while it's already being called. Instead, the message is
+// written to reentrantOutput.
+
+// reentrantOutput receives messages which would otherwise deadlock; see enqueue
+var reentrantOutput io.Writer = os.Stderr
+
+// goroutineSet is a set of goroutine IDs
+type goroutineSet struct {
+	n   int32 // len(ids); read atomically to avoid the cost of goroutineID when ids is empty
+	mu  sync.Mutex
+	ids []uint64 // usually only a few
+}
+
+func (s *goroutineSet) add(id uint64) {
+	s.mu.Lock()
+	s.ids = append(s.ids, id)
+	atomic.StoreInt32(&s.n, int32(len(s.ids)))
+	s.mu.Unlock()
+}
+
+func (s *goroutineSet) remove(id uint64) {
+	s.mu.Lock()
+	for i, id2 := range s.ids {
+		if id2 == id {
+			s.ids[i] = s.ids[len(s.ids)-1]
+			s.ids = s.ids[:len(s.ids)-1]
+			break
+		}
+	}
+	atomic.StoreInt32(&s.n, int32(len(s.ids)))
+	s.mu.Unlock()
+}
+
+func (s *goroutineSet) contains(id uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id2 := range s.ids {
+		if id2 == id {
+			return true
+		}
+	}
+	return false
+}
+
+// containsCurrent returns true if s contains the calling goroutine. The goroutine's ID is
+// only looked up when s is non-empty; the writeLoop leaves q.writing while idle so that this
+// is usually not the case.
+func (s *goroutineSet) containsCurrent() bool {
+	if atomic.LoadInt32(&s.n) == 0 {
+		return false
+	}
+	return s.contains(goroutineID())
+}
+
+// enqueue sends m to the writeLoop, or writes it to reentrantOutput if the queue is full and
+// the calling goroutine is writing records of q, in which case the writeLoop would never make
+// room. If the queue is full and the context of m (see LogContext) is done, m is dropped.
+//
+// q.mu must be held for reading.
+func (q *queue) enqueue(m *logRecord) {
+	ch := q.input(m)
+	select {
+	case ch <- m:
+		return
+	default:
+	}
+	if q.writing.containsCurrent() {
+		q.skip(ch, m)
+		q.writeReentrant(m)
+		return
+	}
+	if m.ctx == nil {
+		ch <- m
+		return
+	}
+	select {
+	case ch <- m:
+		return
+	case <-m.ctx.Done():
+	}
+	q.skip(ch, m)
+	m.logger.metrics.drop()
+	if m.done != nil {
+		m.done(m.ctx.Err())
+	}
+	if m.walSeq != 0 {
+		q.writeFailed(q.wal.written(m.walSeq))
+	}
+	m.free()
+}
+
+// writeReentrant writes m to reentrantOutput. m is formatted as text even if its logger has a
+// Formatter, since the Formatter may be what logged m. m may be a ctlBatch record.
+func (q *queue) writeReentrant(m *logRecord) {
+	if m.level == ctlBatch {
+		for _, m := range m.ctlarg.([]*logRecord) {
+			q.writeReentrant(m)
+		}
+		m.free()
+		return
+	}
+	if m.deferred() {
+		m.render()
+		q.redact(m)
+	}
+	b := getBuffer()
+	m.formatWith(&b.B, m.logger)
+	_, err := reentrantOutput.Write(b.B)
+	b.Release()
+	if m.done != nil {
+		m.done(err)
+	}
+	if m.walSeq != 0 {
+		q.writeFailed(q.wal.written(m.walSeq))
+	}
+	m.free()
+}
@@ -0,0 +1,170 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+// hookWriter calls f while writing, with its mutex held, like a writer reporting a failure
+type hookWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	f   func(p []byte)
+}
+
+func (w *hookWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	if w.f != nil {
+		w.f(p)
+	}
+	return len(p), nil
+}
+
+func (w *hookWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// withReentrantOutput replaces reentrantOutput for the duration of the test
+func withReentrantOutput(t *testing.T) *MemorySink {
+	sink := NewMemorySink(100)
+	prev := reentrantOutput
+	reentrantOutput = sink
+	t.Cleanup(func() { reentrantOutput = prev })
+	return sink
+}
+
+// noDeadlock fails the test if f does not return within a few seconds
+func noDeadlock(t *testing.T, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock")
+	}
+}
+
+func TestReentrantQueueFull(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	fallback := withReentrantOutput(t)
+	w := &hookWriter{}
+	l := New(Options{Writer: w, QueueSize: 1})
+	defer l.Close()
+	w.f = func(p []byte) {
+		if !bytes.HasPrefix(p, []byte("from writer")) {
+			for i := 0; i < 3; i++ {
+				l.Info("from writer %d", i)
+			}
+			l.Sync()
+		}
+	}
+	noDeadlock(t, func() {
+		l.Info("a")
+		l.Sync()
+		l.Sync() // writes messages queued by the writer
+	})
+	assert.Ok("message written", strings.HasPrefix(w.String(), "a\n"))
+	assert.Eq("all messages from writer written",
+		strings.Count(w.String(), "from writer")+fallback.Len(), 3)
+}
+
+func TestReentrantBatch(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	fallback := withReentrantOutput(t)
+	w := &hookWriter{}
+	l := New(Options{Writer: w, QueueSize: 1})
+	defer l.Close()
+	w.f = func(p []byte) {
+		if bytes.HasPrefix(p, []byte("a")) {
+			for i := 0; i < 3; i++ {
+				l.Batch(func(b *Batch) {
+					b.Info("batch %d", i)
+					b.Columns(LevelInfo, "k", "v")
+				})
+			}
+		}
+	}
+	noDeadlock(t, func() {
+		l.Info("a")
+		l.Sync()
+		l.Sync() // writes batches queued by the writer
+	})
+	out := w.String()
+	for _, r := range fallback.Records() {
+		out += r.Msg + "\n"
+	}
+	assert.Eq("all batches written", strings.Count(out, "batch "), 3)
+	assert.Eq("all columns written", strings.Count(out, "k: v"), 3)
+	assert.Ok("some written to the fallback", fallback.Len() > 0)
+}
+
+func TestReentrantSyncWrite(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	fallback := withReentrantOutput(t)
+	w := &hookWriter{}
+	l := NewLogger(w, "", LevelInfo, FSyncError)
+	defer l.Close()
+	w.f = func(p []byte) {
+		if string(p) == "b\n" {
+			l.Error("from writer")
+		}
+	}
+	noDeadlock(t, func() { l.Error("b") })
+	assert.Eq("output", w.String(), "b\n")
+	assert.Ok("fallback", fallback.Contains(LevelInfo, "from writer"))
+
+	// ordered mode waits for the writeLoop, which would be waiting for the writer
+	l.SetOrdered(true)
+	noDeadlock(t, func() { l.Error("b") })
+	assert.Eq("ordered output", w.String(), "b\nb\n")
+}
+
+func TestReentrantOnError(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	fallback := withReentrantOutput(t)
+	var l *Logger
+	l = New(Options{
+		Writer:   &errorWriter{errors.New("disk full")},
+		Features: FSyncError,
+		OnError: func(err error) {
+			l.Error("write failed: %v", err)
+			l.Close()
+		},
+	})
+	noDeadlock(t, func() {
+		l.Info("a")
+		l.Sync()
+	})
+	assert.Ok("fallback", fallback.Contains(LevelInfo, "write failed: disk full"))
+	noDeadlock(t, func() { l.Close() })
+}
+
+func TestReentrantReporter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+	n := 0
+	l.ReportErrors(func(r ErrorReport) {
+		n++
+		l.Error("reported %q", r.Msg)
+	}, 0, 0)
+	l.Error("a")
+	l.Sync()
+	assert.Eq("reports", n, 1)
+	assert.Eq("output", w.String(), "reported \"a\"\na\n")
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+var registry = struct {
+	sync.Mutex
+	loggers map[string]*Logger
+}{loggers: make(map[string]*Logger)}
+
+// GetLogger returns the logger with the given dotted name, like "server.http", creating it
+// if needed. Named loggers form a hierarchy where "server" is the parent of "server.http" and
+// RootLogger is the parent of top-level names like "server". A new named logger is a sub-logger
+// of its parent (creating the parent as needed). It uses the level, features and writer of its
+// parent, even when they are changed later, until its own are set with SetLevel, SetFeatures
+// (or EnableFeatures etc.) and SetWriter. Its prefix is the prefix of RootLogger followed by
+// "[name]", e.g. "[server.http]".
+//
+// This allows configuring the logging of components in one place, e.g. in main, regardless of
+// whether the packages of the components have created their loggers yet:
+//
+//   var logger = log.GetLogger("server.http") // in package http
+//   ...
+//   log.GetLogger("server").SetLevel(log.LevelWarn) // in main; also applies to server.http
+//   log.GetLogger("server.http").SetLevel(log.LevelDebug)
+//   log.RootLogger.SetWriter(f) // also applies to all named loggers
+//
+func GetLogger(name string) *Logger {
+	if name == "" {
+		return RootLogger
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	return getLogger(name)
+}
+
+func getLogger(name string) *Logger {
+	if l := registry.loggers[name]; l != nil {
+		return l
+	}
+	parent := RootLogger
+	if i := strings.LastIndexByte(name, '.'); i != -1 {
+		parent = getLogger(name[:i])
+	}
+	l := parent.SubLogger("")
+	l.Level = LevelInherit
+	l.Features = featuresInherit
+	l.w.Store(writerRef{inherit: true})
+	l.Prefix = RootLogger.Prefix + "[" + name + "]"
+	l.name = name
+	l.stats = new(metrics)
+	registry.loggers[name] = l
+	return l
+}
+
+// Loggers returns all named loggers, sorted by name
+func Loggers() []*Logger {
+	registry.Lock()
+	loggers := make([]*Logger, 0, len(registry.loggers))
+	for _, l := range registry.loggers {
+		loggers = append(loggers, l)
+	}
+	registry.Unlock()
+	sort.Slice(loggers, func(i, j int) bool { return loggers[i].name < loggers[j].name })
+	return loggers
+}
+
+// Name returns the name of a logger created with GetLogger, or "" for other loggers
+func (l *Logger) Name() string {
+	return l.name
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestGetLogger(t *testing.T) {
+	assert := testutil.NewAssert(t)
+
+	assert.Ok("empty name is RootLogger", GetLogger("") == RootLogger)
+
+	srv := GetLogger("TestGetLogger")
+	http := GetLogger("TestGetLogger.http")
+	srv.SetLevel(LevelWarn)
+	assert.Ok("same logger", GetLogger("TestGetLogger.http") == http)
+	assert.Eq("name", http.Name(), "TestGetLogger.http")
+	assert.Eq("prefix", http.Prefix, "[TestGetLogger.http]")
+	assert.Eq("level inherited from parent", http.GetLevel(), LevelWarn)
+	srv.SetLevel(LevelInherit)
+
+	// parents are created as needed
+	db := GetLogger("TestGetLogger2.db.conn")
+	assert.Eq("name", db.Name(), "TestGetLogger2.db.conn")
+	assert.Ok("parent created", db.parent == GetLogger("TestGetLogger2.db"))
+	assert.Ok("grandparent created", db.parent.parent == GetLogger("TestGetLogger2"))
+	assert.Ok("top-level parent is RootLogger", db.parent.parent.parent == RootLogger)
+
+	var names []string
+	for _, l := range Loggers() {
+		if strings.HasPrefix(l.Name(), "TestGetLogger") {
+			names = append(names, l.Name())
+		}
+	}
+	assert.Eq("Loggers", strings.Join(names, " "),
+		"TestGetLogger TestGetLogger.http TestGetLogger2 TestGetLogger2.db TestGetLogger2.db.conn")
+
+	assert.Eq("sub-loggers are not named", http.SubLogger("[x]").Name(), "")
+}
+
+func TestLevelInherit(t *testing.T) {
+	assert := testutil.NewAssert(t)
+
+	name := fmt.Sprintf("TestLevelInherit-%p", t) // unique for -count=N
+	parent := GetLogger(name)
+	parent.SetLevel(LevelWarn)
+	child := GetLogger(name + ".child")
+	assert.Eq("named loggers inherit", child.Level, LevelInherit)
+	assert.Eq("inherited at creation", child.GetLevel(), LevelWarn)
+
+	sub := child.SubLogger("[sub]")
+	parent.SetLevel(LevelDebug)
+	assert.Eq("inherited", child.GetLevel(), LevelDebug)
+	assert.Eq("sub-logger inherits", sub.GetLevel(), LevelDebug)
+	assert.Ok("enabled", child.Enabled(LevelDebug))
+
+	child.SetLevel(LevelError)
+	parent.SetLevel(LevelInfo)
+	assert.Eq("explicit", child.GetLevel(), LevelError)
+
+	var level Level
+	assert.NoErr("parse", level.UnmarshalText([]byte("Inherit")))
+	assert.Eq("parsed", level, LevelInherit)
+	assert.Eq("name", LevelInherit.String(), "inherit")
+
+	l := NewLogger(nil, "", LevelInherit, 0)
+	defer l.Close()
+	assert.Eq("no parent", l.GetLevel(), LevelInfo)
+}
+
+func TestGetLoggerInheritsOutput(t *testing.T) {
+	assert := testutil.NewAssert(t)
+
+	// created before RootLogger is configured, like a package-level variable
+	name := fmt.Sprintf("TestInheritOutput-%p", t) // unique for -count=N
+	l := GetLogger(name)
+	child := GetLogger(name + ".child")
+	sub := l.SubLogger("[sub]")
+
+	prevWriter, prevFeatures := RootLogger.Writer(), RootLogger.GetFeatures()
+	defer func() {
+		RootLogger.SetWriter(prevWriter)
+		RootLogger.SetFeatures(prevFeatures)
+	}()
+	w := &bytes.Buffer{}
+	RootLogger.SetWriter(w)
+	RootLogger.SetFeatures(FPrefixWarn)
+	l.Warn("a")
+	child.Warn("b")
+	sub.Warn("c")
+	RootLogger.Sync()
+	assert.Eq("inherited", w.String(), ""+
+		"[warn] ["+name+"] a\n"+
+		"[warn] ["+name+".child] b\n"+
+		"[warn] ["+name+"][sub] c\n")
+
+	// a logger with its own writer and features no longer follows its parent
+	w.Reset()
+	w2 := &bytes.Buffer{}
+	child.SetWriter(w2)
+	child.SetFeatures(0)
+	RootLogger.SetFeatures(FPrefixWarn | FPrefixInfo)
+	l.Info("d")
+	child.Info("e")
+	RootLogger.Sync()
+	assert.Eq("parent", w.String(), "[info] ["+name+"] d\n")
+	assert.Eq("own writer", w2.String(), "["+name+".child] e\n")
+	assert.Eq("own features", child.GetFeatures(), Features(0))
+}
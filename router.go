@@ -0,0 +1,125 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+// LevelRouter is a writer which routes messages to different writers by level. For example,
+// to write warnings and errors to stderr and other messages to stdout:
+//
+//   logger.SetWriter(log.NewLevelRouter(os.Stdout).Route(log.LevelWarn, os.Stderr))
+//
+// Messages are formatted according to the logger's features. When FColorAuto is enabled,
+// colors are only used if all writers are terminals which support colors.
+type LevelRouter struct {
+	routes []levelRoute // ordered by level, highest first; last route is for all levels
+}
+
+type levelRoute struct {
+	level Level // minimum level
+	w     io.Writer
+}
+
+// NewLevelRouter returns a LevelRouter which writes all messages to w, until routes are added
+// with Route
+func NewLevelRouter(w io.Writer) *LevelRouter {
+	return &LevelRouter{routes: []levelRoute{{LevelDebug, w}}}
+}
+
+// Route routes messages of level and above to w (unless a route for a higher level is also
+// added.) Returns r for convenience. Routes should be added before r is used by a logger.
+func (r *LevelRouter) Route(level Level, w io.Writer) *LevelRouter {
+	for i := range r.routes {
+		if r.routes[i].level == level {
+			r.routes[i].w = w
+			return r
+		}
+	}
+	r.routes = append(r.routes, levelRoute{level, w})
+	sort.SliceStable(r.routes, func(i, j int) bool { return r.routes[i].level > r.routes[j].level })
+	return r
+}
+
+// Writer returns the writer of messages of level
+func (r *LevelRouter) Writer(level Level) io.Writer {
+	for _, rt := range r.routes {
+		if level.featureLevel() >= rt.level {
+			return rt.w
+		}
+	}
+	return r.routes[len(r.routes)-1].w
+}
+
+func (r *LevelRouter) writeRecord(m *logRecord) error {
+	w := r.Writer(m.level)
+	if rw, ok := w.(recordWriter); ok {
+		return rw.writeRecord(m)
+	}
+	b := getBuffer()
+	m.format(&b.B)
+	_, err := writeBuffer(w, b)
+	return err
+}
+
+// Write writes p to the writer of LevelInfo
+func (r *LevelRouter) Write(p []byte) (int, error) {
+	return r.Writer(LevelInfo).Write(p)
+}
+
+// Sync syncs all writers which have a Sync method. See FFsync
+func (r *LevelRouter) Sync() error {
+	var err error
+	for _, rt := range r.routes {
+		if e := syncWriter(rt.w); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close closes all writers which implement io.Closer, except for os.Stdout and os.Stderr
+func (r *LevelRouter) Close() error {
+	var err error
+	for _, rt := range r.routes {
+		if c, ok := rt.w.(io.Closer); ok && rt.w != os.Stdout && rt.w != os.Stderr {
+			if e := c.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// supportsColor returns true if all writers are terminals which support colors
+func (r *LevelRouter) supportsColor() bool {
+	for _, rt := range r.routes {
+		if featuresWithAutoColor(rt.w, 0)&FColor == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// RouteLevel routes messages of level and above to w, by making the writer of l a LevelRouter
+// if it isn't already one. For example:
+//
+//   logger.RouteLevel(log.LevelWarn, os.Stderr) // warnings and errors to stderr
+//
+func (l *Logger) RouteLevel(level Level, w io.Writer) {
+	r, ok := l.Writer().(*LevelRouter)
+	if ok {
+		// copy since r may be in use
+		r = &LevelRouter{routes: append([]levelRoute(nil), r.routes...)}
+	} else {
+		r = NewLevelRouter(l.Writer())
+	}
+	l.SetWriter(r.Route(level, w))
+}
+
+// NewStdSplitLogger makes a new logger which writes warnings and errors to os.Stderr and
+// other messages to os.Stdout, which is the convention for command-line programs
+func NewStdSplitLogger(prefix string, level Level, feats Features) *Logger {
+	return NewLogger(NewLevelRouter(os.Stdout).Route(LevelWarn, os.Stderr), prefix, level, feats)
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestLevelRouter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	out, errout := &bytes.Buffer{}, &bytes.Buffer{}
+	var feats Features = FPrefixWarn | FPrefixError
+	l := NewLogger(out, "[p]", LevelDebug, feats)
+	defer l.Close()
+	l.RouteLevel(LevelWarn, errout)
+	now := time.Now()
+	l.SetClock(func() time.Time { return now })
+
+	l.Debug("d")
+	l.Info("i")
+	l.Warn("w")
+	l.Error("e")
+	l.Time("t")()
+	l.Sync()
+	assert.Eq("stdout", out.String(), "[p] d\n[p] i\n[p] t: 0s\n")
+	assert.Eq("stderr", errout.String(), "[warn] [p] w\n[error] [p] e\n")
+
+	r := NewLevelRouter(out).Route(LevelError, errout).Route(LevelWarn, out)
+	assert.Eq("debug", r.Writer(LevelDebug), out)
+	assert.Eq("warn", r.Writer(LevelWarn), out)
+	assert.Eq("error", r.Writer(LevelError), errout)
+}
@@ -0,0 +1,153 @@
+package log
+
+import "sync/atomic"
+
+// A sharded queue (see Options.Shards) has several channels instead of one, so that goroutines
+// logging at the same time contend for one of several channel locks rather than for a single
+// one. Every record sent gets a sequence number and is sent on the channel of that number, in
+// turn, so the writeLoop knows which channel the next record arrives on and receives records
+// in the order they were numbered. Each goroutine's records are thus written in the order it
+// logged them. Since goroutines race between taking a number and sending, a record may arrive
+// before records with lower numbers on the same channel; it is held in early until its turn.
+//
+// The writeLoop waits for every number. When a record which has been numbered is not sent
+// after all, e.g. because its context was cancelled while waiting for room in the queue, a
+// placeholder (ctlSkip) is sent in its place; see skip.
+
+// shardSet holds the channels of a sharded queue
+type shardSet struct {
+	chs     []chan *logRecord     // chs[0] is the queue's ch
+	seq     uint64                // number of the last record sent; accessed atomically
+	next    uint64                // number of the next record to receive; owned by writeLoop
+	early   map[uint64]*logRecord // received before their turn; owned by writeLoop
+	drained bool                  // chs are closed and all records have been moved to early
+}
+
+func newShardSet(ch chan *logRecord, n int) *shardSet {
+	s := &shardSet{
+		chs:   make([]chan *logRecord, n),
+		next:  1,
+		early: make(map[uint64]*logRecord),
+	}
+	s.chs[0] = ch
+	for i := 1; i < n; i++ {
+		s.chs[i] = make(chan *logRecord, cap(ch))
+	}
+	return s
+}
+
+// shardCount returns the number of channels of q
+func (q *queue) shardCount() int {
+	if q.shards == nil {
+		return 1
+	}
+	return len(q.shards.chs)
+}
+
+// input returns the channel to send m on. q.mu must be held for reading, and m must either be
+// sent on the returned channel before q.mu is released or be passed to skip.
+func (q *queue) input(m *logRecord) chan *logRecord {
+	s := q.shards
+	if s == nil {
+		return q.ch
+	}
+	m.seq = atomic.AddUint64(&s.seq, 1)
+	return s.chs[m.seq%uint64(len(s.chs))]
+}
+
+// skip is called instead of sending m on ch, the channel returned by input for m, e.g. when m
+// is dropped. In a sharded queue, a placeholder is sent on ch in its place, once there is room.
+// Must be called before m is freed.
+func (q *queue) skip(ch chan *logRecord, m *logRecord) {
+	if q.shards == nil {
+		return
+	}
+	p := logRecordFree.Get().(*logRecord)
+	p.level = ctlSkip
+	p.seq = m.seq
+	go func() {
+		q.mu.RLock()
+		if q.closed {
+			p.free() // the writeLoop doesn't wait for missing numbers once the queue is closed
+		} else {
+			ch <- p
+		}
+		q.mu.RUnlock()
+	}()
+}
+
+// next returns the next record if it has been received already (see accept), or else the
+// channel to receive it from. Only called by the writeLoop.
+func (q *queue) next() (*logRecord, <-chan *logRecord) {
+	s := q.shards
+	if s == nil {
+		return nil, q.ch
+	}
+	for len(s.early) > 0 {
+		m := s.early[s.next]
+		if m == nil {
+			if !s.drained {
+				break
+			}
+			s.next = s.lowestEarly() // the records in between were never sent
+			continue
+		}
+		delete(s.early, s.next)
+		atomic.AddInt32(&q.held, -1)
+		s.next++
+		if m.level == ctlSkip {
+			m.free()
+			continue
+		}
+		return m, nil
+	}
+	if s.drained {
+		return nil, q.ch // closed
+	}
+	return nil, s.chs[s.next%uint64(len(s.chs))]
+}
+
+// accept returns m, received from the channel returned by next, if it's the next record.
+// Otherwise m is held until its turn and accept returns nil, true. Returns false once the
+// queue is closed and all records have been returned.
+func (q *queue) accept(m *logRecord, ok bool) (*logRecord, bool) {
+	s := q.shards
+	if s == nil {
+		return m, ok
+	}
+	if !ok {
+		if s.drained {
+			return nil, false
+		}
+		// closed; move what remains in the other channels to early
+		for _, ch := range s.chs {
+			for m := range ch {
+				s.early[m.seq] = m
+				atomic.AddInt32(&q.held, 1)
+			}
+		}
+		s.drained = true
+		return nil, true
+	}
+	if m.seq != s.next {
+		s.early[m.seq] = m
+		atomic.AddInt32(&q.held, 1)
+		return nil, true
+	}
+	s.next++
+	if m.level == ctlSkip {
+		m.free()
+		return nil, true
+	}
+	return m, true
+}
+
+func (s *shardSet) lowestEarly() uint64 {
+	var lowest uint64
+	for seq := range s.early {
+		if lowest == 0 || seq < lowest {
+			lowest = seq
+		}
+	}
+	return lowest
+}
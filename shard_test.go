@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestShards(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewWith(WithWriter(w), WithFeatures(0), WithQueueSize(4), WithShards(4))
+	assert.Eq("shards", l.q.shardCount(), 4)
+
+	// messages are written in the order they were logged
+	for i := 0; i < 100; i++ {
+		l.Info("%d", i)
+	}
+	assert.NoErr("sync", l.Sync())
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	assert.Eq("lines", len(lines), 100)
+	for i, line := range lines {
+		if line != strconv.Itoa(i) {
+			t.Fatalf("line %d: %q", i, line)
+		}
+	}
+
+	// messages of each goroutine are written in order
+	w.Reset()
+	var wg sync.WaitGroup
+	for g := 0; g < 20; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			sub := l.SubLogger(fmt.Sprintf("g%d", g))
+			for i := 0; i < 50; i++ {
+				sub.Info("%d", i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.NoErr("sync timeout", l.SyncTimeout(5*time.Second))
+	next := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(w.String()), "\n") {
+		f := strings.Fields(line)
+		if n, _ := strconv.Atoi(f[1]); n != next[f[0]] {
+			t.Fatalf("%s: got %d, expected %d", f[0], n, next[f[0]])
+		}
+		next[f[0]]++
+	}
+	assert.Eq("goroutines", len(next), 20)
+
+	// a child with its own writer is sharded too
+	c := l.Child(ChildOptions{Writer: &bytes.Buffer{}})
+	assert.Eq("child shards", c.q.shardCount(), 4)
+	assert.NoErr("close child", c.Close())
+
+	// records queued when the logger is closed are written
+	w.Reset()
+	for i := 0; i < 10; i++ {
+		l.Info("%d", i)
+	}
+	assert.NoErr("close", l.Close())
+	assert.Eq("written on close", w.String(), "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n")
+	l.Info("after close")
+	assert.Eq("queue depth", l.Metrics().QueueDepth, 0)
+}
+
+func TestShardsSkip(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	bw := &blockingWriter{unblock: make(chan struct{})}
+	l := New(Options{Writer: bw, QueueSize: 1, Shards: 2})
+
+	// fill the queue (and the writeLoop)
+	for l.Metrics().QueueDepth < 2 {
+		l.Info("filler")
+		time.Sleep(time.Millisecond)
+	}
+
+	// a dropped message and a timed out sync leave gaps in the sequence numbers
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	noDeadlock(t, func() { l.InfoContext(ctx, "abandoned") })
+	assert.Eq("dropped", l.Metrics().Dropped, uint64(1))
+	assert.Eq("sync timeout", l.SyncTimeout(10*time.Millisecond), ErrSyncTimeout)
+
+	// which must not stop later messages from being written
+	close(bw.unblock)
+	noDeadlock(t, func() {
+		l.Info("after")
+		assert.NoErr("sync", l.Sync())
+	})
+	assert.Ok("written", strings.HasSuffix(bw.String(), "after\n"))
+	assert.NoErr("close", l.Close())
+}
+
+func TestShardsPriority(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := New(Options{Writer: w, Level: LevelDebug, QueueSize: 10, Shards: 3, PriorityWindow: 10})
+	l.Batch(func(b *Batch) {
+		b.Debug("a")
+		b.Debug("b")
+	})
+	l.Debug("c")
+	l.Error("d")
+	assert.NoErr("close", l.Close())
+	out := w.String()
+	assert.Eq("lines", strings.Count(out, "\n"), 4)
+	assert.Ok("batch first", strings.HasPrefix(out, "a\nb\n"))
+}
@@ -0,0 +1,9 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package log
+
+// onHangup does nothing since there is no SIGHUP on this platform (e.g. js/wasm)
+func onHangup(f func()) (stop func()) {
+	return func() {}
+}
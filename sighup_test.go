@@ -0,0 +1,27 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package log
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestOnHangup(t *testing.T) {
+	called := make(chan struct{}, 1)
+	stop := onHangup(func() { called <- struct{}{} })
+	defer stop()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("f not called on SIGHUP")
+	}
+	stop()
+	stop() // may be called more than once
+}
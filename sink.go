@@ -0,0 +1,60 @@
+package log
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// Sink is implemented by custom backends which receive records rather than formatted text,
+// for example to send log messages to a service or to store them in a database.
+// Use SinkWriter to make a logger write to a Sink:
+//
+//   logger.SetWriter(log.SinkWriter(mySink))
+//
+// Write is called from the logger's write goroutine, one record at a time. r is only valid
+// for the duration of the call.
+// A Sink which implements Sync() error or io.Closer is synced or closed with the logger.
+type Sink interface {
+	Write(r *Record) error
+}
+
+// SinkWriter returns a writer which passes records to s.
+// Output written to it with Write (e.g. via io.MultiWriter or a Go log.Logger) is passed
+// to s as LevelInfo records, one per line.
+func SinkWriter(s Sink) io.Writer {
+	return &sinkWriter{s}
+}
+
+type sinkWriter struct {
+	s Sink
+}
+
+func (w *sinkWriter) writeRecord(m *logRecord) error {
+	r := m.record()
+	return w.s.Write(&r)
+}
+
+func (w *sinkWriter) Write(p []byte) (int, error) {
+	now := time.Now()
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		if err := w.s.Write(&Record{Level: LevelInfo, Time: now, Msg: line}); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *sinkWriter) Sync() error {
+	if s, ok := w.s.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (w *sinkWriter) Close() error {
+	if c, ok := w.s.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+type testSink struct {
+	records []Record
+	closed  bool
+}
+
+func (s *testSink) Write(r *Record) error {
+	s.records = append(s.records, *r)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := &testSink{}
+	l := NewLogger(SinkWriter(sink), "[app] ", LevelDebug, FDefault|FDebugOrigin)
+	l.Info("hello %d", 1)
+	l.Debug("debug")
+	l.Sync()
+	assert.Eq("records", len(sink.records), 2)
+	assert.Eq("level", sink.records[0].Level, LevelInfo)
+	assert.Eq("prefix", sink.records[0].Prefix, "[app] ")
+	assert.Eq("msg", sink.records[0].Msg, "hello 1")
+	assert.Eq("no caller", sink.records[0].Caller, "")
+	assert.Eq("debug msg", sink.records[1].Msg, "debug")
+	assert.Ok("debug caller", strings.Contains(sink.records[1].Caller, "sink_test.go:"))
+
+	l.Writer().Write([]byte("a\nb\n"))
+	assert.Eq("plain write", len(sink.records), 4)
+	assert.Eq("plain write msg", sink.records[3].Msg, "b")
+
+	l.Close()
+	assert.Ok("closed", sink.closed)
+}
+
+func TestDebugOrigin(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf strings.Builder
+	l := NewLogger(&buf, "", LevelDebug, FDebugOrigin)
+	l.Debug("x")
+	l.Sync()
+	s := buf.String()
+	assert.Ok("origin after msg", strings.HasPrefix(s, "x (") && strings.Contains(s, "sink_test.go:"))
+}
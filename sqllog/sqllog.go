@@ -0,0 +1,441 @@
+// Package sqllog logs the queries a program runs with database/sql to a go-log Logger, by
+// wrapping the database driver:
+//
+//   sql.Register("postgres+log", sqllog.Wrap(&pq.Driver{}, logger, nil))
+//   db, err := sql.Open("postgres+log", dsn)
+//
+// or its connector:
+//
+//   db := sql.OpenDB(sqllog.WrapConnector(connector, logger, nil))
+//
+// Each query and statement is logged at LevelDebug once it has completed, with its arguments,
+// the number of rows returned or affected, and how long it took:
+//
+//   [debug] SELECT name FROM users WHERE id = $1 [42]: 1 rows in 1.3ms
+//
+// The time of a query includes reading its rows, until the rows are closed. Arguments are
+// formatted into the message, so that redactors of the logger (see Logger.AddRedactor) apply to
+// them; set Options.HideArgs to not log them at all. This package does not depend on a driver.
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/rsms/go-log"
+)
+
+// Options configures logging of queries. The zero value logs all queries with their arguments
+// at LevelDebug.
+type Options struct {
+	HideArgs  bool          // log "?" instead of the values of arguments
+	SlowQuery time.Duration // queries which take at least this long are logged as warnings
+}
+
+// Wrap returns a driver which logs queries run with d to l. opts may be nil.
+func Wrap(d driver.Driver, l *log.Logger, opts *Options) driver.Driver {
+	lg := newLogger(l, opts)
+	if _, ok := d.(driver.DriverContext); ok {
+		return &driverContext{wrappedDriver{d, lg}}
+	}
+	return &wrappedDriver{d, lg}
+}
+
+// WrapConnector returns a connector which logs queries run with connections of c to l, for
+// sql.OpenDB. opts may be nil.
+func WrapConnector(c driver.Connector, l *log.Logger, opts *Options) driver.Connector {
+	return &connector{c, newLogger(l, opts), nil}
+}
+
+type logger struct {
+	l    *log.Logger
+	opts Options
+}
+
+func newLogger(l *log.Logger, opts *Options) *logger {
+	lg := &logger{l: l}
+	if opts != nil {
+		lg.opts = *opts
+	}
+	return lg
+}
+
+// log logs a completed query. rows is the number of rows returned or affected, or -1 if
+// unknown.
+func (lg *logger) log(query string, args []driver.NamedValue, rows int64, err error, d time.Duration) {
+	level := log.LevelDebug
+	if lg.opts.SlowQuery > 0 && d >= lg.opts.SlowQuery {
+		level = log.LevelWarn
+	}
+	if !lg.l.Enabled(level) || err == driver.ErrSkip {
+		return
+	}
+	query = strings.TrimSpace(query)
+	switch {
+	case err != nil:
+		lg.l.Log(level, "%s %s: failed after %s: %v", query, lg.formatArgs(args), log.Dur(d), err)
+	case rows < 0:
+		lg.l.Log(level, "%s %s: %s", query, lg.formatArgs(args), log.Dur(d))
+	default:
+		lg.l.Log(level, "%s %s: %d rows in %s", query, lg.formatArgs(args), rows, log.Dur(d))
+	}
+}
+
+func (lg *logger) formatArgs(args []driver.NamedValue) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, a := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if a.Name != "" {
+			b.WriteString(a.Name)
+			b.WriteByte('=')
+		}
+		switch v := a.Value.(type) {
+		case string:
+			if lg.opts.HideArgs {
+				b.WriteByte('?')
+			} else {
+				fmt.Fprintf(&b, "%q", v)
+			}
+		case []byte:
+			fmt.Fprintf(&b, "<%d bytes>", len(v))
+		default:
+			if lg.opts.HideArgs {
+				b.WriteByte('?')
+			} else {
+				fmt.Fprint(&b, v)
+			}
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+type wrappedDriver struct {
+	driver.Driver
+	lg *logger
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{c, d.lg}, nil
+}
+
+// driverContext wraps a driver which implements driver.DriverContext
+type driverContext struct {
+	wrappedDriver
+}
+
+func (d *driverContext) OpenConnector(name string) (driver.Connector, error) {
+	c, err := d.Driver.(driver.DriverContext).OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &connector{c, d.lg, d}, nil
+}
+
+type connector struct {
+	c  driver.Connector
+	lg *logger
+	d  driver.Driver // the wrapped driver, or nil to wrap the driver of c
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{dc, c.lg}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	if c.d != nil {
+		return c.d
+	}
+	return &wrappedDriver{c.c.Driver(), c.lg}
+}
+
+// Close closes the wrapped connector if it implements io.Closer; called by sql.DB.Close
+func (c *connector) Close() error {
+	if cl, ok := c.c.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
+
+type conn struct {
+	driver.Conn
+	lg *logger
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{s, query, c.lg}, nil
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	cp, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	s, err := cp.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{s, query, c.lg}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if cb, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return cb.BeginTx(ctx, opts)
+	}
+	// like database/sql does for drivers without BeginTx
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *conn) ExecContext(
+	ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var r driver.Result
+	var err error
+	if ec, ok := c.Conn.(driver.ExecerContext); ok {
+		r, err = ec.ExecContext(ctx, query, args)
+	} else if e, ok := c.Conn.(driver.Execer); ok {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			r, err = e.Exec(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip // database/sql prepares a statement instead
+	}
+	c.lg.log(query, args, rowsAffected(r, err), err, time.Since(start))
+	return r, err
+}
+
+func (c *conn) QueryContext(
+	ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var r driver.Rows
+	var err error
+	if qc, ok := c.Conn.(driver.QueryerContext); ok {
+		r, err = qc.QueryContext(ctx, query, args)
+	} else if q, ok := c.Conn.(driver.Queryer); ok {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			r, err = q.Query(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	if err != nil {
+		c.lg.log(query, args, -1, err, time.Since(start))
+		return nil, err
+	}
+	return &rows{Rows: r, lg: c.lg, query: query, args: args, start: start}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type stmt struct {
+	driver.Stmt
+	query string
+	lg    *logger
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var r driver.Result
+	var err error
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		r, err = ec.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			r, err = s.Stmt.Exec(values)
+		}
+	}
+	s.lg.log(s.query, args, rowsAffected(r, err), err, time.Since(start))
+	return r, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var r driver.Rows
+	var err error
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		r, err = qc.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			r, err = s.Stmt.Query(values)
+		}
+	}
+	if err != nil {
+		s.lg.log(s.query, args, -1, err, time.Since(start))
+		return nil, err
+	}
+	return &rows{Rows: r, lg: s.lg, query: s.query, args: args, start: start}, nil
+}
+
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	if cc, ok := s.Stmt.(driver.ColumnConverter); ok {
+		v, err := cc.ColumnConverter(nv.Ordinal - 1).ConvertValue(nv.Value)
+		if err != nil {
+			return err
+		}
+		nv.Value = v
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+// rows counts the rows read and logs the query when closed. The optional interfaces of
+// driver.Rows are forwarded, with the defaults of database/sql for rows which lack them.
+type rows struct {
+	driver.Rows
+	lg    *logger
+	query string
+	args  []driver.NamedValue
+	start time.Time
+	n     int64
+	err   error // error from Next other than io.EOF
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == nil {
+		r.n++
+	} else if err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	r.lg.log(r.query, r.args, r.n, r.err, time.Since(r.start))
+	return err
+}
+
+func (r *rows) HasNextResultSet() bool {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *rows) NextResultSet() error {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *rows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// namedValues converts args for drivers which don't support named arguments
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
+
+// rowsAffected returns the number of rows affected according to r, or -1 if unknown
+func rowsAffected(r driver.Result, err error) int64 {
+	if err != nil || r == nil {
+		return -1
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,103 @@
+package sqllog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-log"
+	"github.com/rsms/go-testutil"
+)
+
+// fakeConn returns two rows from each query and affects three rows with each exec
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("no prepare") }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no tx") }
+
+func (fakeConn) QueryContext(
+	ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{n: 2}, nil
+}
+
+func (fakeConn) ExecContext(
+	ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.HasPrefix(query, "BAD") {
+		return nil, errors.New("syntax error")
+	}
+	return driver.RowsAffected(3), nil
+}
+
+type fakeRows struct{ n int }
+
+func (r *fakeRows) Columns() []string { return []string{"x"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		return io.EOF
+	}
+	r.n--
+	dest[0] = int64(1)
+	return nil
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+// driverConnector opens connections with a driver, like sql.Open does with registered drivers,
+// which can't be unregistered and would thus make the test fail when run more than once
+type driverConnector struct{ d driver.Driver }
+
+func (c driverConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c driverConnector) Driver() driver.Driver                        { return c.d }
+
+func TestWrap(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf bytes.Buffer
+	l := log.NewLogger(&buf, "", log.LevelDebug, log.FPrefixDebug|log.FPrefixWarn)
+	db := sql.OpenDB(driverConnector{Wrap(fakeDriver{}, l, nil)})
+	rows, err := db.Query("SELECT x FROM t WHERE a = ? AND b = ?", 42, "bob")
+	assert.NoErr("query", err)
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	assert.Eq("rows", n, 2)
+	assert.NoErr("close rows", rows.Close())
+	_, err = db.Exec("DELETE FROM t")
+	assert.NoErr("exec", err)
+	_, err = db.Exec("BAD")
+	assert.Err("exec", "syntax error", err)
+	assert.NoErr("close db", db.Close())
+
+	// hidden arguments and slow queries
+	db = sql.OpenDB(WrapConnector(fakeConnector{}, l, &Options{HideArgs: true, SlowQuery: 1}))
+	_, err = db.ExecContext(context.Background(), "UPDATE t SET a = ?", "secret")
+	assert.NoErr("exec", err)
+	assert.NoErr("close db", db.Close())
+
+	assert.NoErr("close logger", l.Close())
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Eq("lines", len(lines), 4)
+	expect := []string{
+		`[debug] SELECT x FROM t WHERE a = ? AND b = ? [42, "bob"]: 2 rows in `,
+		`[debug] DELETE FROM t []: 3 rows in `,
+		`[debug] BAD []: failed after `,
+		`[warn] UPDATE t SET a = ? [?]: 3 rows in `,
+	}
+	for i := 0; i < len(lines) && i < len(expect); i++ {
+		assert.Ok(lines[i], strings.HasPrefix(lines[i], expect[i]))
+	}
+}
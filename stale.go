@@ -0,0 +1,70 @@
+package log
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// staleReportInterval is how often dropped stale records are reported while the queue is
+// backed up; see SetMaxRecordAge
+const staleReportInterval = 10 * time.Second
+
+// SetMaxRecordAge makes the write goroutine drop debug and info messages which have waited
+// longer than age in the queue, so that when messages are logged faster than they can be
+// written, the queue drains faster and logging goroutines are blocked for less time.
+// Warnings, errors and messages logged with a callback (see LogCB) are always written.
+//
+// The number of dropped messages is reported in a warning when the queue has drained, and at
+// most every 10 seconds while it is backed up. Dropped messages are also counted in
+// Metrics.Dropped. Zero (the default) disables dropping.
+//
+// The setting applies to l, its parent and sub-loggers, which share a queue.
+func (l *Logger) SetMaxRecordAge(age time.Duration) {
+	atomic.StoreInt64(&l.q.maxAge, int64(age))
+}
+
+// staleFilter drops records older than queue.maxAge in writeLoop
+type staleFilter struct {
+	n     int       // number of records dropped since the last report
+	since time.Time // time of the first of those drops
+}
+
+// drop returns true if m is stale, in which case m has been freed
+func (s *staleFilter) drop(l *Logger, m *logRecord) bool {
+	maxAge := time.Duration(atomic.LoadInt64(&l.q.maxAge))
+	if maxAge <= 0 || m.level > LevelInfo || m.done != nil || m.logger.now().Sub(m.time) <= maxAge {
+		return false
+	}
+	if s.n == 0 {
+		s.since = l.now()
+	}
+	s.n++
+	m.logger.metrics.drop()
+	if m.walSeq != 0 {
+		l.q.writeFailed(l.q.wal.written(m.walSeq))
+	}
+	m.free()
+	return true
+}
+
+// report returns a warning about records dropped since the last report, or nil if there are
+// none, or if force is false and the first of them was dropped less than staleReportInterval ago
+func (s *staleFilter) report(l *Logger, force bool) *logRecord {
+	if s.n == 0 {
+		return nil
+	}
+	now := l.now()
+	if !force && now.Sub(s.since) < staleReportInterval {
+		return nil
+	}
+	maxAge := time.Duration(atomic.LoadInt64(&l.q.maxAge))
+	m := logRecordFree.Get().(*logRecord)
+	m.logger = l
+	m.level = LevelWarn
+	m.time = now
+	m.msg = append(m.msg, fmt.Sprintf(
+		"dropped %d debug and info messages which were queued for longer than %s", s.n, maxAge)...)
+	s.n = 0
+	return m
+}
@@ -0,0 +1,36 @@
+package log
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestMaxRecordAge(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var now int64 // seconds
+	clock := func() time.Time { return time.Unix(atomic.LoadInt64(&now), 0) }
+	bw := &blockingWriter{unblock: make(chan struct{})}
+	l := New(Options{Writer: bw, Clock: clock, MaxRecordAge: time.Second, Level: LevelDebug})
+
+	// wedge the writeLoop on the first record
+	l.Info("first")
+	for l.Metrics().QueueDepth > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Debug("old debug")
+	l.Info("old info")
+	l.Warn("old warning")
+	l.LogCB(LevelInfo, func(error) {}, "old with callback")
+	atomic.StoreInt64(&now, 2)
+	l.Info("new")
+
+	close(bw.unblock)
+	assert.NoErr("sync", l.Sync())
+	assert.Eq("written", bw.String(), "first\nold warning\nold with callback\nnew\n"+
+		"dropped 2 debug and info messages which were queued for longer than 1s\n")
+	assert.Eq("dropped", l.Metrics().Dropped, uint64(2))
+	assert.NoErr("close", l.Close())
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+)
+
+// SetStaticFields sets fields which are included with every record logged by l in structured
+// output, like FormatJSON, binary logs and sinks (see Record.Fields), but not in text output.
+// keyvals are key/value pairs. Fields of a message with the same key take precedence.
+// Log aggregators collecting logs of many processes use such fields to tell them apart:
+//
+//   logger.SetStaticFields(log.ProcessFields()...)
+//   logger.SetStaticFields("region", "eu-west-1", "pid", strconv.Itoa(os.Getpid()))
+//
+// Sub-loggers created after the call inherit the fields. Calling SetStaticFields without
+// arguments removes the fields.
+func (l *Logger) SetStaticFields(keyvals ...string) {
+	fields := make([]Field, 0, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields = append(fields, Field{keyvals[i], keyvals[i+1]})
+	}
+	if len(fields) == 0 {
+		fields = nil
+	}
+	l.static = fields
+}
+
+// ProcessFields returns key/value pairs describing the running program, for use with
+// SetStaticFields:
+//
+//   "pid"     the process ID
+//   "host"    the host name, if known
+//   "app"     the name of the program: the last element of the main module's path, or the
+//             name of the executable if the program was built without module support
+//   "version" the version of the main module, if known (e.g. "v1.2.3" when built with
+//             "go install example.com/app@v1.2.3")
+func ProcessFields() []string {
+	kv := []string{"pid", strconv.Itoa(os.Getpid())}
+	if host, err := os.Hostname(); err == nil {
+		kv = append(kv, "host", host)
+	}
+	app := filepath.Base(os.Args[0])
+	version := ""
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Path != "" {
+		app = filepath.Base(info.Main.Path)
+		if info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+	}
+	kv = append(kv, "app", app)
+	if version != "" {
+		kv = append(kv, "version", version)
+	}
+	return kv
+}
+
+// structuredFields returns the fields of m including the static fields of its logger, the
+// field "code" with the error code of m, if any (see ErrorC), and the field "id" with the
+// unique ID of m, if any (see ErrorID)
+func (m *logRecord) structuredFields() []Field {
+	fields := m.fields
+	if len(m.static) > 0 {
+		fields = append(m.static[:len(m.static):len(m.static)], m.fields...)
+	}
+	if len(m.code) > 0 {
+		fields = append(fields[:len(fields):len(fields)], Field{"code", m.code})
+	}
+	if len(m.id) > 0 {
+		fields = append(fields[:len(fields):len(fields)], Field{"id", m.id})
+	}
+	return fields
+}
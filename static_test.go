@@ -0,0 +1,58 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestStaticFields(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	sink := NewMemorySink(10)
+	l := NewLogger(NewTeeWriter(TeeSink{W: w}, TeeSink{W: sink}), "", LevelInfo, 0)
+	defer l.Close()
+	l.SetStaticFields("app", "test", "region", "eu")
+
+	l.Info("a")
+	l.SubLogger("[sub]").WithWorker("2").Info("b")
+	l.Sync()
+	l.SetStaticFields()
+	l.Info("c")
+	l.Sync()
+	assert.Eq("text", w.String(), "a\n[sub] b worker=2\nc\n")
+	r := sink.Records()
+	assert.Eq("fields", len(r[0].Fields), 2)
+	assert.Eq("app", r[0].Fields["app"], "test")
+	assert.Eq("sub-logger", r[1].Fields["region"], "eu")
+	assert.Eq("record fields", r[1].Fields["worker"], "2")
+	assert.Eq("removed", len(r[2].Fields), 0)
+
+	// fields of messages take precedence
+	buf := &bytes.Buffer{}
+	bl := NewLogger(NewBinaryWriter(buf), "", LevelInfo, 0)
+	bl.SetStaticFields("worker", "static", "app", "test")
+	bl.WithWorker("1").Info("d")
+	bl.Close()
+	br, err := NewBinaryReader(bytes.NewReader(buf.Bytes())).Read()
+	assert.NoErr("read", err)
+	assert.Eq("binary", br.Fields["app"], "test")
+	assert.Eq("precedence", br.Fields["worker"], "1")
+}
+
+func TestProcessFields(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	kv := ProcessFields()
+	fields := map[string]string{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		fields[kv[i]] = kv[i+1]
+	}
+	assert.Eq("pid", fields["pid"], strconv.Itoa(os.Getpid()))
+	assert.Ok("app", fields["app"] != "")
+	if host, err := os.Hostname(); err == nil {
+		assert.Eq("host", fields["host"], host)
+	}
+}
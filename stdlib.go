@@ -0,0 +1,71 @@
+package log
+
+import (
+	"log"
+	"strings"
+)
+
+// CaptureStdlib redirects the standard library's logger (the functions of the "log" package
+// and log.Default) to l, so that messages of packages which log with the standard library are
+// formatted and written like messages logged with l. It returns a function which restores the
+// previous output, flags and prefix of the standard library's logger.
+//
+// Messages are logged with the given level, unless they start with a level name in brackets or
+// followed by a colon, like "[warn] disk almost full" or "ERROR: timeout", in which case they
+// are logged with that level and the level name is removed.
+//
+//   log.CaptureStdlib(logger.SubLogger("[stdlib]"), log.LevelInfo)
+//   stdlog.Printf("error: %v", err) // "[error] [stdlib] EOF"
+//
+func CaptureStdlib(l *Logger, level Level) (restore func()) {
+	w, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	log.SetOutput(&stdlibWriter{l, level})
+	log.SetFlags(0) // time, date and origin are added by l
+	log.SetPrefix("")
+	return func() {
+		log.SetOutput(w)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+// stdlibWriter receives messages from a standard library logger. Each call to Write is one
+// message.
+type stdlibWriter struct {
+	l     *Logger
+	level Level
+}
+
+func (w *stdlibWriter) Write(p []byte) (int, error) {
+	level, msg := parseStdlibLevel(strings.TrimSuffix(string(p), "\n"), w.level)
+	w.l.Log(level, "%s", msg)
+	return len(p), nil
+}
+
+// parseStdlibLevel returns the level named at the start of msg, like "[warn] msg" or
+// "ERROR: msg", and msg without it. If msg does not start with a level name, level and msg are
+// returned as they are.
+func parseStdlibLevel(msg string, level Level) (Level, string) {
+	var name, rest string
+	if strings.HasPrefix(msg, "[") {
+		end := strings.IndexByte(msg, ']')
+		if end == -1 {
+			return level, msg
+		}
+		name, rest = msg[1:end], msg[end+1:]
+	} else {
+		end := strings.IndexByte(msg, ':')
+		if end == -1 || end > len("warning") {
+			return level, msg
+		}
+		name, rest = msg[:end], msg[end+1:]
+	}
+	if strings.EqualFold(name, "warning") {
+		name = "warn"
+	}
+	l, err := ParseLevel(name)
+	if err != nil || l == LevelDisable {
+		return level, msg
+	}
+	return l, strings.TrimLeft(rest, " ")
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestCaptureStdlib(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixWarn|FPrefixError)
+	defer l.Close()
+
+	log.SetFlags(log.LstdFlags)
+	restore := CaptureStdlib(l.SubLogger("[std]"), LevelInfo)
+	log.Printf("hello %d", 1)
+	log.Print("[WARN] careful")
+	log.Print("error: failed")
+	log.Print("debug: not logged")
+	log.Print("[note] kept")
+	log.Print("time: 3s")
+	restore()
+	assert.Eq("flags restored", log.Flags(), log.LstdFlags)
+	l.Sync()
+	assert.Eq("output", w.String(), "[std] hello 1\n"+
+		"[warn] [std] careful\n"+
+		"[error] [std] failed\n"+
+		"[std] [note] kept\n"+
+		"[std] time: 3s\n")
+}
@@ -0,0 +1,171 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSyncTimeout is returned by SyncTimeout when messages could not be written in time
+var ErrSyncTimeout = errors.New("log: sync timed out")
+
+// FlushTimeout is the time Flush waits for messages to be written
+var FlushTimeout = 5 * time.Second
+
+// SyncTimeout is like Sync but gives up after d, returning ErrSyncTimeout, for example
+// when the writer is blocked on a hung network file system.
+func (l *Logger) SyncTimeout(d time.Duration) error {
+	timeout := make(chan struct{})
+	t := time.AfterFunc(d, func() { close(timeout) })
+	defer t.Stop()
+	return l.q.sync(timeout)
+}
+
+// SyncResult describes the outcome of SyncContext
+type SyncResult struct {
+	Records  int           // number of messages queued when SyncContext was called
+	Err      error         // last write error, or the error of the context if it was cancelled
+	Duration time.Duration // time spent waiting
+}
+
+// SyncContext is like Sync but gives up when ctx is cancelled, in which case Err is ctx.Err().
+// The result tells how much Sync had to wait for, which helps diagnosing slow writers:
+//
+//   ctx, cancel := context.WithTimeout(ctx, time.Second)
+//   defer cancel()
+//   if r := logger.SyncContext(ctx); r.Err != nil {
+//     fmt.Fprintf(os.Stderr, "log sync: %v (%d messages, %s)\n", r.Err, r.Records, r.Duration)
+//   }
+//
+func (l *Logger) SyncContext(ctx context.Context) SyncResult {
+	start := time.Now()
+	r := SyncResult{Records: l.q.len()}
+	r.Err = l.q.sync(ctx.Done())
+	if r.Err == ErrSyncTimeout {
+		r.Err = ctx.Err()
+	}
+	r.Duration = time.Since(start)
+	return r
+}
+
+// SyncTimeout waits for the messages of all loggers which have not been closed to be written,
+// giving up after d. Returns the first error encountered, or ErrSyncTimeout if not all
+// loggers finished writing within d.
+func SyncTimeout(d time.Duration) error {
+	timeout := make(chan struct{})
+	t := time.AfterFunc(d, func() { close(timeout) })
+	defer t.Stop()
+	queues := openQueues.list()
+	errs := make([]error, len(queues))
+	var wg sync.WaitGroup
+	wg.Add(len(queues))
+	for i, q := range queues {
+		go func(i int, q *queue) {
+			defer wg.Done()
+			errs[i] = q.sync(timeout)
+		}(i, q)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush waits for the messages of all loggers to be written, or for at most FlushTimeout.
+// Call Flush before a program exits to make sure messages are not lost without risking
+// that shutdown hangs on a wedged writer:
+//
+//   func main() {
+//     defer log.Flush()
+//     ...
+//   }
+//
+func Flush() error {
+	return SyncTimeout(FlushTimeout)
+}
+
+// sync waits for all queued records to be written and returns the last write error.
+// If timeout is non-nil, sync gives up and returns ErrSyncTimeout when timeout is closed.
+// Each call has its own reply channel, so that concurrent calls don't receive each other's
+// replies, which could make a call return before the records it waits for are written.
+func (q *queue) sync(timeout <-chan struct{}) error {
+	if q.writing.containsCurrent() {
+		return nil // called while writing; the writeLoop would wait for us and we for it
+	}
+	m := logRecordFree.Get().(*logRecord)
+	m.level = ctlSync
+	ch := make(chan error, 1) // buffered so that the writeLoop never blocks on it
+	m.ctlarg = ch
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		m.free()
+		select {
+		case <-q.done:
+			return q.err
+		case <-timeout:
+			return ErrSyncTimeout
+		}
+	}
+	input := q.input(m)
+	select {
+	case input <- m:
+	case <-timeout:
+		q.skip(input, m)
+		q.mu.RUnlock()
+		m.free()
+		return ErrSyncTimeout
+	}
+	q.mu.RUnlock()
+	select {
+	case err := <-ch:
+		return err
+	case <-timeout:
+		return ErrSyncTimeout
+	}
+}
+
+// openQueues holds the queues of loggers which have not been closed (see SyncTimeout),
+// mapped to the logger which owns the queue (see Exit)
+var openQueues = queueSet{m: make(map[*queue]*Logger)}
+
+type queueSet struct {
+	mu sync.Mutex
+	m  map[*queue]*Logger
+}
+
+func (s *queueSet) add(l *Logger) {
+	s.mu.Lock()
+	s.m[l.q] = l
+	s.mu.Unlock()
+}
+
+func (s *queueSet) remove(q *queue) {
+	s.mu.Lock()
+	delete(s.m, q)
+	s.mu.Unlock()
+}
+
+func (s *queueSet) list() []*queue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queues := make([]*queue, 0, len(s.m))
+	for q := range s.m {
+		queues = append(queues, q)
+	}
+	return queues
+}
+
+func (s *queueSet) loggers() []*Logger {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	loggers := make([]*Logger, 0, len(s.m))
+	for _, l := range s.m {
+		loggers = append(loggers, l)
+	}
+	return loggers
+}
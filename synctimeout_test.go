@@ -0,0 +1,92 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+// blockingWriter blocks writes until unblock is closed
+type blockingWriter struct {
+	bytes.Buffer
+	unblock chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.unblock
+	return w.Buffer.Write(p)
+}
+
+func TestSyncTimeout(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	l.Info("a")
+	assert.NoErr("sync", l.SyncTimeout(time.Second))
+	assert.Eq("written", w.String(), "a\n")
+
+	bw := &blockingWriter{unblock: make(chan struct{})}
+	l2 := NewLogger(bw, "", LevelInfo, 0)
+	l2.Info("b")
+	assert.Eq("wedged writer", l2.SyncTimeout(10*time.Millisecond), ErrSyncTimeout)
+	assert.Eq("all loggers", SyncTimeout(10*time.Millisecond), ErrSyncTimeout)
+
+	close(bw.unblock)
+	assert.NoErr("all loggers after unblocking", SyncTimeout(time.Second))
+	assert.Eq("written after unblocking", bw.String(), "b\n")
+
+	assert.NoErr("close", l2.Close())
+	assert.NoErr("close", l.Close())
+	assert.NoErr("sync closed logger", l.SyncTimeout(time.Second))
+	_, open := openQueues.m[l.q]
+	assert.Ok("closed logger unregistered", !open)
+}
+
+func TestSyncContext(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	bw := &blockingWriter{unblock: make(chan struct{})}
+	l := NewLogger(bw, "", LevelInfo, 0)
+	defer l.Close()
+	l.Info("a")
+	l.Info("b")
+	time.Sleep(10 * time.Millisecond) // let writeLoop take "a"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	r := l.SyncContext(ctx)
+	assert.Eq("err", r.Err, context.DeadlineExceeded)
+	assert.Eq("records", r.Records, 1)
+	assert.Ok("duration", r.Duration >= 10*time.Millisecond)
+
+	close(bw.unblock)
+	r = l.SyncContext(context.Background())
+	assert.NoErr("err", r.Err)
+	assert.Eq("written", bw.String(), "a\nb\n")
+}
+
+func TestSyncConcurrent(t *testing.T) {
+	sink := NewMemorySink(100)
+	l := NewLogger(sink, "", LevelInfo, 0)
+	defer l.Close()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				msg := fmt.Sprintf("g%d-%d", g, i)
+				l.Info("%s", msg)
+				l.Sync()
+				if !sink.Contains(LevelInfo, msg) {
+					t.Errorf("%q not written when Sync returned", msg)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
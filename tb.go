@@ -0,0 +1,100 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrFailNow is the value TB.FailNow panics with
+var ErrFailNow = errors.New("log: FailNow called")
+
+// TB implements the logging and failure methods of testing.TB by logging to a Logger, so that
+// test helpers written against an interface with those methods can be used outside of tests,
+// e.g. by a program which checks a deployment with the checks of its integration tests:
+//
+//   type T interface {
+//     Helper()
+//     Logf(format string, args ...interface{})
+//     Errorf(format string, args ...interface{})
+//     Fatalf(format string, args ...interface{})
+//   }
+//
+//   func checkHealth(t T, url string) { ... }
+//
+//   tb := logger.TB("healthcheck")
+//   checkHealth(tb, url)
+//   if tb.Failed() {
+//     os.Exit(1)
+//   }
+//
+// Log and Logf log at LevelInfo, and Error and Errorf at LevelError and mark tb as failed.
+// Fatal and Fatalf do the same and then call FailNow, which syncs the logger and panics with
+// ErrFailNow, since the goroutine can't be stopped like a test's. Recover from it where the
+// test helper is called.
+type TB struct {
+	l      *Logger
+	name   string
+	failed int32 // accessed atomically
+}
+
+// TB returns a TB which logs to l. name is returned by Name.
+func (l *Logger) TB(name string) *TB {
+	return &TB{l: l, name: name}
+}
+
+// Name returns the name given to Logger.TB
+func (tb *TB) Name() string { return tb.name }
+
+// Helper does nothing; it exists for compatibility with testing.TB
+func (tb *TB) Helper() {}
+
+// Log formats its arguments like fmt.Sprintln (without the newline) and logs at LevelInfo
+func (tb *TB) Log(args ...interface{}) { tb.l.Info("%s", sprintln(args)) }
+
+// Logf logs at LevelInfo
+func (tb *TB) Logf(format string, args ...interface{}) { tb.l.Info(format, args...) }
+
+// Error is like Log but logs at LevelError and marks tb as failed
+func (tb *TB) Error(args ...interface{}) {
+	tb.l.Error("%s", sprintln(args))
+	tb.Fail()
+}
+
+// Errorf is like Logf but logs at LevelError and marks tb as failed
+func (tb *TB) Errorf(format string, args ...interface{}) {
+	tb.l.Error(format, args...)
+	tb.Fail()
+}
+
+// Fatal is like Error followed by FailNow
+func (tb *TB) Fatal(args ...interface{}) {
+	tb.Error(args...)
+	tb.FailNow()
+}
+
+// Fatalf is like Errorf followed by FailNow
+func (tb *TB) Fatalf(format string, args ...interface{}) {
+	tb.Errorf(format, args...)
+	tb.FailNow()
+}
+
+// Fail marks tb as failed
+func (tb *TB) Fail() { atomic.StoreInt32(&tb.failed, 1) }
+
+// Failed returns true if tb has been marked as failed
+func (tb *TB) Failed() bool { return atomic.LoadInt32(&tb.failed) != 0 }
+
+// FailNow marks tb as failed, waits for messages to be written (see Logger.Sync) and panics
+// with ErrFailNow
+func (tb *TB) FailNow() {
+	tb.Fail()
+	tb.l.Sync()
+	panic(ErrFailNow)
+}
+
+// sprintln formats args like fmt.Sprintln, without the trailing newline
+func sprintln(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
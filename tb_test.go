@@ -0,0 +1,31 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestTB(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "", LevelInfo, FPrefixError)
+	tb := l.TB("check")
+	assert.Eq("name", tb.Name(), "check")
+	tb.Log("a", 1)
+	tb.Logf("b %d", 2)
+	assert.Ok("not failed", !tb.Failed())
+	tb.Errorf("c")
+	assert.Ok("failed", tb.Failed())
+
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		tb.Fatalf("d %s", "x")
+		t.Error("Fatalf returned")
+	}()
+	assert.Eq("panic value", recovered, ErrFailNow)
+	assert.Eq("written before panicking", buf.String(), "a 1\nb 2\n[error] c\n[error] d x\n")
+	assert.NoErr("close", l.Close())
+}
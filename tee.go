@@ -0,0 +1,127 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// TeeSink is a destination of a TeeWriter with its own level and formatting
+type TeeSink struct {
+	W        io.Writer
+	Level    Level     // minimum level of records written to W
+	Features Features  // formatting features like FTime and FColor, used when Format is nil
+	Format   Formatter // optional formatter, e.g. FormatJSON
+}
+
+// Formatter appends a newline-terminated representation of r to buf
+type Formatter func(buf *[]byte, r *Record)
+
+// FormatJSON is a Formatter which formats records as JSON objects, one per line
+func FormatJSON(buf *[]byte, r *Record) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		data, _ = json.Marshal(err.Error())
+	}
+	*buf = append(*buf, data...)
+	*buf = append(*buf, '\n')
+}
+
+// TeeWriter writes each record to multiple sinks, formatting it separately for each sink.
+// For example, to write colored text to the terminal and JSON to a file:
+//
+//   logger.SetWriter(log.NewTeeWriter(
+//     log.TeeSink{W: os.Stderr, Level: log.LevelInfo, Features: log.FDefault},
+//     log.TeeSink{W: file, Level: log.LevelDebug, Format: log.FormatJSON},
+//   ))
+//
+// Records are formatted in the writeLoop from the record itself, so the features of the
+// logger which affect formatting (like FTime and FColor) are replaced by those of each sink.
+// Sinks which receive records rather than text, like BinaryWriter and OTLPExporter, get the
+// records as-is when Format is nil.
+// The level of the logger still applies before any sink levels.
+type TeeWriter struct {
+	sinks []TeeSink
+}
+
+// NewTeeWriter creates a TeeWriter. FColorAuto of sinks is resolved here, as for NewLogger.
+func NewTeeWriter(sinks ...TeeSink) *TeeWriter {
+	t := &TeeWriter{sinks: make([]TeeSink, len(sinks))}
+	for i, s := range sinks {
+		if s.Features&FColorAuto != 0 {
+			s.Features = featuresWithAutoColor(s.W, s.Features&^FColor)
+		}
+		t.sinks[i] = s
+	}
+	return t
+}
+
+func (t *TeeWriter) writeRecord(m *logRecord) error {
+	var err error
+	var r *Record // lazily created for sinks with a Formatter
+	for i := range t.sinks {
+		s := &t.sinks[i]
+		if m.level < s.Level {
+			continue
+		}
+		if rw, ok := s.W.(recordWriter); ok && s.Format == nil {
+			if e := rw.writeRecord(m); e != nil && err == nil {
+				err = e
+			}
+			continue
+		}
+		b := getBuffer()
+		if s.Format != nil {
+			if r == nil {
+				rec := m.record()
+				r = &rec
+			}
+			s.Format(&b.B, r)
+		} else {
+			m.formatFeatures(&b.B, s.Features)
+		}
+		if _, e := writeBuffer(s.W, b); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Write writes p to all sinks as-is
+func (t *TeeWriter) Write(p []byte) (int, error) {
+	var err error
+	for _, s := range t.sinks {
+		if _, e := s.W.Write(p); e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync syncs all sinks which have a Sync method. See FFsync
+func (t *TeeWriter) Sync() error {
+	var err error
+	for _, s := range t.sinks {
+		if e := syncWriter(s.W); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close closes the writers of all sinks which implement io.Closer, except for os.Stdout and
+// os.Stderr
+func (t *TeeWriter) Close() error {
+	var err error
+	for _, s := range t.sinks {
+		if c, ok := s.W.(io.Closer); ok && s.W != os.Stdout && s.W != os.Stderr {
+			if e := c.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestTeeWriter(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	text := &bytes.Buffer{}
+	color := &bytes.Buffer{}
+	js := &bytes.Buffer{}
+	var feats Features = FPrefixWarn
+	tw := NewTeeWriter(
+		TeeSink{W: text, Level: LevelInfo, Features: feats},
+		TeeSink{W: color, Level: LevelWarn, Features: FColor | feats},
+		TeeSink{W: js, Level: LevelDebug, Format: FormatJSON},
+	)
+	l := NewLogger(tw, "[p]", LevelDebug, FTime|FPrefixInfo)
+	defer l.Close()
+
+	l.Debug("d")
+	l.Info("i")
+	l.Warn("w")
+	l.Sync()
+
+	assert.Eq("text sink", text.String(), "[p] i\n[warn] [p] w\n")
+	assert.Eq("color sink", color.String(), currentTheme().levelPrefix[LevelWarn]+"[p] w\n")
+	dec := json.NewDecoder(js)
+	var r Record
+	var msgs []string
+	for dec.More() {
+		assert.NoErr("json", dec.Decode(&r))
+		msgs = append(msgs, r.Level.String()+":"+r.Msg)
+	}
+	assert.Eq("json sink", len(msgs), 3)
+	assert.Eq("json record", r.Prefix, "[p]")
+	assert.Eq("json records", msgs[0], "debug:d")
+}
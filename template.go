@@ -0,0 +1,114 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// V holds the values of placeholders of a message template; see Logger.InfoT
+type V map[string]interface{}
+
+// InfoT logs a message rendered from a template with named placeholders, like
+//
+//   logger.InfoT("user {user} logged in from {ip}", log.V{"user": u.Name, "ip": ip})
+//   // "[info] user bob logged in from 10.0.0.1"
+//
+// Placeholders are replaced by their values formatted with fmt.Sprint. The values are also
+// attached to the record as fields (see Record.Fields), and the template itself as
+// Record.Template, so that structured output (e.g. FormatJSON) can be searched and aggregated
+// by template and values. In text output, the values appear only in the message. Fields of
+// values like Bytes and Dur hold plain numbers rather than the formatted values.
+//
+// Use "{{" and "}}" for literal braces. Placeholders without a value in v are left as-is.
+func (l *Logger) InfoT(template string, v V) {
+	if l.GetLevel() <= LevelInfo {
+		l.logT(LevelInfo, template, v)
+	}
+}
+
+// DebugT is like InfoT but logs at LevelDebug
+func (l *Logger) DebugT(template string, v V) {
+	if l.GetLevel() <= LevelDebug {
+		m := l.newRecord(LevelDebug)
+		m.appendTemplate(template, v)
+		if l.GetFeatures()&FDebugOrigin != 0 {
+			m.appendOrigin(1)
+		}
+		l.submit(m)
+	}
+}
+
+// WarnT is like InfoT but logs at LevelWarn
+func (l *Logger) WarnT(template string, v V) {
+	if l.GetLevel() <= LevelWarn {
+		l.logT(LevelWarn, template, v)
+	}
+}
+
+// ErrorT is like InfoT but logs at LevelError
+func (l *Logger) ErrorT(template string, v V) {
+	if l.GetLevel() <= LevelError {
+		l.logT(LevelError, template, v)
+	}
+}
+
+func (l *Logger) logT(level Level, template string, v V) {
+	m := l.newRecord(level)
+	m.appendTemplate(template, v)
+	l.submit(m)
+}
+
+// appendTemplate appends template rendered with v to m.msg and adds the values of its
+// placeholders to m.fields
+func (m *logRecord) appendTemplate(template string, v V) {
+	m.template = template
+	fields := m.fields[:len(m.fields):len(m.fields)] // copy on append; m.fields is shared
+	rs, _ := m.logger.q.redactors.Load().([]Redactor)
+	s := template
+	for len(s) > 0 {
+		i := strings.IndexAny(s, "{}")
+		if i == -1 {
+			m.msg = append(m.msg, s...)
+			break
+		}
+		m.msg = append(m.msg, s[:i]...)
+		s = s[i:]
+		if len(s) > 1 && s[1] == s[0] { // "{{" or "}}"
+			m.msg = append(m.msg, s[0])
+			s = s[2:]
+			continue
+		}
+		if end := strings.IndexByte(s, '}'); s[0] == '{' && end != -1 {
+			name := s[1:end]
+			if val, ok := v[name]; ok {
+				str := fmt.Sprint(val)
+				if len(rs) > 0 {
+					// redact values in the message as their fields are (see Redactor)
+					str = redactField(rs, Field{name, str})
+				}
+				m.msg = append(m.msg, str...)
+				if !hasField(fields[len(m.fields):], name) {
+					if r, ok := val.(rawValuer); ok {
+						str = r.rawValue()
+					}
+					fields = append(fields, Field{name, str})
+				}
+				s = s[end+1:]
+				continue
+			}
+		}
+		m.msg = append(m.msg, s[0])
+		s = s[1:]
+	}
+	m.props = len(fields) - len(m.fields)
+	m.fields = fields
+}
+
+func hasField(fields []Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
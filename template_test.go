@@ -0,0 +1,55 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestTemplate(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, FPrefixWarn)
+	defer l.Close()
+
+	l.InfoT("user {user} logged in from {ip}", V{"user": "bob", "ip": "10.0.0.1"})
+	l.WarnT("{n} of {n} {{literal}} {missing} {", V{"n": 3})
+	l.DebugT("not logged {x}", V{"x": 1})
+	l.Sync()
+	assert.Eq("output", w.String(),
+		"user bob logged in from 10.0.0.1\n[warn] 3 of 3 {literal} {missing} {\n")
+
+	// values of placeholders are printed as fields only once, after scope fields
+	w.Reset()
+	done := PushScope("req", "7")
+	l.ErrorT("failed: {err}", V{"err": "timeout"})
+	done()
+	l.Sync()
+	assert.Eq("scope fields", w.String(), "failed: timeout req=7\n")
+}
+
+func TestTemplateRecord(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(10)
+	l := NewLogger(sink, "", LevelInfo, FDefault)
+	defer l.Close()
+
+	l.InfoT("user {user} logged in from {ip}", V{"user": "bob", "ip": "10.0.0.1"})
+	l.Sync()
+	r := sink.Records()[0]
+	assert.Eq("msg", r.Msg, "user bob logged in from 10.0.0.1")
+	assert.Eq("template", r.Template, "user {user} logged in from {ip}")
+	assert.Eq("fields", len(r.Fields), 2)
+	assert.Eq("user", r.Fields["user"], "bob")
+	assert.Eq("ip", r.Fields["ip"], "10.0.0.1")
+
+	buf := &bytes.Buffer{}
+	bl := NewLogger(NewBinaryWriter(buf), "", LevelInfo, FDefault)
+	bl.InfoT("hello {name}", V{"name": "anne"})
+	bl.Close()
+	br, err := NewBinaryReader(bytes.NewReader(buf.Bytes())).Read()
+	assert.NoErr("read", err)
+	assert.Eq("binary template", br.Template, "hello {name}")
+	assert.Eq("binary field", br.Fields["name"], "anne")
+}
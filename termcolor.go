@@ -0,0 +1,200 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ColorDepth is the number of colors a terminal can display
+type ColorDepth int
+
+const (
+	ColorDepthNone ColorDepth = 0       // no color support
+	ColorDepth16   ColorDepth = 16      // the basic ANSI colors, as used by the default theme
+	ColorDepth256  ColorDepth = 256     // the 256-color palette; see Color256
+	ColorDepthTrue ColorDepth = 1 << 24 // 24-bit "true color"; see ColorRGB
+)
+
+// TermInfo describes the terminal a writer is connected to; see TerminalInfo
+type TermInfo struct {
+	TTY    bool       // the writer is a terminal
+	Colors ColorDepth // colors supported by the terminal; ColorDepthNone if not a terminal
+}
+
+// TerminalInfo reports whether w is a terminal and how many colors it supports. FColorAuto
+// enables FColor when this reports color support for a logger's writer.
+//
+// Color support is determined by, in order:
+//   - NO_COLOR, which disables color when set (see https://no-color.org)
+//   - COLORTERM=truecolor or 24bit
+//   - the "colors" capability of $TERM in the terminfo database
+//   - well-known names in $TERM, like "xterm", "screen", "tmux" and "kitty", for systems
+//     without a terminfo database
+//   - on Windows, whether the console supports ANSI escape sequences
+func TerminalInfo(w io.Writer) TermInfo {
+	if !isTerminal(w) {
+		return TermInfo{}
+	}
+	info := TermInfo{TTY: true}
+	if f, ok := w.(*os.File); ok {
+		info.Colors = terminalColorDepth(f)
+	} else {
+		info.Colors = envColorDepth()
+	}
+	return info
+}
+
+// envColorDepth returns the color depth of the terminal described by environment variables
+func envColorDepth() ColorDepth {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return ColorDepthNone
+	}
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorDepthTrue
+	}
+	term := os.Getenv("TERM")
+	if term == "" || term == "dumb" {
+		return ColorDepthNone
+	}
+	if n, ok := terminfoColors(term); ok {
+		return colorDepthOf(n)
+	}
+	return termNameColorDepth(term)
+}
+
+// termNameColorDepth guesses the color depth of a terminal from its $TERM name
+func termNameColorDepth(term string) ColorDepth {
+	switch {
+	case strings.HasSuffix(term, "-direct") || strings.Contains(term, "truecolor"):
+		return ColorDepthTrue
+	case strings.Contains(term, "256color"):
+		return ColorDepth256
+	}
+	for _, s := range []string{
+		"xterm", "vt100", "color", "ansi", "screen", "tmux", "kitty", "alacritty", "rxvt",
+		"linux", "cygwin", "konsole", "putty", "wezterm", "foot", "st-",
+	} {
+		if strings.Contains(term, s) {
+			return ColorDepth16
+		}
+	}
+	return ColorDepthNone
+}
+
+func colorDepthOf(colors int) ColorDepth {
+	switch {
+	case colors >= int(ColorDepthTrue):
+		return ColorDepthTrue
+	case colors >= 256:
+		return ColorDepth256
+	case colors >= 8:
+		return ColorDepth16
+	}
+	return ColorDepthNone
+}
+
+// terminfoCache maps terminal names to their number of colors, or -1 if not found
+var terminfoCache struct {
+	sync.Mutex
+	m map[string]int
+}
+
+// terminfoColors returns the "colors" capability of term from the terminfo database.
+// ok is false if term is not in the database.
+func terminfoColors(term string) (colors int, ok bool) {
+	key := os.Getenv("TERMINFO") + "\x00" + os.Getenv("TERMINFO_DIRS") + "\x00" + term
+	terminfoCache.Lock()
+	defer terminfoCache.Unlock()
+	if n, found := terminfoCache.m[key]; found {
+		return n, n >= 0
+	}
+	colors = -1
+	if data := readTerminfo(term); data != nil {
+		if n, err := parseTerminfoColors(data); err == nil {
+			colors = n
+		}
+	}
+	if terminfoCache.m == nil {
+		terminfoCache.m = make(map[string]int)
+	}
+	terminfoCache.m[key] = colors
+	return colors, colors >= 0
+}
+
+// readTerminfo returns the compiled terminfo entry for term, or nil if not found.
+// Directories are searched in the same order as ncurses does.
+func readTerminfo(term string) []byte {
+	if strings.ContainsAny(term, `/\`) || term[0] == '.' {
+		return nil
+	}
+	var dirs []string
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	for _, dir := range strings.Split(os.Getenv("TERMINFO_DIRS"), ":") {
+		if dir == "" {
+			dir = "/usr/share/terminfo"
+		}
+		dirs = append(dirs, dir)
+	}
+	dirs = append(dirs, "/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo",
+		"/usr/lib/terminfo", "/usr/share/lib/terminfo")
+	for _, dir := range dirs {
+		// entries are stored in a directory named by the first letter of the name, or by its
+		// hex code on case-insensitive file systems (macOS)
+		for _, sub := range []string{term[:1], strconv.FormatInt(int64(term[0]), 16)} {
+			if data, err := ioutil.ReadFile(filepath.Join(dir, sub, term)); err == nil {
+				return data
+			}
+		}
+	}
+	return nil
+}
+
+var errTerminfo = errors.New("log: malformed terminfo entry")
+
+// parseTerminfoColors returns the "colors" capability of a compiled terminfo entry, as
+// described by term(5), or a negative number if the entry doesn't have the capability
+func parseTerminfoColors(data []byte) (int, error) {
+	const colorsIndex = 13 // index of "colors" in the numbers section
+	if len(data) < 12 {
+		return 0, errTerminfo
+	}
+	header := func(i int) int { return int(int16(binary.LittleEndian.Uint16(data[i*2:]))) }
+	numSize := 2
+	switch header(0) {
+	case 0432:
+	case 01036: // extended number format
+		numSize = 4
+	default:
+		return 0, errTerminfo
+	}
+	namesSize, boolCount, numCount := header(1), header(2), header(3)
+	if namesSize < 0 || boolCount < 0 || numCount < 0 {
+		return 0, errTerminfo
+	}
+	if numCount <= colorsIndex {
+		return -1, nil
+	}
+	offs := 12 + namesSize + boolCount
+	offs += offs % 2 // numbers are aligned on an even byte
+	offs += colorsIndex * numSize
+	if offs+numSize > len(data) {
+		return 0, errTerminfo
+	}
+	if numSize == 4 {
+		return int(int32(binary.LittleEndian.Uint32(data[offs:]))), nil
+	}
+	return int(int16(binary.LittleEndian.Uint16(data[offs:]))), nil
+}
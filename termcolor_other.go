@@ -0,0 +1,10 @@
+// +build !windows
+
+package log
+
+import "os"
+
+// terminalColorDepth returns the color depth of the terminal f; see TerminalInfo
+func terminalColorDepth(f *os.File) ColorDepth {
+	return envColorDepth()
+}
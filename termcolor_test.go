@@ -0,0 +1,114 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+// setenv sets an environment variable for the duration of a test
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// unsetenv unsets an environment variable for the duration of a test
+func unsetenv(t *testing.T, key string) {
+	setenv(t, key, "")
+	os.Unsetenv(key)
+}
+
+// makeTerminfo returns a compiled terminfo entry with the "colors" capability
+func makeTerminfo(names string, colors int, extended bool) []byte {
+	var b bytes.Buffer
+	magic, numSize := 0432, 2
+	if extended {
+		magic, numSize = 01036, 4
+	}
+	boolCount := 3
+	for _, v := range []int{magic, len(names) + 1, boolCount, 14, 0, 0} {
+		binary.Write(&b, binary.LittleEndian, int16(v))
+	}
+	b.WriteString(names + "\x00")
+	b.Write(make([]byte, boolCount))
+	if b.Len()%2 != 0 {
+		b.WriteByte(0)
+	}
+	for i := 0; i < 14; i++ {
+		n := -1
+		if i == 13 {
+			n = colors
+		}
+		if numSize == 4 {
+			binary.Write(&b, binary.LittleEndian, int32(n))
+		} else {
+			binary.Write(&b, binary.LittleEndian, int16(n))
+		}
+	}
+	return b.Bytes()
+}
+
+func TestTerminfoColors(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	n, err := parseTerminfoColors(makeTerminfo("exotic|Exotic terminal", 256, false))
+	assert.NoErr("parse", err)
+	assert.Eq("colors", n, 256)
+	n, err = parseTerminfoColors(makeTerminfo("direct", 1<<24, true))
+	assert.NoErr("parse extended", err)
+	assert.Eq("colors extended", n, 1<<24)
+	_, err = parseTerminfoColors([]byte("not terminfo"))
+	assert.Eq("malformed", err, errTerminfo)
+	_, err = parseTerminfoColors(makeTerminfo("truncated", 8, false)[:20])
+	assert.Eq("truncated", err, errTerminfo)
+}
+
+func TestTerminalInfo(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir, err := ioutil.TempDir("", "terminfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "e"), 0755)
+	err = ioutil.WriteFile(filepath.Join(dir, "e", "exotic"), makeTerminfo("exotic", 256, false), 0644)
+	assert.NoErr("write terminfo", err)
+	setenv(t, "TERMINFO", dir)
+	unsetenv(t, "NO_COLOR")
+	unsetenv(t, "COLORTERM")
+
+	assert.Eq("not a terminal", TerminalInfo(&bytes.Buffer{}), TermInfo{})
+	for _, test := range []struct {
+		term  string
+		depth ColorDepth
+	}{
+		{"exotic", ColorDepth256},          // terminfo database
+		{"myterm-256color", ColorDepth256}, // names
+		{"mykitty", ColorDepth16},
+		{"tmux-foo", ColorDepth16},
+		{"foo-direct", ColorDepthTrue},
+		{"unknown", ColorDepthNone},
+		{"dumb", ColorDepthNone},
+		{"", ColorDepthNone},
+	} {
+		setenv(t, "TERM", test.term)
+		assert.Eq(test.term, TerminalInfo(&fakeTerminal{}), TermInfo{TTY: true, Colors: test.depth})
+	}
+
+	setenv(t, "TERM", "unknown")
+	setenv(t, "COLORTERM", "truecolor")
+	assert.Eq("COLORTERM", TerminalInfo(&fakeTerminal{}).Colors, ColorDepthTrue)
+	setenv(t, "NO_COLOR", "")
+	assert.Eq("NO_COLOR", TerminalInfo(&fakeTerminal{}).Colors, ColorDepthNone)
+}
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+const enableVirtualTerminalProcessing = 0x0004 // ENABLE_VIRTUAL_TERMINAL_PROCESSING
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// terminalColorDepth returns the color depth of the console f; see TerminalInfo.
+//
+// Windows 10 consoles (ConHost) interpret ANSI escape sequences, including 24-bit colors, once
+// virtual terminal processing is enabled for the console handle, which this function attempts
+// to do. Windows Terminal and ConEmu always support them, and terminals which set TERM (e.g.
+// mintty) are detected like on other systems.
+// Older versions of Windows do not support enabling virtual terminal processing.
+func terminalColorDepth(f *os.File) ColorDepth {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return ColorDepthNone
+	}
+	if os.Getenv("WT_SESSION") != "" {
+		return ColorDepthTrue
+	}
+	if os.Getenv("ConEmuANSI") == "ON" {
+		return ColorDepth256
+	}
+	if depth := envColorDepth(); depth != ColorDepthNone {
+		return depth
+	}
+	h := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return ColorDepthNone // not a console
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return ColorDepthTrue
+	}
+	if err := procSetConsoleMode.Find(); err != nil {
+		return ColorDepthNone
+	}
+	r, _, _ := procSetConsoleMode.Call(uintptr(h), uintptr(mode|enableVirtualTerminalProcessing))
+	if r == 0 {
+		return ColorDepthNone
+	}
+	return ColorDepthTrue
+}
@@ -0,0 +1,135 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// termWidth returns the number of columns of the terminal w, or 0 if w is not a terminal.
+// It is a variable so that tests can simulate a terminal.
+var termWidth = terminalWidth
+
+// termWidths caches the width of terminals by file descriptor.
+// The cache is cleared when the terminal is resized (SIGWINCH.)
+var termWidths struct {
+	sync.Mutex
+	m     map[uintptr]int
+	watch sync.Once
+}
+
+func terminalWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(w) {
+		return 0
+	}
+	termWidths.watch.Do(func() { watchTerminalResize(resetTerminalWidths) })
+	fd := f.Fd()
+	termWidths.Lock()
+	defer termWidths.Unlock()
+	if width, ok := termWidths.m[fd]; ok {
+		return width
+	}
+	width := queryTerminalWidth(fd)
+	if width <= 0 {
+		// fall back to $COLUMNS, which many shells set
+		width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	if termWidths.m == nil {
+		termWidths.m = make(map[uintptr]int)
+	}
+	termWidths.m[fd] = width
+	return width
+}
+
+func resetTerminalWidths() {
+	termWidths.Lock()
+	termWidths.m = nil
+	termWidths.Unlock()
+}
+
+// fitWidth truncates (FTruncate) or wraps (FWrap) lines in (*buf)[start:] which are wider
+// than width columns. Wrapped lines are indented by indent columns.
+func fitWidth(buf *[]byte, start, width, indent int, wrap bool) {
+	text := (*buf)[start:]
+	fits := true
+	for _, line := range bytes.Split(text, []byte{'\n'}) {
+		if visibleWidth(line) > width {
+			fits = false
+			break
+		}
+	}
+	if fits {
+		return
+	}
+	if indent > width/2 {
+		indent = 0 // wide header; don't leave too little room for the message
+	}
+	text = append([]byte(nil), text...)
+	*buf = (*buf)[:start]
+	for i, line := range bytes.Split(text, []byte{'\n'}) {
+		if i > 0 {
+			*buf = append(*buf, '\n')
+		}
+		if visibleWidth(line) <= width {
+			*buf = append(*buf, line...)
+		} else if wrap {
+			appendWrapped(buf, line, width, indent)
+		} else {
+			appendTruncated(buf, line, width)
+		}
+	}
+}
+
+// appendTruncated appends the first width-1 columns of line to buf, followed by an ellipsis
+func appendTruncated(buf *[]byte, line []byte, width int) {
+	*buf = append(*buf, line[:columnIndex(line, width-1)]...)
+	*buf = append(*buf, "…"...)
+	if bytes.IndexByte(line, 0x1b) != -1 {
+		*buf = append(*buf, colorFgReset...) // in case we cut off the end of a colored span
+	}
+}
+
+// appendWrapped appends line to buf, broken into lines of at most width columns.
+// Lines are broken at spaces where possible and continuation lines are indented.
+func appendWrapped(buf *[]byte, line []byte, width, indent int) {
+	avail := width
+	min := columnIndex(line, indent) // don't break within the header of the first line
+	for visibleWidth(line) > avail {
+		end := columnIndex(line, avail)
+		brk := bytes.LastIndexByte(line[:end+1], ' ') // a space just past the edge is fine too
+		if brk <= min {
+			brk = end // no space to break at; break mid-word
+		}
+		*buf = append(*buf, bytes.TrimRight(line[:brk], " ")...)
+		*buf = append(*buf, '\n')
+		for i := 0; i < indent; i++ {
+			*buf = append(*buf, ' ')
+		}
+		line = bytes.TrimLeft(line[brk:], " ")
+		avail = width - indent
+		min = 0
+	}
+	*buf = append(*buf, line...)
+}
+
+// columnIndex returns the byte offset in b after n visible columns (see visibleWidth)
+func columnIndex(b []byte, n int) int {
+	col := 0
+	for i := 0; i < len(b); i++ {
+		if b[i] == 0x1b && i+1 < len(b) && b[i+1] == '[' {
+			for i += 2; i < len(b) && (b[i] < 0x40 || b[i] > 0x7e); i++ {
+			}
+			continue
+		}
+		if b[i]&0xc0 != 0x80 {
+			if col == n {
+				return i
+			}
+			col++
+		}
+	}
+	return len(b)
+}
@@ -0,0 +1,9 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package log
+
+func queryTerminalWidth(fd uintptr) int {
+	return 0 // use $COLUMNS
+}
+
+func watchTerminalResize(f func()) {}
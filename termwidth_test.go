@@ -0,0 +1,58 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestTermWidth(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	termWidth = func(tw io.Writer) int {
+		if tw == w {
+			return 20
+		}
+		return 0
+	}
+	defer func() { termWidth = terminalWidth }()
+
+	var feats Features = FTruncate | FPrefixInfo
+	l := NewLogger(w, "", LevelInfo, feats)
+	defer l.Close()
+
+	l.Info("short")
+	l.Info("this message is too long")
+	l.Sync()
+	l.DisableFeatures(FTruncate)
+	l.EnableFeatures(FWrap)
+	l.Info("this message is too long to fit on one line")
+	l.Info("abcdefghijklmnopqrstuvwxyz")
+	l.Sync()
+	assert.Eq("output", w.String(), ""+
+		"[info] short\n"+
+		"[info] this message…\n"+
+		"[info] this message\n"+
+		"       is too long\n"+
+		"       to fit on one\n"+
+		"       line\n"+
+		"[info] abcdefghijklm\n"+
+		"       nopqrstuvwxyz\n")
+
+	// not a terminal
+	w2 := &bytes.Buffer{}
+	l.SetWriter(w2)
+	l.Info("this message is too long to fit on one line")
+	l.Sync()
+	assert.Eq("output", w2.String(), "[info] this message is too long to fit on one line\n")
+
+	assert.Eq("color", string(fitLine("\x1b[90mabcdef\x1b[39m", 4, false)), "\x1b[90mabc…\x1b[39m")
+}
+
+func fitLine(s string, width int, wrap bool) []byte {
+	buf := []byte(s)
+	fitWidth(&buf, 0, width, 0, wrap)
+	return buf
+}
@@ -0,0 +1,28 @@
+// Package vetprintf is checked by TestVetPrintf. Lines ending with "// want" are expected
+// to be reported by go vet.
+package vetprintf
+
+import (
+	"errors"
+
+	"github.com/rsms/go-log"
+)
+
+func calls(l *log.Logger) {
+	err := errors.New("boom")
+	l.Error("open failed: %w", err)
+	l.Warn("%d items", 3)
+	l.Info("hello")
+	log.Info("user %s", "bob")
+
+	l.Error("open failed: %w", "not an error")   // want
+	l.Warn("%d items", "three")                  // want
+	l.Info("%s")                                 // want
+	l.Debug("%s %s", "a")                        // want
+	log.Error("%d", "x")                         // want
+	l.ErrorContext(nil, "%d", "x")               // want
+	l.LogEvery(log.LevelWarn, "k", 0, "%d", "x") // want
+	l.Batch(func(b *log.Batch) {
+		b.Info("%d", "x") // want
+	})
+}
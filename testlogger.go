@@ -0,0 +1,87 @@
+package log
+
+import (
+	"strings"
+	"sync"
+)
+
+// TestingT is the subset of testing.TB used by TestLogger
+type TestingT interface {
+	Logf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// TestLogger is a logger for use in tests. Messages are written synchronously to t.Logf and
+// recorded so that tests can make assertions on them without having to call Sync.
+type TestLogger struct {
+	*Logger
+	t       TestingT
+	mu      sync.Mutex
+	entries []Record
+}
+
+// NewTestLogger creates a logger which logs to t and is closed when the test finishes.
+// Its level is LevelDebug. Sub-loggers created from it log to t as well.
+//
+// Example:
+//
+//   func TestThing(t *testing.T) {
+//     tl := log.NewTestLogger(t)
+//     thing := NewThing(tl.Logger)
+//     thing.Frob()
+//     if !tl.Contains(log.LevelWarn, "frobbed twice") {
+//       t.Error("expected warning")
+//     }
+//   }
+//
+func NewTestLogger(t TestingT) *TestLogger {
+	tl := &TestLogger{t: t}
+	var feats Features = FPrefixDebug | FPrefixInfo | FPrefixWarn | FPrefixError | FSync
+	tl.Logger = NewLogger((*testLoggerWriter)(tl), "", LevelDebug, feats)
+	t.Cleanup(func() { tl.Logger.Close() })
+	return tl
+}
+
+// Entries returns all records logged so far
+func (tl *TestLogger) Entries() []Record {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return append([]Record(nil), tl.entries...)
+}
+
+// Contains returns true if a record of level with a message containing substr has been logged
+func (tl *TestLogger) Contains(level Level, substr string) bool {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	for _, r := range tl.entries {
+		if r.Level == level && strings.Contains(r.Msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset forgets all records logged so far
+func (tl *TestLogger) Reset() {
+	tl.mu.Lock()
+	tl.entries = nil
+	tl.mu.Unlock()
+}
+
+type testLoggerWriter TestLogger
+
+func (w *testLoggerWriter) Write(p []byte) (int, error) {
+	w.t.Logf("%s", strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func (w *testLoggerWriter) writeRecord(m *logRecord) error {
+	w.mu.Lock()
+	w.entries = append(w.entries, m.record())
+	w.mu.Unlock()
+	b := getBuffer()
+	m.format(&b.B)
+	w.Write(b.B)
+	b.Release()
+	return nil
+}
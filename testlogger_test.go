@@ -0,0 +1,55 @@
+package log
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+// fakeT records calls to Logf
+type fakeT struct {
+	logs     []string
+	cleanups []func()
+}
+
+func (t *fakeT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+
+func TestTestLogger(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	ft := &fakeT{}
+	tl := NewTestLogger(ft)
+
+	tl.Debug("debug %d", 1)
+	tl.SubLogger("[sub]").Warn("careful")
+	tl.Info("hello")
+
+	// no Sync needed
+	entries := tl.Entries()
+	if assert.Eq("entries", len(entries), 3) {
+		assert.Eq("entry 0 level", entries[0].Level, LevelDebug)
+		assert.Eq("entry 1 prefix", entries[1].Prefix, "[sub]")
+		assert.Eq("entry 1 msg", entries[1].Msg, "careful")
+	}
+	assert.Ok("Contains warn", tl.Contains(LevelWarn, "care"))
+	assert.Ok("not Contains info", !tl.Contains(LevelInfo, "care"))
+	if assert.Eq("Logf calls", len(ft.logs), 3) {
+		assert.Eq("Logf 1", ft.logs[1], "[warn] [sub] careful")
+		assert.Eq("Logf 2", ft.logs[2], "[info] hello")
+	}
+
+	tl.Reset()
+	assert.Eq("entries after Reset", len(tl.Entries()), 0)
+
+	assert.Eq("cleanup registered", len(ft.cleanups), 1)
+	ft.cleanups[0]()
+	tl.Info("after close")
+	assert.Eq("entries after close", len(tl.Entries()), 0)
+
+	// with a real *testing.T
+	NewTestLogger(t).Info("hello from TestTestLogger")
+}
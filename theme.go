@@ -0,0 +1,165 @@
+package log
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Color is a foreground color of ANSI terminals, expressed as SGR parameters.
+// Use the Color* constants for the 16 standard colors, Color256 for the 256-color palette
+// and ColorRGB for 24-bit "truecolor".
+type Color string
+
+const (
+	ColorDefault Color = "" // the terminal's default foreground color
+	ColorBlack   Color = "30"
+	ColorRed     Color = "31"
+	ColorGreen   Color = "32"
+	ColorYellow  Color = "33"
+	ColorBlue    Color = "34"
+	ColorMagenta Color = "35"
+	ColorCyan    Color = "36"
+	ColorWhite   Color = "37"
+	ColorGrey    Color = "90"
+)
+
+// Color256 returns color n of the 256-color palette
+func Color256(n uint8) Color {
+	return Color("38;5;" + strconv.Itoa(int(n)))
+}
+
+// ColorRGB returns a 24-bit color. Not all terminals support these.
+func ColorRGB(r, g, b uint8) Color {
+	return Color("38;2;" + strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" +
+		strconv.Itoa(int(b)))
+}
+
+// seq returns the escape sequence which sets the color, optionally in bold
+func (c Color) seq(bold bool) string {
+	if c == ColorDefault {
+		c = "39"
+	}
+	if bold {
+		return "\x1b[" + string(c) + ";1m"
+	}
+	return "\x1b[" + string(c) + "m"
+}
+
+// Theme defines the colors used when FColor is enabled. See SetTheme
+type Theme struct {
+	Debug     Color // "debug" level prefix
+	Info      Color // "info" level prefix
+	Warn      Color // "warn" level prefix
+	Error     Color // "error" level prefix
+	Time      Color // "time" level prefix (see Logger.Time)
+	Brackets  Color // brackets around level prefixes
+	Timestamp Color // date and time (FDate, FTime)
+	Origin    Color // source location of debug messages (FDebugOrigin)
+	Fields    Color // key=value fields (see PushScope)
+}
+
+// DarkTheme is suitable for terminals with a dark background. It is the default theme.
+var DarkTheme = Theme{
+	Debug:     ColorBlue,
+	Info:      ColorDefault,
+	Warn:      ColorYellow,
+	Error:     ColorRed,
+	Time:      ColorCyan,
+	Brackets:  ColorGrey,
+	Timestamp: ColorGrey,
+	Origin:    ColorGrey,
+	Fields:    ColorGrey,
+}
+
+// LightTheme is suitable for terminals with a light background
+var LightTheme = Theme{
+	Debug:     Color256(25), // dark blue
+	Info:      ColorDefault,
+	Warn:      Color256(130), // dark orange; yellow is hard to read on white
+	Error:     Color256(160), // dark red
+	Time:      Color256(30),  // dark cyan
+	Brackets:  Color256(246), // grey
+	Timestamp: Color256(244), // grey
+	Origin:    Color256(244), // grey
+	Fields:    Color256(242), // dark grey
+}
+
+// SetTheme changes the colors used by all loggers when FColor is enabled.
+// It is safe to call at any time, e.g. when the user switches their terminal to a light theme.
+func SetTheme(t Theme) {
+	themeMu.Lock()
+	defer themeMu.Unlock()
+	currentThemeValue.Store(t.compile(currentTheme().labels))
+}
+
+// SetLevelLabels replaces the names of levels shown in level prefixes, e.g. "[warn]", for
+// example to translate them for the users of a command-line program:
+//
+//   log.SetLevelLabels(map[log.Level]string{
+//     log.LevelWarn:  "avertissement",
+//     log.LevelError: "erreur",
+//   })
+//
+// Levels missing from labels keep their English names. Passing nil restores all names.
+// Like SetTheme, this affects all loggers. Structured output like FormatJSON is not affected.
+func SetLevelLabels(labels map[Level]string) {
+	themeMu.Lock()
+	defer themeMu.Unlock()
+	names := levelNames
+	for level, label := range labels {
+		if level >= 0 && int(level) < len(names) && level != LevelDisable {
+			names[level] = label
+		}
+	}
+	ct := currentTheme()
+	currentThemeValue.Store(ct.theme.compile(names))
+}
+
+// compiledTheme holds the escape sequences of a Theme
+type compiledTheme struct {
+	theme       Theme
+	labels      [6]string // level names; see SetLevelLabels
+	levelPrefix [6]string // e.g. "[warn] " with colors
+	levelPlain  [6]string // e.g. "[warn] "
+	levelSymbol [6]string // e.g. "⚠ " with colors; see FSymbols
+	timestamp   string
+	origin      string
+	fields      string
+}
+
+var (
+	themeMu           sync.Mutex   // serializes changes to currentThemeValue
+	currentThemeValue atomic.Value // *compiledTheme
+	darkTheme         = DarkTheme.compile(levelNames)
+)
+
+// currentTheme returns the theme set with SetTheme, or DarkTheme
+func currentTheme() *compiledTheme {
+	if t, ok := currentThemeValue.Load().(*compiledTheme); ok {
+		return t
+	}
+	return darkTheme
+}
+
+func (t *Theme) compile(labels [6]string) *compiledTheme {
+	ct := &compiledTheme{
+		theme:     *t,
+		labels:    labels,
+		timestamp: t.Timestamp.seq(false),
+		origin:    t.Origin.seq(false),
+		fields:    t.Fields.seq(false),
+	}
+	colors := [len(ct.levelPrefix)]Color{t.Debug, t.Info, t.Warn, t.Error, "", t.Time}
+	for level, c := range colors {
+		if Level(level) == LevelDisable {
+			continue
+		}
+		// e.g. "[warn] " with grey brackets and bold yellow "warn"
+		ct.levelPrefix[level] = t.Brackets.seq(false) + "[" + c.seq(true) + labels[level] +
+			"\x1b[22m" + t.Brackets.seq(false) + "]" + colorFgReset + " "
+		ct.levelPlain[level] = "[" + labels[level] + "] "
+		ct.levelSymbol[level] = c.seq(true) + levelSymbols[level] + "\x1b[22m" + colorFgReset + " "
+	}
+	return ct
+}
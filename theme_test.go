@@ -0,0 +1,95 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestTheme(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	assert.Eq("256", Color256(130), Color("38;5;130"))
+	assert.Eq("rgb", ColorRGB(255, 128, 0), Color("38;2;255;128;0"))
+	assert.Eq("dark", currentTheme().levelPrefix[LevelWarn],
+		"\x1b[90m[\x1b[33;1mwarn\x1b[22m\x1b[90m]\x1b[39m ")
+
+	w := &bytes.Buffer{}
+	var feats Features = FColor | FPrefixWarn | FTime
+	l := NewLogger(w, "", LevelInfo, feats)
+	defer l.Close()
+
+	SetTheme(LightTheme)
+	defer SetTheme(DarkTheme)
+	l.Warn("hello")
+	l.Sync()
+	assert.Eq("light", w.String()[:len("\x1b[38;5;244m")], "\x1b[38;5;244m")
+	assert.Eq("light warn", bytes.Contains(w.Bytes(), []byte("\x1b[38;5;130;1mwarn")), true)
+}
+
+func TestSetLevelLabels(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	var feats Features = FPrefixWarn | FPrefixError
+	l := NewLogger(w, "", LevelInfo, feats)
+	defer l.Close()
+
+	SetLevelLabels(map[Level]string{LevelWarn: "avertissement"})
+	defer SetLevelLabels(nil)
+	l.Warn("a")
+	l.Error("b")
+	l.Sync()
+	assert.Eq("plain", w.String(), "[avertissement] a\n[error] b\n")
+
+	// labels survive theme changes
+	SetTheme(LightTheme)
+	defer SetTheme(DarkTheme)
+	assert.Eq("color", bytes.Contains([]byte(currentTheme().levelPrefix[LevelWarn]),
+		[]byte("avertissement")), true)
+
+	SetLevelLabels(nil)
+	assert.Eq("restored", currentTheme().levelPlain[LevelWarn], "[warn] ")
+}
+
+// fakeTerminal is a writer which isTerminal reports as a terminal
+type fakeTerminal struct {
+	bytes.Buffer
+}
+
+func (t *fakeTerminal) Stat() (os.FileInfo, error) { return fakeTerminalInfo{}, nil }
+
+type fakeTerminalInfo struct{ os.FileInfo }
+
+func (fakeTerminalInfo) Mode() os.FileMode { return os.ModeDevice | os.ModeCharDevice }
+
+func TestSymbols(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	var feats Features = FSymbols | FPrefixInfo | FPrefixWarn | FPrefixError
+	term := &fakeTerminal{}
+	l := NewLogger(term, "", LevelInfo, feats)
+	defer l.Close()
+	l.Info("a")
+	l.Warn("b")
+	l.Error("c")
+	l.Sync()
+	assert.Eq("terminal", term.String(), "• a\n⚠ b\n✗ c\n")
+
+	l.EnableFeatures(FColor)
+	l.Warn("d")
+	l.Sync()
+	assert.Eq("color", strings.HasSuffix(term.String(), "\x1b[33;1m⚠\x1b[22m\x1b[39m d\n"), true)
+
+	// not a terminal
+	w := &bytes.Buffer{}
+	l.SetWriter(w)
+	l.DisableFeatures(FColor)
+	l.Warn("e")
+	l.Sync()
+	assert.Eq("fallback", w.String(), "[warn] e\n")
+
+	f, err := ParseFeatures("symbols")
+	assert.NoErr("parse", err)
+	assert.Eq("parse", f, FSymbols)
+}
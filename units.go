@@ -0,0 +1,95 @@
+package log
+
+import (
+	"strconv"
+	"time"
+)
+
+// Bytes is a number of bytes which is formatted with binary units, like "1.4MiB", by the %v and
+// %s verbs. %d formats the number itself. When used as a value of a message template (see
+// InfoT), the field holds the number, so that structured output stays machine-readable:
+//
+//   logger.InfoT("wrote {size} to {file}", log.V{"size": log.Bytes(n), "file": name})
+//   // text: "[info] wrote 1.4MiB to a.dat"
+//   // JSON: {"msg":"wrote 1.4MiB to a.dat","fields":{"file":"a.dat","size":"1468006"},...}
+//
+type Bytes int64
+
+// Dur is a duration which is formatted with one decimal of its largest unit, like "2.3s" or
+// "4.1ms", by the %v and %s verbs, rather than with full precision like time.Duration.
+// Durations of one minute or longer are rounded to seconds, like "3m12s". When used as a value
+// of a message template, the field holds the number of seconds, like "2.3456".
+type Dur time.Duration
+
+// Count is a number which is formatted with SI units, like "1.2k" or "3.4M", by the %v and %s
+// verbs. %d formats the number itself, as does a field of a message template.
+type Count int64
+
+// rawValuer is implemented by values which are formatted differently in fields of structured
+// output than in messages
+type rawValuer interface {
+	rawValue() string
+}
+
+func (n Bytes) String() string {
+	if n > -1024 && n < 1024 {
+		return strconv.FormatInt(int64(n), 10) + "B"
+	}
+	return formatUnits(float64(n), 1024, []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"})
+}
+
+func (n Bytes) rawValue() string { return strconv.FormatInt(int64(n), 10) }
+
+func (n Count) String() string {
+	if n > -1000 && n < 1000 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return formatUnits(float64(n), 1000, []string{"k", "M", "G", "T", "P", "E"})
+}
+
+func (n Count) rawValue() string { return strconv.FormatInt(int64(n), 10) }
+
+func (d Dur) String() string {
+	if d < 0 {
+		return "-" + (-d).String()
+	}
+	switch td := time.Duration(d); {
+	case td < time.Microsecond:
+		return td.String()
+	case td < time.Millisecond:
+		return formatDecimal(float64(td)/float64(time.Microsecond)) + "µs"
+	case td < time.Second:
+		return formatDecimal(float64(td)/float64(time.Millisecond)) + "ms"
+	case td < time.Minute:
+		return formatDecimal(td.Seconds()) + "s"
+	default:
+		return td.Round(time.Second).String()
+	}
+}
+
+func (d Dur) rawValue() string {
+	return strconv.FormatFloat(float64(d)/float64(time.Second), 'f', -1, 64)
+}
+
+// formatUnits formats f, which is at least base, in the largest of units (which are powers of
+// base, starting with base) that is not larger than f
+func formatUnits(f float64, base float64, units []string) string {
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	i := 0
+	for f /= base; f >= base && i < len(units)-1; i++ {
+		f /= base
+	}
+	return sign + formatDecimal(f) + units[i]
+}
+
+// formatDecimal formats f with at most one decimal, like "1.4" or "12"
+func formatDecimal(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 1, 64)
+	if len(s) > 2 && s[len(s)-2:] == ".0" {
+		s = s[:len(s)-2]
+	}
+	return s
+}
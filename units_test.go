@@ -0,0 +1,53 @@
+package log
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestUnits(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	for _, c := range []struct {
+		v    interface{}
+		want string
+	}{
+		{Bytes(0), "0B"},
+		{Bytes(1023), "1023B"},
+		{Bytes(1024), "1KiB"},
+		{Bytes(1468006), "1.4MiB"},
+		{Bytes(-2048), "-2KiB"},
+		{Bytes(1 << 62), "4EiB"},
+		{Count(999), "999"},
+		{Count(1200), "1.2k"},
+		{Count(3400000), "3.4M"},
+		{Dur(0), "0s"},
+		{Dur(500), "500ns"},
+		{Dur(12345 * time.Nanosecond), "12.3µs"},
+		{Dur(4100 * time.Microsecond), "4.1ms"},
+		{Dur(2345 * time.Millisecond), "2.3s"},
+		{Dur(-2 * time.Second), "-2s"},
+		{Dur(192400 * time.Millisecond), "3m12s"},
+	} {
+		assert.Eq(fmt.Sprintf("%#v", c.v), fmt.Sprint(c.v), c.want)
+	}
+	assert.Eq("%d", fmt.Sprintf("%d", Bytes(1468006)), "1468006")
+}
+
+func TestUnitsRecord(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	sink := NewMemorySink(10)
+	l := NewLogger(sink, "", LevelInfo, 0)
+	defer l.Close()
+
+	l.InfoT("wrote {size} in {time}", V{"size": Bytes(1468006), "time": Dur(2345 * time.Millisecond)})
+	l.Info("read %v", Bytes(2048))
+	l.Sync()
+	records := sink.Records()
+	assert.Eq("msg", records[0].Msg, "wrote 1.4MiB in 2.3s")
+	assert.Eq("size field", records[0].Fields["size"], "1468006")
+	assert.Eq("time field", records[0].Fields["time"], "2.345")
+	assert.Eq("deferred msg", records[1].Msg, "read 2KiB")
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"flag"
+	"strconv"
+)
+
+// SetVerbosity sets the level of l according to the verbosity conventions of command-line
+// programs:
+//
+//   -1  quiet: errors only
+//    0  default: warnings and errors
+//    1  info messages too (-v)
+//    2  debug messages too (-vv)
+//    3  debug messages with their source location (FDebugOrigin) (-vvv)
+//
+// Values below -1 are treated as -1 and values above 3 as 3.
+func (l *Logger) SetVerbosity(n int) {
+	switch {
+	case n < 0:
+		l.SetLevel(LevelError)
+	case n == 0:
+		l.SetLevel(LevelWarn)
+	case n == 1:
+		l.SetLevel(LevelInfo)
+	default:
+		l.SetLevel(LevelDebug)
+		if n > 2 {
+			l.EnableFeatures(FDebugOrigin)
+		} else {
+			l.DisableFeatures(FDebugOrigin)
+		}
+	}
+}
+
+// SetVerbosity calls SetVerbosity on RootLogger
+func SetVerbosity(n int) { RootLogger.SetVerbosity(n) }
+
+// VerbosityFlags defines the flags -q, -v and -vv on fs (flag.CommandLine if nil) and returns
+// a function which returns the verbosity they select, for SetVerbosity. -v may be repeated,
+// e.g. "-v -v" is the same as "-vv", and -q takes precedence over -v.
+//
+//   verbosity := log.VerbosityFlags(nil)
+//   flag.Parse()
+//   log.SetVerbosity(verbosity())
+//
+func VerbosityFlags(fs *flag.FlagSet) func() int {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	var count int
+	quiet := fs.Bool("q", false, "Only log errors")
+	fs.Var(verbosityFlag{&count, 1}, "v", "Log more; repeat for even more")
+	fs.Var(verbosityFlag{&count, 2}, "vv", "Log debug messages")
+	return func() int {
+		if *quiet {
+			return -1
+		}
+		return count
+	}
+}
+
+// verbosityFlag is a boolean flag which adds step to count each time it is set
+type verbosityFlag struct {
+	count *int
+	step  int
+}
+
+func (f verbosityFlag) IsBoolFlag() bool { return true }
+
+func (f verbosityFlag) String() string {
+	if f.count == nil {
+		return "0"
+	}
+	return strconv.Itoa(*f.count)
+}
+
+func (f verbosityFlag) Set(s string) error {
+	set, err := strconv.ParseBool(s)
+	if set {
+		*f.count += f.step
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package log
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestSetVerbosity(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	l := NewLogger(ioutil.Discard, "", LevelInfo, 0)
+	defer l.Close()
+	for _, c := range []struct {
+		n     int
+		level Level
+	}{{-5, LevelError}, {-1, LevelError}, {0, LevelWarn}, {1, LevelInfo}, {2, LevelDebug}} {
+		l.SetVerbosity(c.n)
+		assert.Eq("level", l.GetLevel(), c.level)
+	}
+	assert.Eq("no origin", l.GetFeatures()&FDebugOrigin, Features(0))
+	l.SetVerbosity(3)
+	assert.Eq("origin", l.GetFeatures()&FDebugOrigin, FDebugOrigin)
+	assert.Eq("debug", l.GetLevel(), LevelDebug)
+}
+
+func TestVerbosityFlags(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	for _, c := range []struct {
+		args []string
+		n    int
+	}{
+		{nil, 0},
+		{[]string{"-q"}, -1},
+		{[]string{"-v"}, 1},
+		{[]string{"-vv"}, 2},
+		{[]string{"-v", "-v", "-v"}, 3},
+		{[]string{"-vv", "-v"}, 3},
+		{[]string{"-q", "-vv"}, -1},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		verbosity := VerbosityFlags(fs)
+		assert.NoErr("parse", fs.Parse(c.args))
+		assert.Eq("verbosity %v", verbosity(), c.n, c.args)
+	}
+}
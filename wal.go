@@ -0,0 +1,210 @@
+package log
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// wal is a write-ahead log of queued records; see Options.WAL.
+//
+// Records are appended in the binary log format (see BinaryWriter) when they are queued, and
+// numbered in the order they were appended. The file is truncated whenever all records
+// appended to it have been written. Records may be written out of order (see PriorityWindow),
+// so while some are pending, the file is compacted by removing the longest run of written
+// records at its start, once that run is larger than walCompactSize and half of the file.
+type wal struct {
+	mu      sync.Mutex
+	f       *os.File
+	buf     []byte
+	size    int64   // size of f
+	first   uint64  // sequence number of the first record in f
+	ends    []int64 // offsets in f of the end of records first, first+1, ...
+	done    []bool  // whether records first, first+1, ... have been written
+	pending int     // number of records in f which have not been written yet
+}
+
+// walCompactSize is the minimum number of bytes of written records removed by compacting a WAL
+var walCompactSize int64 = 1 << 20
+
+// openWAL opens the WAL at path, creating it if needed, and returns the records it holds,
+// which were queued but not written by a previous process. A partially written record at the
+// end of the file, from a crash while appending it, is discarded. The records have the
+// sequence numbers 1, 2, ...
+func openWAL(path string) (*wal, []*Record, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	w := &wal{f: f, first: 1}
+	var records []*Record
+	for w.size < int64(len(data)) {
+		n, i := binary.Uvarint(data[w.size:])
+		if i <= 0 || n > uint64(int64(len(data))-w.size-int64(i)) {
+			break
+		}
+		r, err := decodeBinaryRecord(data[w.size+int64(i) : w.size+int64(i)+int64(n)])
+		if err != nil {
+			break
+		}
+		records = append(records, r)
+		w.size += int64(i) + int64(n)
+		w.ends = append(w.ends, w.size)
+		w.done = append(w.done, false)
+	}
+	if w.size < int64(len(data)) {
+		if err := f.Truncate(w.size); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+	}
+	w.pending = len(records)
+	return w, records, nil
+}
+
+// append adds m to the log and assigns m.walSeq. It must be called before m is queued.
+func (w *wal) append(m *logRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = appendBinaryDelimited(w.buf[:0], appendBinaryRecordBody(nil, m))
+	n, err := w.f.Write(w.buf)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	m.walSeq = w.first + uint64(len(w.ends))
+	w.ends = append(w.ends, w.size)
+	w.done = append(w.done, false)
+	w.pending++
+	return nil
+}
+
+// written is called with the sequence numbers of records appended to the log when they have
+// been written (or dropped)
+func (w *wal) written(seqs ...uint64) error {
+	if len(seqs) == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, seq := range seqs {
+		if i := seq - w.first; seq >= w.first && i < uint64(len(w.done)) && !w.done[i] {
+			w.done[i] = true
+			w.pending--
+		}
+	}
+	if w.pending == 0 {
+		w.first += uint64(len(w.ends))
+		w.ends = w.ends[:0]
+		w.done = w.done[:0]
+		if w.size == 0 {
+			return nil
+		}
+		w.size = 0
+		return w.f.Truncate(0)
+	}
+	n := 0 // number of written records at the start of the file
+	for n < len(w.done) && w.done[n] {
+		n++
+	}
+	if n == 0 || w.ends[n-1] < walCompactSize || w.ends[n-1] < w.size/2 {
+		return nil
+	}
+	return w.compact(n)
+}
+
+// compact removes the first n records from the log, by writing the others to a new file which
+// replaces it
+func (w *wal) compact(n int) error {
+	off := w.ends[n-1]
+	data := make([]byte, w.size-off)
+	if _, err := w.f.ReadAt(data, off); err != nil {
+		return err
+	}
+	path := w.f.Name()
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	w.f.Close() // before renaming, which fails for open files on some systems
+	if err := os.Rename(tmp, path); err != nil {
+		// continue with the original file, which is unchanged
+		f.Close()
+		os.Remove(tmp)
+		f, e := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0600)
+		if e != nil {
+			return e
+		}
+		w.f = f
+		return err
+	}
+	w.f = f
+	w.size -= off
+	w.first += uint64(n)
+	w.ends = append(w.ends[:0], w.ends[n:]...)
+	for i := range w.ends {
+		w.ends[i] -= off
+	}
+	w.done = append(w.done[:0], w.done[n:]...)
+	return nil
+}
+
+// close closes the log, removing it if all of its records have been written
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.f.Close()
+	if w.pending == 0 {
+		if e := os.Remove(w.f.Name()); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// replayWAL queues records read from the WAL by openWAL. They are counted as pending by the
+// WAL already and are not appended again.
+func (l *Logger) replayWAL(records []*Record) {
+	loggers := map[string]*Logger{l.Prefix: l}
+	for i, r := range records {
+		logger := loggers[r.Prefix]
+		if logger == nil {
+			logger = l.clone()
+			logger.Prefix = r.Prefix
+			loggers[r.Prefix] = logger
+		}
+		m := logRecordFree.Get().(*logRecord)
+		m.logger = logger
+		m.level = r.Level
+		if m.level < LevelDebug || m.level > levelTime || m.level == LevelDisable {
+			m.level = LevelInfo
+		}
+		m.time = r.Time
+		m.scope = r.Scope
+		m.fields = sortedFields(r.Fields)
+		m.msg = append(m.msg, r.Msg...)
+		m.origin = append(m.origin, r.Caller...)
+		m.template = r.Template
+		if len(r.TraceID) == 2*len(m.trace.TraceID) && len(r.SpanID) == 2*len(m.trace.SpanID) {
+			hex.Decode(m.trace.TraceID[:], []byte(r.TraceID))
+			hex.Decode(m.trace.SpanID[:], []byte(r.SpanID))
+		}
+		m.walSeq = uint64(i + 1)
+		l.q.mu.RLock()
+		l.q.input(m) <- m
+		l.q.mu.RUnlock()
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestWAL(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.wal")
+
+	// records stay in the WAL while the writer is wedged
+	bw := &blockingWriter{unblock: make(chan struct{})}
+	l := New(Options{Writer: bw, Prefix: "[billing]", WAL: path})
+	l.Info("a")
+	l.Warn("b")
+	l.WithFields("k", "v").Info("c")
+	data, err := ioutil.ReadFile(path)
+	assert.NoErr("read WAL", err)
+
+	// simulate a crash while appending a fourth record
+	crashed := filepath.Join(dir, "crashed.wal")
+	data = append(data, 42, 1, 2)
+	assert.NoErr("write WAL", ioutil.WriteFile(crashed, data, 0600))
+
+	close(bw.unblock)
+	assert.NoErr("close", l.Close())
+	assert.Eq("written", bw.String(), "[billing] a\n[billing] b\n[billing] c k=v\n")
+	_, err = os.Stat(path)
+	assert.Ok("WAL removed by Close", os.IsNotExist(err))
+
+	// records of the crashed process are written before new ones
+	sink := NewMemorySink(10)
+	l2 := New(Options{Writer: sink, Prefix: "[new]", WAL: crashed})
+	l2.Info("d")
+	assert.NoErr("sync", l2.Sync())
+	records := sink.Records()
+	assert.Eq("replayed", len(records), 4)
+	if len(records) == 4 {
+		assert.Eq("msg", records[0].Msg, "a")
+		assert.Eq("prefix", records[0].Prefix, "[billing]")
+		assert.Eq("level", records[1].Level, LevelWarn)
+		assert.Eq("fields", records[2].Fields["k"], "v")
+		assert.Eq("new record", records[3].Msg, "d")
+		assert.Eq("new prefix", records[3].Prefix, "[new]")
+	}
+	info, err := os.Stat(crashed)
+	assert.NoErr("stat WAL", err)
+	assert.Eq("WAL truncated once written", info.Size(), int64(0))
+	assert.NoErr("close", l2.Close())
+}
+
+func TestWALCompaction(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	defer func(size int64) { walCompactSize = size }(walCompactSize)
+	walCompactSize = 100
+	path := filepath.Join(t.TempDir(), "log.wal")
+	w, _, err := openWAL(path)
+	if !assert.NoErr("open", err) {
+		return
+	}
+	l := NewLogger(ioutil.Discard, "", LevelInfo, 0)
+	defer l.Close()
+	var seqs []uint64
+	for i := 0; i < 10; i++ {
+		m := l.newRecord(LevelInfo)
+		m.msg = append(m.msg, fmt.Sprintf("record %d", i)...)
+		assert.NoErr("append", w.append(m))
+		seqs = append(seqs, m.walSeq)
+		m.free()
+	}
+	size := w.size
+
+	// records written ahead of others are kept until those have been written
+	assert.NoErr("written", w.written(seqs[1:8]...))
+	assert.Eq("not compacted", w.size, size)
+	assert.NoErr("written", w.written(seqs[0]))
+	assert.Ok("compacted", w.size < size)
+	assert.NoErr("close", w.close())
+
+	w, records, err := openWAL(path)
+	if !assert.NoErr("reopen", err) {
+		return
+	}
+	assert.Eq("pending records", len(records), 2)
+	if len(records) == 2 {
+		assert.Eq("msg", records[0].Msg, "record 8")
+	}
+	assert.NoErr("written", w.written(1, 2))
+	assert.Eq("truncated", w.size, int64(0))
+	assert.NoErr("close", w.close())
+	_, err = os.Stat(path)
+	assert.Ok("removed", os.IsNotExist(err))
+}
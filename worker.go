@@ -0,0 +1,25 @@
+package log
+
+import "strconv"
+
+// WithWorker returns a sub-logger which tags its messages with the field "worker" set to label,
+// which makes messages from a pool of goroutines attributable to the worker that logged them:
+//
+//   for i := 0; i < 4; i++ {
+//     go func(logger *log.Logger) {
+//       for job := range jobs {
+//         logger.Info("processing %s", job) // "processing a.txt worker=2"
+//       }
+//     }(logger.WithWorker(strconv.Itoa(i)))
+//   }
+//
+// If label is empty, the ID of the calling goroutine is used instead, so WithWorker("") should
+// be called by the worker goroutine itself. Goroutine IDs are unique among running goroutines
+// but may be reused once a goroutine has exited. Calling WithWorker on a logger returned by
+// WithWorker replaces the label.
+func (l *Logger) WithWorker(label string) *Logger {
+	if label == "" {
+		label = strconv.FormatUint(goroutineID(), 10)
+	}
+	return l.withFields([]Field{{"worker", label}})
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/rsms/go-testutil"
+)
+
+func TestWithWorker(t *testing.T) {
+	assert := testutil.NewAssert(t)
+	w := &bytes.Buffer{}
+	l := NewLogger(w, "", LevelInfo, 0)
+	defer l.Close()
+
+	w1 := l.WithWorker("1")
+	w1.Info("a")
+	w1.WithWorker("x").Info("nested")
+	done := PushScope("job", "7")
+	w1.Info("b")
+	done()
+	l.Info("c")
+	l.Sync()
+	assert.Eq("output", w.String(),
+		"a worker=1\nnested worker=x\nb worker=1 job=7\nc\n")
+
+	w.Reset()
+	g := l.WithWorker("")
+	g.Info("d")
+	l.Sync()
+	assert.Eq("goroutine id", w.String(), "d worker="+strconv.FormatUint(goroutineID(), 10)+"\n")
+}